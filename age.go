@@ -0,0 +1,65 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+/*
+WithAgeFile adds the config file at path, encrypted with age, as a source of values
+below environment variables. Its format is chosen by the extension before `.age`, e.g.
+`config.toml.age` is decoded as TOML.
+
+The file is decrypted with the identity file at identity. If it is empty, the path of
+the identity file is taken from the `AGE_IDENTITY_FILE` environment variable, or else
+the key itself, such as `AGE-SECRET-KEY-1...`, from the `AGE_IDENTITY` environment
+variable. [WithConfigFile] also decrypts files with the `.age` extension this way.
+
+This runs the `age` command, which must be installed. A file that cannot be decrypted
+is an error.
+*/
+func WithAgeFile(path, identity string) Option {
+	return func(l *loader) {
+		l.sources = append(l.sources, &source{name: "file " + path, open: func(l *loader) (map[string]string, error) {
+			return l.decryptAge(path, identity)
+		}})
+	}
+}
+
+func (l *loader) decryptAge(path, identity string) (map[string]string, error) {
+	decode, err := decoderFor(strings.TrimSuffix(path, filepath.Ext(path)))
+	if err != nil {
+		return nil, err
+	}
+	if identity == "" {
+		identity, _ = l.lookupenv("AGE_IDENTITY_FILE")
+	}
+	if identity == "" {
+		key, ok := l.lookupenv("AGE_IDENTITY")
+		if !ok || key == "" {
+			return nil, fmt.Errorf("no age identity, set AGE_IDENTITY_FILE or AGE_IDENTITY")
+		}
+		//age only reads identities from files, so the key is written to a private one.
+		f, err := os.CreateTemp("", "age-identity-*")
+		if err != nil {
+			return nil, err
+		}
+		defer os.Remove(f.Name())
+		_, err = f.WriteString(key + "\n")
+		if closeErr := f.Close(); err == nil {
+			err = closeErr
+		}
+		if err != nil {
+			return nil, err
+		}
+		identity = f.Name()
+	}
+	stdout, err := runCommand(exec.Command("age", "--decrypt", "--identity", identity, path))
+	if err != nil {
+		return nil, err
+	}
+	return decode(l, stdout)
+}
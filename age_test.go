@@ -0,0 +1,62 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestAgeFile(t *testing.T) {
+	//The fake decrypts by checking the identity file holds the key and printing the file.
+	fakeCommand(t, "age", `
+[ "$1 $2" = "--decrypt --identity" ] || exit 2
+grep -q '^AGE-SECRET-KEY-1TEST$' "$3" || { echo "age: error: no identity matched any of the recipients" >&2; exit 1; }
+cat "$4"
+`)
+	dir := t.TempDir()
+	path := filepath.Join(dir, "config.toml.age")
+	if err := os.WriteFile(path, []byte("name = \"age\"\n[db]\nport = 5432\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	identity := writeFile(t, "key.txt", "# created: 2024-01-01\nAGE-SECRET-KEY-1TEST\n")
+	type C struct {
+		Name string `env:"NAME"`
+		DB   struct {
+			Port int `env:"PORT"`
+		}
+	}
+	want := &C{Name: "age"}
+	want.DB.Port = 5432
+	tests := []struct {
+		name string
+		env  map[string]string
+		opt  Option
+	}{
+		{name: "identity file", opt: WithAgeFile(path, identity)},
+		{name: "identity file env", env: map[string]string{"AGE_IDENTITY_FILE": identity}, opt: WithAgeFile(path, "")},
+		{name: "key env", env: map[string]string{"AGE_IDENTITY": "AGE-SECRET-KEY-1TEST"}, opt: WithAgeFile(path, "")},
+		{name: "config file", env: map[string]string{"AGE_IDENTITY": "AGE-SECRET-KEY-1TEST"}, opt: WithConfigFiles(path)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(makeLookup(tt.env), []string{"test"}, &C{}, tt.opt)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("New() = %+v, want %+v", got, want)
+			}
+		})
+	}
+
+	_, err := New(makeLookup(map[string]string{"AGE_IDENTITY": "AGE-SECRET-KEY-1WRONG"}), []string{"test"}, &C{}, WithAgeFile(path, ""))
+	if err == nil || !strings.Contains(err.Error(), "age: age: error: no identity matched") {
+		t.Errorf("New() error = %v, want the age error", err)
+	}
+	_, err = New(makeLookup(nil), []string{"test"}, &C{}, WithAgeFile(path, ""))
+	if err == nil || !strings.HasSuffix(err.Error(), "no age identity, set AGE_IDENTITY_FILE or AGE_IDENTITY") {
+		t.Errorf("New() error = %v, want a missing identity error", err)
+	}
+}
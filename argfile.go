@@ -0,0 +1,118 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+// maxArgFileDepth limits how deeply argument files may include each other, to catch
+// cycles.
+const maxArgFileDepth = 10
+
+/*
+WithArgFiles enables argument files, as used by javac and gcc for long argument lists.
+A command line argument `@path` is replaced by the arguments in the file at path,
+before any flags are parsed:
+
+	app @flags.txt -PORT 8080
+
+Arguments in the file are separated by whitespace, including newlines. Single or double
+quotes group an argument containing whitespace, a backslash escapes the next character
+outside single quotes, and lines starting with `#`, after any indentation, are
+comments. Argument files may reference other argument files. An argument starting with
+`@@` is passed on with a single `@`, and arguments after a `--` terminator are not
+expanded. A missing file is an error.
+*/
+func WithArgFiles() Option {
+	return func(l *loader) {
+		l.argFiles = true
+	}
+}
+
+func expandArgFiles(args []string, depth int) ([]string, error) {
+	if depth > maxArgFileDepth {
+		return nil, fmt.Errorf("argument files nested more than %d deep", maxArgFileDepth)
+	}
+	var expanded []string
+	for i, arg := range args {
+		switch {
+		case arg == "--":
+			return append(expanded, args[i:]...), nil
+		case strings.HasPrefix(arg, "@@"):
+			expanded = append(expanded, arg[1:])
+		case strings.HasPrefix(arg, "@") && len(arg) > 1:
+			data, err := os.ReadFile(arg[1:])
+			if err != nil {
+				return nil, fmt.Errorf("failed to read argument file: %w", err)
+			}
+			fileArgs, err := splitArgs(string(data))
+			if err != nil {
+				return nil, fmt.Errorf("argument file %s: %w", arg[1:], err)
+			}
+			fileArgs, err = expandArgFiles(fileArgs, depth+1)
+			if err != nil {
+				return nil, err
+			}
+			expanded = append(expanded, fileArgs...)
+		default:
+			expanded = append(expanded, arg)
+		}
+	}
+	return expanded, nil
+}
+
+// splitArgs splits the contents of an argument file into arguments.
+func splitArgs(s string) ([]string, error) {
+	var args []string
+	var arg strings.Builder
+	inArg, lineStart := false, true
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c == '#' && lineStart && quote == 0 {
+			for i < len(s) && s[i] != '\n' {
+				i++
+			}
+			continue
+		}
+		lineStart = c == '\n' && quote == 0 || lineStart && (c == ' ' || c == '\t')
+		switch {
+		case quote == '\'':
+			if c == '\'' {
+				quote = 0
+			} else {
+				arg.WriteByte(c)
+			}
+		case c == '\\' && i+1 < len(s):
+			i++
+			arg.WriteByte(s[i])
+			inArg = true
+		case quote == '"':
+			if c == '"' {
+				quote = 0
+			} else {
+				arg.WriteByte(c)
+			}
+		case c == '\'' || c == '"':
+			quote = c
+			inArg = true
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			if inArg {
+				args = append(args, arg.String())
+				arg.Reset()
+				inArg = false
+			}
+		default:
+			arg.WriteByte(c)
+			inArg = true
+		}
+	}
+	if quote != 0 {
+		return nil, fmt.Errorf("unterminated %c quote", quote)
+	}
+	if inArg {
+		args = append(args, arg.String())
+	}
+	return args, nil
+}
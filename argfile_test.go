@@ -0,0 +1,73 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestSplitArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    []string
+		wantErr bool
+	}{
+		{name: "whitespace", input: "-a 1\n\t-b  2\r\n", want: []string{"-a", "1", "-b", "2"}},
+		{name: "quotes", input: `-name "hello world" -path 'C:\dir name' -x "a\"b"`, want: []string{"-name", "hello world", "-path", `C:\dir name`, "-x", `a"b`}},
+		{name: "escapes", input: `a\ b c\\`, want: []string{"a b", `c\`}},
+		{name: "comments", input: "# comment\n-a 1\n  # indented\n-color #fff\n", want: []string{"-a", "1", "-color", "#fff"}},
+		{name: "empty quotes", input: `-a ""`, want: []string{"-a", ""}},
+		{name: "unterminated", input: `-a "b`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := splitArgs(tt.input)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("splitArgs() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitArgs() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestArgFiles(t *testing.T) {
+	type C struct {
+		Name string `env:"NAME"`
+		Port int    `env:"PORT"`
+		Tag  string `env:"TAG"`
+	}
+	inner := writeFile(t, "inner.txt", "-TAG inner")
+	outer := writeFile(t, "flags.txt", "# defaults\n-NAME \"my app\"\n-PORT 80\n@"+inner+"\n")
+	loop := filepath.Join(t.TempDir(), "loop.txt")
+	if err := os.WriteFile(loop, []byte("@"+loop), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		args    []string
+		opts    []Option
+		want    *C
+		wantErr bool
+	}{
+		{name: "expanded", args: []string{"test", "@" + outer, "-PORT", "8080"}, opts: []Option{WithArgFiles()}, want: &C{Name: "my app", Port: 8080, Tag: "inner"}},
+		{name: "escaped", args: []string{"test", "-TAG", "@@home"}, opts: []Option{WithArgFiles()}, want: &C{Tag: "@home"}},
+		{name: "disabled", args: []string{"test", "-TAG", "@" + outer}, want: &C{Tag: "@" + outer}},
+		{name: "missing", args: []string{"test", "@" + filepath.Join(t.TempDir(), "missing")}, opts: []Option{WithArgFiles()}, wantErr: true},
+		{name: "cycle", args: []string{"test", "@" + loop}, opts: []Option{WithArgFiles()}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(makeLookup(nil), tt.args, &C{}, tt.opts...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
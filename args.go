@@ -0,0 +1,238 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+const (
+	// argRest is the `arg` tag of the field taking the positional arguments after those
+	// of the indexed fields.
+	argRest = "rest"
+	// argPassthrough is the arg of the field with a `passthrough` tag, which takes the
+	// arguments after `--`.
+	argPassthrough = "--"
+)
+
+/*
+WithRemainingArgs sets args to the positional arguments left after parsing the command
+line that are not taken by a field, such as the file names in `app -v a.txt b.txt`:
+
+	var files []string
+	c, err := config.New(nil, nil, &C{}, config.WithRemainingArgs(&files))
+
+With subcommands, these are the arguments left after those of the selected subcommand.
+args is set to an empty slice if there are none.
+*/
+func WithRemainingArgs(args *[]string) Option {
+	return func(l *loader) {
+		l.remaining = args
+	}
+}
+
+/*
+WithInterspersedFlags lets flags follow positional arguments, as in `app input.txt
+-verbose`, rather than ending the flags at the first positional argument as the `flag`
+package does. A `--` still ends the flags, so the arguments after it are positional
+even if they start with `-`.
+
+With subcommands, the flags of each command end at the name of its subcommand, which
+is followed by the flags of the subcommand.
+*/
+func WithInterspersedFlags() Option {
+	return func(l *loader) {
+		l.interspersed = true
+	}
+}
+
+// positional holds the fields set by positional arguments, in the order of their index.
+type positional struct {
+	fields      []field
+	rest        *field
+	passthrough *field
+}
+
+// collectPositional returns the fields with an `arg` or `passthrough` tag, checking that
+// their indices run from 0 without gaps and that at most one slice field takes the rest,
+// and one the arguments after `--`.
+func collectPositional(fields []field) (positional, error) {
+	var p positional
+	indexed := map[int]field{}
+	for _, f := range fields {
+		switch {
+		case f.arg == "":
+			continue
+		case f.arg == argRest:
+			if p.rest != nil {
+				return p, fmt.Errorf("fields %s and %s both take the rest of the arguments", p.rest.name, f.name)
+			}
+			if f.value.Kind() != reflect.Slice {
+				return p, fmt.Errorf("field %s takes the rest of the arguments but is not a slice", f.name)
+			}
+			p.rest = &f
+			continue
+		case f.arg == argPassthrough:
+			if p.passthrough != nil {
+				return p, fmt.Errorf("fields %s and %s both take the arguments after --", p.passthrough.name, f.name)
+			}
+			if f.value.Kind() != reflect.Slice {
+				return p, fmt.Errorf("field %s takes the arguments after -- but is not a slice", f.name)
+			}
+			p.passthrough = &f
+			continue
+		}
+		i, err := strconv.Atoi(f.arg)
+		if err != nil || i < 0 {
+			return p, fmt.Errorf("field %s has invalid arg tag '%s', expected an index or '%s'", f.name, f.arg, argRest)
+		}
+		if other, ok := indexed[i]; ok {
+			return p, fmt.Errorf("fields %s and %s have the same arg index %d", other.name, f.name, i)
+		}
+		indexed[i] = f
+	}
+	for i := range len(indexed) {
+		f, ok := indexed[i]
+		if !ok {
+			return p, fmt.Errorf("no field has arg index %d", i)
+		}
+		p.fields = append(p.fields, f)
+	}
+	return p, nil
+}
+
+/*
+Split the arguments left after parsing the flags of args into the positional arguments
+and those after `--`, if a field takes them. The `flag` package drops the `--` ending
+the flags, so it is looked for in args just before the positional arguments, and
+otherwise among them.
+*/
+func (p positional) split(args, parsed []string) ([]string, []string) {
+	if p.passthrough == nil {
+		return parsed, nil
+	}
+	if n := len(args) - len(parsed); n > 0 && args[n-1] == "--" {
+		return nil, parsed
+	}
+	for i, arg := range parsed {
+		if arg == "--" {
+			return parsed[:i], parsed[i+1:]
+		}
+	}
+	return parsed, nil
+}
+
+// parseArgs parses the flags in args onto flagset, and returns the positional arguments
+// and, if a field of t takes them, the arguments after `--`.
+func (l *loader) parseArgs(flagset *flag.FlagSet, args []string, t *target) ([]string, []string, error) {
+	args = l.rewriteArgs(flagset, args)
+	if err := flagset.Parse(args); err != nil {
+		return nil, nil, err
+	}
+	if !l.interspersed || len(t.commands) > 0 {
+		remaining, passthrough := t.positionals.split(args, flagset.Args())
+		return remaining, passthrough, nil
+	}
+	//Parsing is resumed after each positional argument, until the end or a `--`.
+	var remaining []string
+	for {
+		rest := flagset.Args()
+		if n := len(args) - len(rest); n > 0 && args[n-1] == "--" {
+			if t.positionals.passthrough != nil {
+				return remaining, rest, nil
+			}
+			return append(remaining, rest...), nil, nil
+		}
+		if len(rest) == 0 {
+			return remaining, nil, nil
+		}
+		remaining = append(remaining, rest[0])
+		args = rest[1:]
+		args = l.rewriteArgs(flagset, args)
+		if err := flagset.Parse(args); err != nil {
+			return nil, nil, err
+		}
+	}
+}
+
+// rewriteArgs rewrites the flags in args for the options in effect into the form the
+// `flag` package parses.
+func (l *loader) rewriteArgs(flagset *flag.FlagSet, args []string) []string {
+	if l.foldFlags {
+		args = foldArgs(flagset, args, l.gnuFlags)
+	}
+	if l.gnuFlags {
+		args = gnuArgs(flagset, args)
+	}
+	return args
+}
+
+// values returns the positional arguments of args, or of passthrough, the arguments
+// after `--`, for the field f, or false if there are none.
+func (p positional) values(f field, args, passthrough []string) ([]string, bool) {
+	switch f.arg {
+	case "":
+		return nil, false
+	case argPassthrough:
+		return passthrough, passthrough != nil
+	case argRest:
+		if len(args) <= len(p.fields) {
+			return nil, false
+		}
+		return args[len(p.fields):], true
+	}
+	i, _ := strconv.Atoi(f.arg)
+	if i >= len(args) {
+		return nil, false
+	}
+	return args[i : i+1], true
+}
+
+// check returns an error for arguments left over after those taken by the fields, if
+// any field takes positional arguments and none takes the rest.
+func (p positional) check(args []string) error {
+	if len(p.fields) == 0 || p.rest != nil || len(args) <= len(p.fields) {
+		return nil
+	}
+	return fmt.Errorf("unexpected argument '%s'", args[len(p.fields)])
+}
+
+// leftover returns the positional arguments of args that no field takes.
+func (p positional) leftover(args []string) []string {
+	if p.rest != nil || len(args) <= len(p.fields) {
+		return []string{}
+	}
+	return slices.Clone(args[len(p.fields):])
+}
+
+// setRest sets the slice field to values, each of which is one element.
+func (l *loader) setRest(field reflect.Value, tag reflect.StructTag, values []string) error {
+	slice := reflect.MakeSlice(field.Type(), len(values), len(values))
+	for i, val := range values {
+		if err := l.setFieldValue(slice.Index(i), tag, val); err != nil {
+			return fmt.Errorf("argument '%s': %w", val, err)
+		}
+	}
+	field.Set(slice)
+	return nil
+}
+
+// argName returns the name of the positional argument of f shown in the help, e.g.
+// `<input>` for the field Input, in brackets if it is optional and with `...` if it
+// takes the rest or the arguments after `--`.
+func (f field) argName() string {
+	name := "<" + kebabCase(f.name[strings.LastIndex(f.name, ".")+1:]) + ">"
+	switch f.arg {
+	case argRest:
+		name += "..."
+	case argPassthrough:
+		name = "-- " + name + "..."
+	}
+	if !f.isRequired() {
+		name = "[" + name + "]"
+	}
+	return name
+}
@@ -0,0 +1,318 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type ArgsStruct struct {
+	Verbose bool     `env:"VERBOSE"`
+	Input   string   `arg:"0" usage:"File to read"`
+	Output  string   `arg:"1" env:"OUTPUT" default:"out.txt"`
+	Extra   []string `arg:"rest" usage:"Files to append"`
+}
+
+func TestNewArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		args    []string
+		want    *ArgsStruct
+		wantErr string
+	}{
+		{name: "Required", args: []string{"app", "in.txt"}, want: &ArgsStruct{Input: "in.txt", Output: "out.txt"}},
+		{name: "Optional", args: []string{"app", "-verbose", "in.txt", "b.txt"}, want: &ArgsStruct{Verbose: true, Input: "in.txt", Output: "b.txt"}},
+		{name: "Env", env: map[string]string{"OUTPUT": "env.txt"}, args: []string{"app", "in.txt"}, want: &ArgsStruct{Input: "in.txt", Output: "env.txt"}},
+		{name: "ArgOverridesEnv", env: map[string]string{"OUTPUT": "env.txt"}, args: []string{"app", "in.txt", "b.txt"}, want: &ArgsStruct{Input: "in.txt", Output: "b.txt"}},
+		{name: "Rest", args: []string{"app", "in.txt", "b.txt", "c,d", "e"}, want: &ArgsStruct{Input: "in.txt", Output: "b.txt", Extra: []string{"c,d", "e"}}},
+		{name: "AfterDashes", args: []string{"app", "--", "-in.txt"}, want: &ArgsStruct{Input: "-in.txt", Output: "out.txt"}},
+		{name: "Missing", args: []string{"app"}, wantErr: "missing required setting Input (argument <input>)"},
+		{name: "NoFlag", args: []string{"app", "-input=in.txt"}, wantErr: "failed to parse command line arguments: flag provided but not defined: -input"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			got, err := New(makeLookup(tt.env), tt.args, &ArgsStruct{}, WithOutput(&b))
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("New() error = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewArgsArity(t *testing.T) {
+	type C struct {
+		Source string `arg:"0"`
+		Target string `arg:"1" required:"false"`
+	}
+	if _, err := New(makeLookup(nil), []string{"app", "a"}, &C{}); err != nil {
+		t.Errorf("New() error = %v, want an optional second argument", err)
+	}
+	_, err := New(makeLookup(nil), []string{"app", "a", "b", "c"}, &C{})
+	if err == nil || err.Error() != "unexpected argument 'c'" {
+		t.Errorf("New() error = %v, want an error for the extra argument", err)
+	}
+	type NoArgs struct {
+		Name string `env:"NAME"`
+	}
+	if _, err := New(makeLookup(nil), []string{"app", "a", "b"}, &NoArgs{}); err != nil {
+		t.Errorf("New() error = %v, want arguments ignored without arg fields", err)
+	}
+	type Rest struct {
+		Files []int `arg:"rest" required:"true"`
+	}
+	got, err := New(makeLookup(nil), []string{"app", "1", "2"}, &Rest{})
+	if err != nil || !reflect.DeepEqual(got.Files, []int{1, 2}) {
+		t.Errorf("New() = %+v, %v, want the rest parsed as ints", got, err)
+	}
+	if _, err := New(makeLookup(nil), []string{"app", "x"}, &Rest{}); err == nil {
+		t.Error("New() expected an error for an invalid element")
+	}
+	_, err = New(makeLookup(nil), []string{"app"}, &Rest{})
+	if err == nil || err.Error() != "missing required setting Files (argument <files>...)" {
+		t.Errorf("New() error = %v, want the rest to be required", err)
+	}
+}
+
+func TestCollectPositional(t *testing.T) {
+	tests := []struct {
+		name    string
+		c       any
+		wantErr string
+	}{
+		{name: "Gap", c: &struct {
+			A string `arg:"0"`
+			B string `arg:"2"`
+		}{}, wantErr: "no field has arg index 1"},
+		{name: "Duplicate", c: &struct {
+			A string `arg:"0"`
+			B string `arg:"0"`
+		}{}, wantErr: "fields A and B have the same arg index 0"},
+		{name: "Invalid", c: &struct {
+			A string `arg:"first"`
+		}{}, wantErr: "field A has invalid arg tag 'first', expected an index or 'rest'"},
+		{name: "RestNotSlice", c: &struct {
+			A string `arg:"rest"`
+		}{}, wantErr: "field A takes the rest of the arguments but is not a slice"},
+		{name: "TwoRests", c: &struct {
+			A []string `arg:"rest"`
+			B []string `arg:"rest"`
+		}{}, wantErr: "fields A and B both take the rest of the arguments"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &loader{lookupenv: makeLookup(nil), envDelimiter: "_", flagDelimiter: "_", keyDelimiter: "_"}
+			fields, err := l.collectFields(reflect.ValueOf(tt.c).Elem(), "", prefix{})
+			if err != nil {
+				t.Fatalf("collectFields() error = %v", err)
+			}
+			if _, err := collectPositional(fields); err == nil || err.Error() != tt.wantErr {
+				t.Errorf("collectPositional() error = %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestWriteUsageArgs(t *testing.T) {
+	var b strings.Builder
+	if _, err := New(makeLookup(nil), []string{"app", "-h"}, &ArgsStruct{}, WithOutput(&b)); err != ErrHelp {
+		t.Fatalf("New() error = %v, want ErrHelp", err)
+	}
+	want := `Usage: app [options] <input> [<output>] [<extra>...]
+
+Arguments:
+  <input>      File to read
+  <output>     (env OUTPUT, default "out.txt")
+  <extra>...   Files to append
+
+Options:
+  -verbose     (env VERBOSE)
+
+General options:
+  -h, -help    Show this help
+`
+	if b.String() != want {
+		t.Errorf("New() printed\n%s\nwant\n%s", b.String(), want)
+	}
+}
+
+func TestNewPassthrough(t *testing.T) {
+	type C struct {
+		Verbose bool     `env:"VERBOSE"`
+		Command string   `arg:"0" default:"run"`
+		Args    []string `passthrough:"true" usage:"Arguments for the child"`
+	}
+	tests := []struct {
+		name string
+		args []string
+		want *C
+	}{
+		{name: "None", args: []string{"app", "-verbose"}, want: &C{Verbose: true, Command: "run"}},
+		{name: "AfterFlags", args: []string{"app", "-verbose", "--", "-x", "y"}, want: &C{Verbose: true, Command: "run", Args: []string{"-x", "y"}}},
+		{name: "AfterArgs", args: []string{"app", "exec", "--", "ls", "-l", "--", "a,b"}, want: &C{Command: "exec", Args: []string{"ls", "-l", "--", "a,b"}}},
+		{name: "Empty", args: []string{"app", "--"}, want: &C{Command: "run", Args: []string{}}},
+		{name: "DoubleDashes", args: []string{"app", "--", "--"}, want: &C{Command: "run", Args: []string{"--"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(makeLookup(nil), tt.args, &C{})
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+	_, err := New(makeLookup(nil), []string{"app", "a", "b", "--", "c"}, &C{})
+	if err == nil || err.Error() != "unexpected argument 'b'" {
+		t.Errorf("New() error = %v, want an error for the extra argument before --", err)
+	}
+
+	var b strings.Builder
+	if _, err := New(makeLookup(nil), []string{"app", "-h"}, &C{}, WithOutput(&b)); err != ErrHelp {
+		t.Fatalf("New() error = %v, want ErrHelp", err)
+	}
+	if !strings.HasPrefix(b.String(), "Usage: app [options] [<command>] [-- <args>...]\n\nArguments:\n  <command>      (default \"run\")\n  -- <args>...   Arguments for the child\n") {
+		t.Errorf("New() printed\n%s\nwant the passthrough arguments", b.String())
+	}
+
+	type NotSlice struct {
+		Args string `passthrough:"true"`
+	}
+	if _, err := New(makeLookup(nil), []string{"app"}, &NotSlice{}); err == nil {
+		t.Error("New() expected an error for a passthrough field that is not a slice")
+	}
+}
+
+func TestWithRemainingArgs(t *testing.T) {
+	type Args struct {
+		Input string   `arg:"0"`
+		Rest  []string `arg:"rest"`
+	}
+	tests := []struct {
+		name string
+		args []string
+		load func(args []string, opt Option) error
+		want []string
+	}{
+		{name: "NoArgFields", args: []string{"app", "-verbose", "a", "-b"}, want: []string{"a", "-b"}, load: func(args []string, opt Option) error {
+			_, err := New(makeLookup(nil), args, &struct {
+				Verbose bool `env:"VERBOSE"`
+			}{}, opt)
+			return err
+		}},
+		{name: "None", args: []string{"app"}, want: []string{}, load: func(args []string, opt Option) error {
+			_, err := New(makeLookup(nil), args, &struct{}{}, opt)
+			return err
+		}},
+		{name: "Taken", args: []string{"app", "a", "b"}, want: []string{}, load: func(args []string, opt Option) error {
+			_, err := New(makeLookup(nil), args, &Args{}, opt)
+			return err
+		}},
+		{name: "Passthrough", args: []string{"app", "a", "--", "b"}, want: []string{"a"}, load: func(args []string, opt Option) error {
+			_, err := New(makeLookup(nil), args, &struct {
+				Args []string `passthrough:"true"`
+			}{}, opt)
+			return err
+		}},
+		{name: "Subcommand", args: []string{"app", "serve", "a", "b"}, want: []string{"a", "b"}, load: func(args []string, opt Option) error {
+			_, err := New(makeLookup(nil), args, &struct {
+				Serve *struct{} `cmd:"serve"`
+			}{}, opt)
+			return err
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			if err := tt.load(tt.args, WithRemainingArgs(&got)); err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() remaining args = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWithInterspersedFlags(t *testing.T) {
+	type Config struct {
+		Verbose bool     `env:"VERBOSE" short:"v"`
+		Level   int      `env:"LEVEL"`
+		Input   string   `arg:"0"`
+		Files   []string `arg:"rest"`
+	}
+	tests := []struct {
+		name string
+		args []string
+		opts []Option
+		want Config
+	}{
+		{name: "After", args: []string{"app", "in.txt", "-verbose"}, want: Config{Verbose: true, Input: "in.txt"}},
+		{name: "Between", args: []string{"app", "in.txt", "-level", "2", "a", "-v", "b"}, want: Config{Verbose: true, Level: 2, Input: "in.txt", Files: []string{"a", "b"}}},
+		{name: "DoubleDash", args: []string{"app", "in.txt", "--", "-verbose", "-level"}, want: Config{Input: "in.txt", Files: []string{"-verbose", "-level"}}},
+		{name: "GNU", args: []string{"app", "in.txt", "--level", "3", "a", "-v"}, opts: []Option{WithGNUFlags()}, want: Config{Verbose: true, Level: 3, Input: "in.txt", Files: []string{"a"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(makeLookup(nil), tt.args, &Config{}, append(tt.opts, WithInterspersedFlags())...)
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			if !reflect.DeepEqual(*got, tt.want) {
+				t.Errorf("New() = %+v, want %+v", *got, tt.want)
+			}
+		})
+	}
+
+	t.Run("Passthrough", func(t *testing.T) {
+		got, err := New(makeLookup(nil), []string{"app", "run", "-verbose", "--", "-x"}, &struct {
+			Verbose bool     `env:"VERBOSE"`
+			Cmd     string   `arg:"0"`
+			Args    []string `passthrough:"true"`
+		}{}, WithInterspersedFlags())
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		if !got.Verbose || got.Cmd != "run" || !reflect.DeepEqual(got.Args, []string{"-x"}) {
+			t.Errorf("New() = %+v, want Verbose, Cmd run and Args [-x]", *got)
+		}
+	})
+
+	t.Run("Subcommand", func(t *testing.T) {
+		type Serve struct {
+			Port int `env:"PORT"`
+		}
+		got, err := New(makeLookup(nil), []string{"app", "-verbose", "serve", "-port", "80"}, &struct {
+			Verbose bool   `env:"VERBOSE"`
+			Serve   *Serve `cmd:"serve"`
+		}{}, WithInterspersedFlags())
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		if !got.Verbose || got.Serve == nil || got.Serve.Port != 80 {
+			t.Errorf("New() = %+v, want Verbose and Serve.Port 80", *got)
+		}
+	})
+
+	t.Run("Default", func(t *testing.T) {
+		got, err := New(makeLookup(nil), []string{"app", "in.txt", "-verbose"}, &Config{})
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		if got.Verbose || !reflect.DeepEqual(got.Files, []string{"-verbose"}) {
+			t.Errorf("New() = %+v, want -verbose left as an argument", *got)
+		}
+	})
+}
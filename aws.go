@@ -0,0 +1,175 @@
+package config
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AWSConfig holds the region and credentials used to call AWS services. Empty fields
+// default to the standard AWS_REGION (or AWS_DEFAULT_REGION), AWS_ACCESS_KEY_ID,
+// AWS_SECRET_ACCESS_KEY, and AWS_SESSION_TOKEN environment variables. Shared config
+// files and instance metadata credentials are not supported.
+type AWSConfig struct {
+	Region          string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	//Endpoint overrides the URL of the service, e.g. for a VPC endpoint or LocalStack.
+	Endpoint string
+	//Timeout limits each request. Defaults to 10 seconds. It is ignored if Client is set.
+	Timeout time.Duration
+	//Client is used to send requests. Defaults to a client with the timeout.
+	Client *http.Client
+}
+
+// resolve fills in the empty fields of c from the environment.
+func (c AWSConfig) resolve(l *loader) (AWSConfig, error) {
+	env := func(field *string, names ...string) {
+		for _, name := range names {
+			if *field != "" {
+				return
+			}
+			*field, _ = l.lookupenv(name)
+		}
+	}
+	env(&c.Region, "AWS_REGION", "AWS_DEFAULT_REGION")
+	env(&c.AccessKeyID, "AWS_ACCESS_KEY_ID")
+	env(&c.SecretAccessKey, "AWS_SECRET_ACCESS_KEY")
+	env(&c.SessionToken, "AWS_SESSION_TOKEN")
+	if c.Region == "" {
+		return c, fmt.Errorf("no AWS region, set AWS_REGION")
+	}
+	if c.AccessKeyID == "" || c.SecretAccessKey == "" {
+		return c, fmt.Errorf("no AWS credentials, set AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY")
+	}
+	if c.Client == nil {
+		c.Client = &http.Client{Timeout: c.Timeout}
+		if c.Client.Timeout == 0 {
+			c.Client.Timeout = 10 * time.Second
+		}
+	}
+	return c, nil
+}
+
+// call sends a request to an AWS JSON protocol API, such as SSM, with the operation
+// target and decodes the response into out.
+func (c AWSConfig) call(service, target string, in, out any) error {
+	body, err := json.Marshal(in)
+	if err != nil {
+		return err
+	}
+	endpoint := c.Endpoint
+	if endpoint == "" {
+		endpoint = fmt.Sprintf("https://%s.%s.amazonaws.com/", service, c.Region)
+	}
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", target)
+	c.sign(req, service, body, time.Now())
+	resp, err := c.Client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		var awsErr struct {
+			Type    string `json:"__type"`
+			Message string `json:"message"`
+		}
+		json.Unmarshal(data, &awsErr)
+		if awsErr.Type != "" {
+			//The type may be prefixed with a namespace, e.g. `com.amazon.coral#Error`.
+			_, name, _ := strings.Cut(awsErr.Type, "#")
+			return &awsError{statusError: statusError{code: resp.StatusCode, status: resp.Status}, name: cmp.Or(name, awsErr.Type), message: awsErr.Message}
+		}
+		return &statusError{code: resp.StatusCode, status: resp.Status, body: bytes.TrimSpace(data)}
+	}
+	return json.Unmarshal(data, out)
+}
+
+// awsError is an error returned by an AWS API, such as ParameterNotFound.
+type awsError struct {
+	statusError
+	name    string
+	message string
+}
+
+func (e *awsError) Error() string {
+	return fmt.Sprintf("%s: %s", e.name, e.message)
+}
+
+// sign adds the headers of an AWS Signature Version 4 to req, whose payload is body.
+func (c AWSConfig) sign(req *http.Request, service string, body []byte, now time.Time) {
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+	req.Header.Set("X-Amz-Date", amzDate)
+	if c.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", c.SessionToken)
+	}
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.Join(values, ",")
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(headers[name]) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	payloadHash := sha256.Sum256(body)
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		path,
+		canonicalQuery(req.URL.Query()),
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+	scope := date + "/" + c.Region + "/" + service + "/aws4_request"
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+	key := []byte("AWS4" + c.SecretAccessKey)
+	for _, part := range []string{date, c.Region, service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	signature := hex.EncodeToString(hmacSHA256(key, stringToSign))
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s", c.AccessKeyID, scope, signedHeaders, signature))
+}
+
+func canonicalQuery(query url.Values) string {
+	//url.Values.Encode sorts by key but encodes spaces as `+`, which AWS does not accept.
+	return strings.ReplaceAll(query.Encode(), "+", "%20")
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
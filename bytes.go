@@ -0,0 +1,94 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// byteUnits maps lower cased byte size suffixes to their multipliers.
+var byteUnits = map[string]uint64{
+	"":    1,
+	"b":   1,
+	"k":   1e3,
+	"kb":  1e3,
+	"m":   1e6,
+	"mb":  1e6,
+	"g":   1e9,
+	"gb":  1e9,
+	"t":   1e12,
+	"tb":  1e12,
+	"p":   1e15,
+	"pb":  1e15,
+	"e":   1e18,
+	"eb":  1e18,
+	"kib": 1 << 10,
+	"mib": 1 << 20,
+	"gib": 1 << 30,
+	"tib": 1 << 40,
+	"pib": 1 << 50,
+	"eib": 1 << 60,
+}
+
+/*
+Parse a human readable byte size such as `25MB` or `1.5GiB` into a number of bytes.
+Decimal units (`KB`, `MB`, ...) are powers of 1000 and binary units (`KiB`, `MiB`, ...)
+are powers of 1024. Units are case insensitive and may be separated from the number by
+a space.
+*/
+func parseByteSize(s string) (uint64, error) {
+	s = strings.TrimSpace(s)
+	i := strings.IndexFunc(s, func(r rune) bool { return (r < '0' || r > '9') && r != '.' })
+	if i == -1 {
+		i = len(s)
+	}
+	number, unit := s[:i], strings.ToLower(strings.TrimSpace(s[i:]))
+	multiplier, ok := byteUnits[unit]
+	if !ok {
+		return 0, fmt.Errorf("unknown byte size unit '%s'", s[i:])
+	}
+	if !strings.Contains(number, ".") {
+		n, err := strconv.ParseUint(number, 10, 64)
+		if err != nil {
+			return 0, err
+		}
+		if n > math.MaxUint64/multiplier {
+			return 0, fmt.Errorf("byte size '%s' overflows uint64", s)
+		}
+		return n * multiplier, nil
+	}
+	f, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return 0, err
+	}
+	bytes := f * float64(multiplier)
+	if bytes >= math.MaxUint64 {
+		return 0, fmt.Errorf("byte size '%s' overflows uint64", s)
+	}
+	return uint64(bytes), nil
+}
+
+// setByteSize sets the integer field to the byte size described by val.
+func setByteSize(field reflect.Value, val string) error {
+	n, err := parseByteSize(val)
+	if err != nil {
+		return err
+	}
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if n > math.MaxInt64 || field.OverflowInt(int64(n)) {
+			return fmt.Errorf("byte size '%s' overflows %s", val, field.Type())
+		}
+		field.SetInt(int64(n))
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if field.OverflowUint(n) {
+			return fmt.Errorf("byte size '%s' overflows %s", val, field.Type())
+		}
+		field.SetUint(n)
+	default:
+		return fmt.Errorf("byte sizes are not supported for type %s", field.Type())
+	}
+	return nil
+}
@@ -0,0 +1,46 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+type ByteSizeStruct struct {
+	MaxUpload int64    `env:"MAX_UPLOAD" bytes:"true" default:"25MB"`
+	Cache     uint64   `env:"CACHE" bytes:"true" default:"1.5GiB"`
+	Small     uint16   `env:"SMALL" bytes:"true" default:"1KiB"`
+	Limit     *int64   `env:"LIMIT" bytes:"true"`
+	Tiers     []uint64 `env:"TIERS" bytes:"true"`
+}
+
+func TestNewByteSize(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		args    []string
+		want    *ByteSizeStruct
+		wantErr bool
+	}{
+		{name: "Defaults", env: map[string]string{}, args: []string{"ConfigTestApp"}, want: &ByteSizeStruct{MaxUpload: 25_000_000, Cache: 1_610_612_736, Small: 1024}},
+		{name: "SetEnv", env: map[string]string{"MAX_UPLOAD": "512", "CACHE": "2 gb"}, args: []string{"ConfigTestApp"}, want: &ByteSizeStruct{MaxUpload: 512, Cache: 2_000_000_000, Small: 1024}},
+		{name: "SetArg", env: map[string]string{}, args: []string{"ConfigTestApp", "-SMALL=64k"}, want: &ByteSizeStruct{MaxUpload: 25_000_000, Cache: 1_610_612_736, Small: 64_000}},
+		{name: "Pointer", env: map[string]string{"LIMIT": "2MB"}, args: []string{"ConfigTestApp"}, want: &ByteSizeStruct{MaxUpload: 25_000_000, Cache: 1_610_612_736, Small: 1024, Limit: ptr[int64](2_000_000)}},
+		{name: "Slice", env: map[string]string{"TIERS": "1KB,2KB"}, args: []string{"ConfigTestApp"}, want: &ByteSizeStruct{MaxUpload: 25_000_000, Cache: 1_610_612_736, Small: 1024, Tiers: []uint64{1000, 2000}}},
+		{name: "SliceUnknownUnit", env: map[string]string{"TIERS": "1KB,2XB"}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+		{name: "Overflow", env: map[string]string{"SMALL": "1MiB"}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+		{name: "UnknownUnit", env: map[string]string{"CACHE": "1XB"}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+		{name: "Negative", env: map[string]string{"MAX_UPLOAD": "-1MB"}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(makeLookup(tt.env), tt.args, &ByteSizeStruct{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,119 @@
+package config
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	sourceCacheMu sync.Mutex
+	sourceCache   = map[string]*sourceCacheEntry{}
+	//cacheIDs numbers the options returned by WithCache, which key their entries.
+	cacheIDs atomic.Uint64
+	//cacheNow returns the current time, and is replaced in tests.
+	cacheNow = time.Now
+)
+
+type sourceCacheEntry struct {
+	value      any
+	fetched    time.Time
+	refreshing bool
+}
+
+/*
+WithCache caches the values of the sources and secret stores added by opts for the life
+of the process, so repeated calls to [New], such as on reload, don't hammer the backing
+service:
+
+	cache := config.WithCache(time.Minute, 10*time.Minute, config.WithSSM(src), config.WithVault(vault))
+	c, err := config.New(os.LookupEnv, os.Args, &C{}, cache)
+
+Cached values are used for ttl after they are fetched. For stale after that, the stale
+values are still used, while they are refreshed in the background for later calls. Once
+that has passed too, they are fetched again before New returns. Errors are not cached,
+and a failed background refresh leaves the stale values in place.
+
+The values are cached by the option returned by WithCache, so it should be created once
+and passed to each call of New. Each of its sources and secret stores has values of its
+own, even if they share a name, and sources that read a path given by the command line
+or the environment, such as [WithConfigFile], are cached separately for each path.
+*/
+func WithCache(ttl, stale time.Duration, opts ...Option) Option {
+	id := cacheIDs.Add(1)
+	return func(l *loader) {
+		sources, resolvers := len(l.sources), len(l.resolvers)
+		for _, opt := range opts {
+			opt(l)
+		}
+		for i, s := range l.sources[sources:] {
+			if s.open == nil {
+				continue
+			}
+			open, key, inputs := s.open, fmt.Sprintf("%d source %d", id, i), s.key
+			s.open = func(l *loader) (map[string]string, error) {
+				key := key
+				if inputs != nil {
+					key += " " + inputs(l)
+				}
+				value, err := cached(key, ttl, stale, func() (any, error) {
+					return open(l)
+				})
+				values, _ := value.(map[string]string)
+				return values, err
+			}
+		}
+		for i, r := range l.resolvers[resolvers:] {
+			resolve, key := r.resolve, fmt.Sprintf("%d resolver %d ", id, i)
+			r.resolve = func(l *loader, ref string) (string, error) {
+				value, err := cached(key+ref, ttl, stale, func() (any, error) {
+					return resolve(l, ref)
+				})
+				s, _ := value.(string)
+				return s, err
+			}
+		}
+	}
+}
+
+// cached returns the value cached under key, calling fetch to fetch it when it has
+// expired and in the background when it is stale.
+func cached(key string, ttl, stale time.Duration, fetch func() (any, error)) (any, error) {
+	sourceCacheMu.Lock()
+	entry, ok := sourceCache[key]
+	if ok {
+		age := cacheNow().Sub(entry.fetched)
+		if age < ttl {
+			sourceCacheMu.Unlock()
+			return entry.value, nil
+		}
+		if age < ttl+stale {
+			if !entry.refreshing {
+				entry.refreshing = true
+				go refresh(key, entry, fetch)
+			}
+			sourceCacheMu.Unlock()
+			return entry.value, nil
+		}
+	}
+	sourceCacheMu.Unlock()
+	value, err := fetch()
+	if err != nil {
+		return nil, err
+	}
+	sourceCacheMu.Lock()
+	sourceCache[key] = &sourceCacheEntry{value: value, fetched: cacheNow()}
+	sourceCacheMu.Unlock()
+	return value, nil
+}
+
+func refresh(key string, entry *sourceCacheEntry, fetch func() (any, error)) {
+	value, err := fetch()
+	sourceCacheMu.Lock()
+	defer sourceCacheMu.Unlock()
+	entry.refreshing = false
+	if err == nil && sourceCache[key] == entry {
+		sourceCache[key] = &sourceCacheEntry{value: value, fetched: cacheNow()}
+	}
+}
@@ -0,0 +1,179 @@
+package config
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestCache(t *testing.T) {
+	now := time.Now()
+	cacheNow = func() time.Time { return now }
+	defer func() { cacheNow = time.Now }()
+	var requests atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := requests.Add(1)
+		if n == 3 {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(`{"version": ` + strconv.Itoa(int(n)) + `}`))
+	}))
+	defer srv.Close()
+	type C struct {
+		Version int `env:"VERSION"`
+	}
+	cache := WithCache(time.Minute, time.Hour, WithHTTP(HTTPSource{URL: srv.URL}))
+	key := fmt.Sprintf("%d source 0", cacheIDs.Load())
+	load := func() int {
+		t.Helper()
+		got, err := New(makeLookup(nil), []string{"test"}, &C{}, cache)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return got.Version
+	}
+	waitRefresh := func() {
+		t.Helper()
+		for i := 0; i < 1000; i++ {
+			sourceCacheMu.Lock()
+			refreshing := sourceCache[key].refreshing
+			sourceCacheMu.Unlock()
+			if !refreshing {
+				return
+			}
+			time.Sleep(time.Millisecond)
+		}
+		t.Fatal("background refresh did not finish")
+	}
+
+	if v := load(); v != 1 {
+		t.Fatalf("first load = %d, want 1", v)
+	}
+	now = now.Add(30 * time.Second)
+	if v := load(); v != 1 || requests.Load() != 1 {
+		t.Fatalf("load within ttl = %d after %d requests, want the cached 1", v, requests.Load())
+	}
+	//Stale values are returned while they are refreshed.
+	now = now.Add(time.Minute)
+	if v := load(); v != 1 {
+		t.Fatalf("stale load = %d, want 1", v)
+	}
+	waitRefresh()
+	if v := load(); v != 2 || requests.Load() != 2 {
+		t.Fatalf("load after refresh = %d after %d requests, want 2", v, requests.Load())
+	}
+	//A failed refresh keeps the stale values.
+	now = now.Add(2 * time.Minute)
+	if v := load(); v != 2 {
+		t.Fatalf("stale load = %d, want 2", v)
+	}
+	waitRefresh()
+	if v := load(); v != 2 {
+		t.Fatalf("load after failed refresh = %d, want 2", v)
+	}
+	waitRefresh()
+	//Expired values are fetched before returning.
+	now = now.Add(2 * time.Hour)
+	if v := load(); v < 4 {
+		t.Fatalf("expired load = %d, want a fresh value", v)
+	}
+}
+
+func TestCacheResolver(t *testing.T) {
+	var calls int
+	resolved := func(l *loader) {
+		l.resolvers = append(l.resolvers, &resolver{tag: "test", name: "test", resolve: func(l *loader, ref string) (string, error) {
+			calls++
+			return ref + "-value", nil
+		}})
+	}
+	type C struct {
+		A string `test:"cache-a"`
+		B string `test:"cache-b"`
+	}
+	cache := WithCache(time.Hour, 0, resolved)
+	for i := 0; i < 3; i++ {
+		got, err := New(makeLookup(nil), []string{"test"}, &C{}, cache)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.A != "cache-a-value" || got.B != "cache-b-value" {
+			t.Errorf("New() = %+v, want resolved values", got)
+		}
+	}
+	if calls != 2 {
+		t.Errorf("resolve called %d times, want once per reference", calls)
+	}
+}
+
+func TestCacheSourceIdentity(t *testing.T) {
+	type C struct {
+		A string `env:"A"`
+		B string `env:"B"`
+	}
+	t.Run("SameName", func(t *testing.T) {
+		//Both commands are named `command echo`.
+		cache := WithCache(time.Hour, 0, WithCommand("echo", `{"a":"1"}`), WithCommand("echo", `{"b":"2"}`))
+		for i := 0; i < 2; i++ {
+			got, err := New(makeLookup(nil), []string{"test"}, &C{}, cache)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.A != "1" || got.B != "2" {
+				t.Errorf("New() = %+v, want A 1 and B 2", got)
+			}
+		}
+	})
+
+	t.Run("Options", func(t *testing.T) {
+		for _, want := range []string{"1", "2"} {
+			got, err := New(makeLookup(nil), []string{"test"}, &C{}, WithCache(time.Hour, 0, WithCommand("echo", `{"a":"`+want+`"}`)))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.A != want {
+				t.Errorf("New() A = %q, want %q", got.A, want)
+			}
+		}
+	})
+
+	t.Run("ConfigFilePath", func(t *testing.T) {
+		dir := t.TempDir()
+		cache := WithCache(time.Hour, 0, WithConfigFile(""))
+		for _, want := range []string{"1", "2"} {
+			path := filepath.Join(dir, want+".json")
+			if err := os.WriteFile(path, []byte(`{"a":"`+want+`"}`), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			got, err := New(makeLookup(map[string]string{"CONFIG_FILE": path}), []string{"test"}, &C{}, cache)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.A != want {
+				t.Errorf("New() with %s A = %q, want %q", path, got.A, want)
+			}
+		}
+	})
+}
+
+func TestCacheSourceName(t *testing.T) {
+	type C struct {
+		Port int `env:"PORT"`
+	}
+	path := writeFile(t, "c.json", `{"port": "abc"}`)
+	cache := WithCache(time.Hour, 0, WithConfigFile(path))
+	for i := 0; i < 2; i++ {
+		_, err := New(makeLookup(nil), []string{"test"}, &C{}, cache)
+		if err == nil || !strings.Contains(err.Error(), "file "+path) {
+			t.Errorf("New() call %d error = %v, want it to name %s", i, err, path)
+		}
+	}
+}
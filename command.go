@@ -0,0 +1,49 @@
+package config
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+/*
+WithCommand adds the output of a command as a source of values below environment
+variables. The command is run once with the given arguments, without a shell, and its
+stdout is parsed as a JSON object if it starts with `{`, like [WithJSONFile], and as
+`key=value` lines otherwise, like [WithPropertiesFile]. This suits secret helpers:
+
+	config.WithCommand("op", "inject", "-i", "config.json.tpl")
+
+A command that cannot be run or exits with an error is an error, which includes its
+stderr.
+*/
+func WithCommand(name string, args ...string) Option {
+	return func(l *loader) {
+		l.sources = append(l.sources, &source{name: "command " + name, open: func(l *loader) (map[string]string, error) {
+			stdout, err := runCommand(exec.Command(name, args...))
+			if err != nil {
+				return nil, err
+			}
+			if bytes.HasPrefix(bytes.TrimSpace(stdout), []byte("{")) {
+				return decoders[".json"](l, stdout)
+			}
+			return decodeProperties(l, stdout)
+		}})
+	}
+}
+
+// runCommand runs cmd and returns its stdout, or an error including its stderr.
+func runCommand(cmd *exec.Cmd) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		var exitErr *exec.ExitError
+		if msg := strings.TrimSpace(stderr.String()); errors.As(err, &exitErr) && msg != "" {
+			return nil, fmt.Errorf("%s: %s", cmd.Args[0], msg)
+		}
+		return nil, fmt.Errorf("%s: %w", cmd.Args[0], err)
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,54 @@
+package config
+
+import (
+	"os/exec"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCommand(t *testing.T) {
+	if _, err := exec.LookPath("sh"); err != nil {
+		t.Skip("sh not available")
+	}
+	type C struct {
+		Name  string `env:"NAME" default:"default"`
+		Token string `env:"TOKEN"`
+		DB    struct {
+			Port int `env:"PORT"`
+		}
+	}
+	want := &C{Name: "default", Token: "t0ken"}
+	want.DB.Port = 5432
+	tests := []struct {
+		name    string
+		script  string
+		want    *C
+		wantErr string
+	}{
+		{name: "json", script: `echo '{"token": "t0ken", "db": {"port": 5432}}'`, want: want},
+		{name: "key value", script: `printf 'TOKEN=t0ken\ndb.port=5432\n'`, want: want},
+		{name: "failure", script: `echo 'not signed in' >&2; exit 1`, wantErr: "not signed in"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(makeLookup(nil), []string{"test"}, &C{}, WithCommand("sh", "-c", tt.script))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("New() error = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	if _, err := New(makeLookup(nil), []string{"test"}, &C{}, WithCommand("definitely-not-a-command")); err == nil {
+		t.Error("New() expected error for missing command")
+	}
+}
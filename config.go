@@ -7,16 +7,191 @@ in the following order of precedence:
 
 - Command line arguments
 - Environment variables
+- Config files, such as [WithJSONFile], [WithTOMLFile], or [WithXMLFile]
 - Defaults, as specified in the struct tags
 
 The struct tags are as follows:
 
-- `env` - The name of the environment variable to use. This is also used as the
-command line flag name.
+- `env` - The name of the environment variable to use. Fields with an `env` tag are also
+set by a command line flag, named after the field in kebab case, e.g. `-http-host` for
+`HTTPHost`. The env name is accepted as a flag too, e.g. `-HTTP_HOST`.
+- `flag` - The name of the command line flag to use instead of the kebab cased field
+name, e.g. `flag:"addr"`. Fields with only a `flag` tag are set by the command line
+alone, and `flag:"-"` leaves a field without a flag. Bool fields that default to `true`
+also get a negation flag to turn them off, e.g. `-no-cache` for `-cache`.
+- `short` - A single character flag name also accepted, e.g. `short:"v"` to set a field
+by `-v` as well as its long flag.
+- `usage` - The description of the field shown in the help listing printed for `-h`,
+along with its flag, type, env name, and default. `desc` is accepted as well. New
+returns [ErrHelp] once the listing is printed, which is written to [WithOutput].
+- `placeholder` - The name of the value of the flag in the help listing, e.g.
+`placeholder:"FILE"` to show `-config FILE` rather than `-config string`.
 - `default` - The default value to use if no environment variable or command line
-argument is provided.
+argument is provided. Defaults containing `{{` are `text/template` templates executed
+against the struct after all other fields are set, e.g. `default:"{{.Host}}:{{.Port}}"`.
+Templated defaults are resolved in field order, so they may reference earlier ones.
+- `count` - If `true`, the integer field is set by how many times its flag is given,
+e.g. `-v -v -v` for 3, the usual idiom for verbosity. A value sets the count, e.g.
+`-v=2`.
+- `arg` - The index of the positional argument that sets the field instead of a flag,
+e.g. `arg:"0"` for the first, or `rest` for a slice field taking all the arguments
+after the indexed ones. Indexed arguments are required unless the field has a default,
+and other arguments are rejected unless a field takes the rest.
+- `cmd` - The name of the subcommand set by a field that is a pointer to its own config
+struct, e.g. `cmd:"serve"`, or the kebab cased field name if empty. The arguments after
+the subcommand name populate a new struct that the field is set to, leaving the other
+subcommands nil. Its fields are named as if it were the root struct, and the fields of
+the outer struct are global options, given before the subcommand name. `-h` after it
+prints the help for the subcommand, and the `usage` tag describes it in the outer help.
+- `passthrough` - If `true`, the slice field is set to the arguments after `--`, e.g.
+to forward them to a child process. Without it, they are positional arguments.
+- `group` - The group the flag is listed in by the help, e.g. `group:"TLS"` for a "TLS
+options" section. The fields of nested structs are grouped by the name of their top
+level struct field, or the struct's own `group` tag.
+- `required` - If `true`, New returns an error naming the field, along with its env and
+flag names, when no value is provided for it by any source. All missing fields are
+listed at once.
+- `deprecated` - A message such as `use HTTP_HOST`, reported along with the env var or
+flag used when the field is set by either. Deprecated flags are left out of the `-h`
+help listing. See [WithDeprecationHandler].
+- `delim` - The separator used to split values for slice fields. Defaults to
+[Delimiter]. The flags of slice fields may also be repeated, appending the elements of
+each occurrence, e.g. `-tag a -tag b,c` for `[]string{"a", "b", "c"}`.
+- `csv` - If `true`, slice and map values are split using `encoding/csv` rules, so
+elements containing the delimiter can be quoted, e.g. `TAGS="a,b",c`. The delimiter must
+be a single character.
+- `ranges` - If `true`, elements of integer slices may be inclusive ranges, e.g.
+`CPUS=0-3,8,10-11` populates `[]int{0, 1, 2, 3, 8, 10, 11}`.
+- `percent` - If `true`, float values may be given as percentages, e.g. `SAMPLE_RATE=2.5%`
+populates `0.025`. Values without a `%` suffix are used as is.
+- `encoding` - How values for `[]byte` and `encoding.BinaryUnmarshaler` fields are
+decoded: `raw`, `base64`, or `hex`.
+- `oneof` - A comma separated list of the values allowed for a string field, including
+named string types used as enums, e.g. `oneof:"json,text,console"`.
+- `format` - If `json`, the value is unmarshalled into the field with `encoding/json`.
+This works for any field type, including structs, maps, and slices. Fields of type
+`json.RawMessage` are always treated as JSON and validated.
+- `bytes` - If `true`, the integer field is parsed as a human readable byte size, e.g.
+`25MB` or `1.5GiB`. Decimal units are powers of 1000 and binary units are powers of 1024.
+- `prec` - The precision in bits of `big.Float` fields, e.g. `prec:"200"`. Defaults to
+64 bits, as for `big.ParseFloat`.
 
-The following struct field &kinds* are supported: `bool`, `float64`, `int`, `int64`, `string`, `uint`, `uint64`. In addition, the field type `time.Duration` is also supported.
+The following struct field &kinds* are supported: `bool`, `complex64`, `complex128`,
+`float32`, `float64`, `int`, `int8`, `int16`, `int32`, `int64`, `string`, `uint`, `uint8`,
+`uint16`, `uint32`, `uint64`. In addition, the field type `time.Duration` is also
+supported. Durations accept the values supported by `time.ParseDuration`, plus the units
+`d` for days and `w` for weeks, e.g. `RETENTION=30d` or `1w2d12h`.
+
+The field type `url.URL` is also supported. Values must be absolute URLs, e.g.
+`ENDPOINT=https://api.example.com:8443/v1`.
+
+The field types `net.IP`, `net.IPNet` and `net.HardwareAddr` are also supported, e.g.
+`BIND_ADDR=0.0.0.0`, `ALLOW_CIDR=10.0.0.0/8` or `MAC=00:00:5e:00:53:01`.
+
+The field type [SemVer] parses semantic versions such as `MIN_CLIENT_VERSION=1.4.0`.
+
+The field type [Decimal] holds exact decimal numbers such as prices. Its number of digits
+after the decimal point can be fixed with the `scale` tag.
+
+The field type [CronSpec] validates cron expressions such as `30 9 * * MON-FRI`.
+
+The field type [Path] holds file system paths, which are expanded when they are set. A
+leading `~` or `~/` is replaced by the `HOME` environment variable, or the user's home
+directory if it is unset, and `$VAR` and `${VAR}` by the environment variables looked
+up by New, e.g. `LOGS=${LOG_ROOT}/app`. A variable that is not set is an error, rather
+than leaving a path relative to the root. Relative paths are then made absolute against
+the directory set by [WithPathBase], or the working directory, and cleaned.
+
+The field type `slog.Level` is also supported. It accepts level names such as `debug`
+or `warn`, optionally with an offset such as `info+2`, as well as plain numbers.
+
+The field type `mail.Address` is also supported, e.g. `ALERT_FROM="Ops <ops@example.com>"`.
+
+The `database/sql` null types, such as `sql.NullString`, `sql.NullInt64` or `sql.Null[T]`,
+are also supported. Their value is parsed as usual and Valid is only set to true when a
+value is provided.
+
+Types implementing `flag.Value` are registered on the flag set as is, and their Set
+method is also used to parse environment variables and defaults. Any other type
+implementing `encoding.TextUnmarshaler`, such as `time.Time` or a custom
+enum, is populated by calling its UnmarshalText method. Failing that, types implementing
+`encoding.BinaryUnmarshaler` are populated by decoding the value according to the
+`encoding` tag, which defaults to `base64` for them, and calling UnmarshalBinary.
+
+Parsing for any other type can be added with [RegisterParser], which takes precedence
+over the built in parsing.
+
+Slices of any supported kind are also supported, e.g. `[]string`, `[]int` or
+`[]time.Duration`. Their values are split on the field's delimiter and each element is
+parsed on its own, so `HOSTS=a,b,c` populates a `[]string` with three elements and
+`RETRY_BACKOFFS=1s,2s,5s` populates a `[]time.Duration`. An empty value produces an
+empty slice. Slices of structs are populated from a JSON array of objects, e.g.
+`UPSTREAMS=[{"host":"a","port":80}]`, or from indexed environment variables, e.g.
+`UPSTREAMS_0_HOST=a`, `UPSTREAMS_0_PORT=80`, `UPSTREAMS_1_HOST=b`. Indices are scanned
+from 0 until one has no variables set, and are only used if no value is provided for
+the field itself.
+
+Pointers to any supported kind, e.g. `*int` or `*bool`, are left nil unless a value is
+provided by default, environment variable, or command line argument, which allows an
+explicit zero value to be told apart from an unset field.
+
+Maps are populated from delimiter separated `key=value` pairs, e.g.
+`LABELS=env=prod,team=core` for a `map[string]string` or `RATE_LIMITS=read=100,write=20`
+for a `map[string]int`. Keys and values may be of any supported kind. Pairs are split on
+the first `=`, so values may contain further `=` characters. To include the delimiter, an
+`=` in a key, or other special characters:
+
+- A backslash escapes the next character, e.g. `\,`, `\=`, `\"`, or `\\`.
+- Double quotes make the delimiter and `=` between them literal, e.g.
+`ACCEPT=html="text/html,application/xhtml+xml"` or `"a=b"=1` for the key `a=b`.
+
+Maps may also be given as a JSON object of scalars, e.g. `LABELS={"env":"prod"}`, which
+is how config files provide them. Likewise, a slice value that is a valid JSON array of
+scalars, e.g. `TAGS=["a,b","c"]`, is split into its elements rather than on the
+delimiter, whether it comes from a config file, an environment variable, or a flag.
+
+Nested struct fields are walked recursively. Their env names are derived by joining
+the struct field's `env` tag, or its upper cased name if it has none, and the nested
+field's `env` tag with an underscore, which can be changed with [WithEnvDelimiter]. Flag
+names are joined the same way from `flag` tags or kebab cased names with a `-`, while
+the env style flag names are joined with the delimiter set by [WithFlagDelimiter]:
+
+	type C struct {
+		DB struct {
+			Host string `env:"HOST" default:"localhost"` // Set by DB_HOST, -db-host, or -DB_HOST
+			Port int    `env:"PORT" default:"5432"`      // Set by DB_PORT, -db-port, or -DB_PORT
+		}
+	}
+
+Embedded structs are flattened into the outer struct, so their fields keep their own
+names unless the embedded field has an `env` tag to use as a prefix:
+
+	type Common struct {
+		LogLevel string `env:"LOG_LEVEL" default:"info"`
+	}
+
+	type C struct {
+		Common // Set by LOG_LEVEL or -log-level
+	}
+
+Config files are a layer of values below environment variables. Their keys are matched
+case insensitively against the `json` tag of each field, or its `env` tag if it has none,
+and nested objects map to nested structs, so `{"db": {"host": "x"}}` sets `DB_HOST`. The
+keys of nested objects are joined with an underscore, which can be changed with
+[WithFileKeyDelimiter]. Fields with only a `json` tag are set by config files alone.
+[WithConfigFile] loads a file whose path is given by the `-config` flag or `CONFIG_FILE`
+environment variable, in the format given by its extension. Several files, added by
+[WithConfigFiles] or by repeating file options, are merged in order, with values in later
+files overriding those in earlier ones. Other stores of values can be added in the same
+layer by implementing [Source].
+
+Secret stores, such as [WithVault], resolve fields by a struct tag of their own that
+references where each secret is stored. They take precedence over config files and
+defaults, but not environment variables or command line arguments.
+
+Interface fields are supported for interfaces with concrete types registered by
+[RegisterKind]. A discriminator setting named after the field, e.g. `STORE_KIND=s3`,
+selects the concrete type, whose fields are then populated like a nested struct.
 
 Example usage:
 
@@ -42,14 +217,35 @@ if the field is an integer, the default value must be a valid integer, not an em
 package config
 
 import (
+	"cmp"
+	"database/sql"
+	"encoding"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
 	"flag"
 	"fmt"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/mail"
+	"net/url"
 	"os"
 	"reflect"
+	"slices"
 	"strconv"
+	"strings"
+	"sync"
+	"text/template"
 	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
+// Delimiter is the default separator used to split values for slice fields.
+// It can be overridden per field with the `delim` struct tag.
+var Delimiter = ","
+
 /*
 Populate a struct with its default values, environment variables, and command line arguments.
 
@@ -58,11 +254,11 @@ Populate a struct with its default values, environment variables, and command li
 `args` is the command line arguments, typically os.Args. args[0] must be the program name. If nil, os.Args is used.
 
 `c` is pointer to the struct to populate.
+
+`opts` are optional settings that change how values are resolved.
 */
-func New[T any](lookupenv func(string) (string, bool), args []string, c *T) (*T, error) {
-	if lookupenv == nil {
-		lookupenv = os.LookupEnv
-	}
+func New[T any](lookupenv func(string) (string, bool), args []string, c *T, opts ...Option) (*T, error) {
+	l := newLoader(lookupenv, opts)
 	if args == nil {
 		args = os.Args
 	}
@@ -76,9 +272,107 @@ func New[T any](lookupenv func(string) (string, bool), args []string, c *T) (*T,
 
 	programName := args[0]
 	args = args[1:]
-	flagset := buildFlagSet(programName, c)
-	if err := flagset.Parse(args); err != nil {
-		return nil, fmt.Errorf("failed to parse command line arguments: %w", err)
+	if l.argFiles {
+		expanded, err := expandArgFiles(args, 0)
+		if err != nil {
+			return nil, err
+		}
+		args = expanded
+	}
+	l.args = args
+	if err := l.loadDotenv(); err != nil {
+		return nil, err
+	}
+	if err := l.openSources(); err != nil {
+		return nil, err
+	}
+	if err := l.load(cValue, programName, args, true); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// target is a struct being populated, along with its fields and subcommands.
+type target struct {
+	v           reflect.Value
+	name        string // Program name, followed by those of the subcommands, if any
+	fields      []field
+	commands    []command
+	positionals positional
+}
+
+// collectTarget collects the fields and subcommands of the struct v. The flags used by
+// options are only added to the root struct.
+func (l *loader) collectTarget(v reflect.Value, name string, root bool) (*target, error) {
+	fields, err := l.collectFields(v, "", prefix{})
+	if err != nil {
+		return nil, err
+	}
+	if root {
+		fields = l.addFileFields(fields)
+	}
+	commands, err := collectCommands(v)
+	if err != nil {
+		return nil, err
+	}
+	positionals, err := collectPositional(fields)
+	if err != nil {
+		return nil, err
+	}
+	if len(commands) > 0 {
+		for _, f := range fields {
+			if f.arg != "" {
+				return nil, fmt.Errorf("field %s takes a positional argument but the struct has subcommands", f.name)
+			}
+		}
+	}
+	return &target{v: v, name: name, fields: fields, commands: commands, positionals: positionals}, nil
+}
+
+// newLoader returns a loader looking up environment variables with lookupenv, or
+// os.LookupEnv if it is nil, with opts applied.
+func newLoader(lookupenv func(string) (string, bool), opts []Option) *loader {
+	if lookupenv == nil {
+		lookupenv = os.LookupEnv
+	}
+	l := &loader{lookupenv: lookupenv, envDelimiter: "_", flagDelimiter: "_", keyDelimiter: "_"}
+	for _, opt := range opts {
+		opt(l)
+	}
+	return l
+}
+
+// load populates the struct v from args and the other layers of values, and then the
+// subcommand selected by args, if any.
+func (l *loader) load(v reflect.Value, name string, args []string, root bool) error {
+	l.args = args
+	t, err := l.collectTarget(v, name, root)
+	if err != nil {
+		return err
+	}
+	flagset, err := l.buildFlagSet(t)
+	if err != nil {
+		return err
+	}
+	remaining, passthrough, err := l.parseArgs(flagset, args, t)
+	if err == flag.ErrHelp {
+		return ErrHelp
+	} else if err != nil {
+		return fmt.Errorf("failed to parse command line arguments: %w", err)
+	}
+	if err := l.printVersion(flagset, t.name); err != nil {
+		return err
+	}
+	return l.resolve(t, flagset, remaining, passthrough)
+}
+
+// resolve sets the fields of t from the parsed flagset, its remaining positional
+// arguments, those after `--`, and the other layers of values, and then loads the
+// subcommand named by the remaining arguments, if any.
+func (l *loader) resolve(t *target, flagset *flag.FlagSet, remaining, passthrough []string) error {
+	fields, positionals, v := t.fields, t.positionals, t.v
+	if err := positionals.check(remaining); err != nil {
+		return err
 	}
 	//The `flag` package doesn't expose its internal formal flag set,
 	//so visiting every flag is the only way to check which ones were set.
@@ -87,9 +381,9 @@ func New[T any](lookupenv func(string) (string, bool), args []string, c *T) (*T,
 		formalFlagSet[f.Name] = f
 	})
 
-	for i := 0; i < cValue.NumField(); i++ {
-		field := cValue.Field(i)
-		tag := cValue.Type().Field(i).Tag
+	var templated, missing []field
+	for _, f := range fields {
+		tag := f.tag
 
 		valueFound := false
 		valueSource := ""
@@ -100,142 +394,783 @@ func New[T any](lookupenv func(string) (string, bool), args []string, c *T) (*T,
 			valueToSet = value
 			valueSource = "default"
 		}
-		if varName, ok := tag.Lookup("env"); ok {
-			if value, ok := lookupenv(varName); ok {
+		if value, name, ok := l.lookupSources(f.key); ok {
+			valueFound = true
+			valueToSet = value
+			valueSource = name
+		}
+		if f.env != "" {
+			value, ok, err := l.lookupEnv(f.env)
+			if err != nil {
+				return fmt.Errorf("failed to read field %s from env: %w", f.name, err)
+			}
+			if ok {
 				valueFound = true
 				valueToSet = value
 				valueSource = "env"
+				l.warnDeprecated(f, "env", f.env)
 			}
-			if value, ok := formalFlagSet[varName]; ok {
+		}
+		if f.flag != "" {
+			var value *flag.Flag
+			for _, name := range []string{f.flag, f.alias, f.short, f.negation} {
+				if value == nil && name != "" {
+					value = formalFlagSet[name]
+				}
+			}
+			if value != nil {
+				prefix := l.longFlag()
+				if len(value.Name) == 1 {
+					prefix = "-"
+				}
+				l.warnDeprecated(f, "flag", prefix+value.Name)
+				fv, ok := value.Value.(*flagValue)
+				if !ok {
+					//The field is itself a flag.Value and was already set while parsing.
+					continue
+				}
+				if len(fv.occurrences) > 1 && isRepeatable(f.value.Type(), tag) {
+					if err := l.setRepeated(f.value, tag, fv.occurrences); err != nil {
+						return fmt.Errorf("failed to set field %s from arglist: %w", f.name, err)
+					}
+					continue
+				}
 				valueFound = true
-				valueToSet = value.Value.String()
+				valueToSet = fv.String()
 				valueSource = "arglist"
+				if f.isCount() {
+					count, err := fv.count()
+					if err != nil {
+						return fmt.Errorf("failed to set field %s from arglist: %w", f.name, err)
+					}
+					valueToSet = count
+				}
+			}
+		}
+		if values, ok := positionals.values(f, remaining, passthrough); ok {
+			if f.arg == argRest || f.arg == argPassthrough {
+				if err := l.setRest(f.value, tag, values); err != nil {
+					return fmt.Errorf("failed to set field %s from arglist: %w", f.name, err)
+				}
+				continue
+			}
+			valueFound = true
+			valueToSet = values[0]
+			valueSource = "arglist"
+		}
+		if valueSource != "env" && valueSource != "arglist" {
+			value, name, ok, err := l.resolveTags(tag)
+			if err != nil {
+				return fmt.Errorf("failed to resolve field %s from %s: %w", f.name, name, err)
+			}
+			if ok {
+				valueFound = true
+				valueToSet = value
+				valueSource = name
 			}
 		}
 
+		if valueSource == "default" && strings.Contains(valueToSet, "{{") {
+			templated = append(templated, f)
+			continue
+		}
 		if valueFound {
-			if err := setFieldValue(field, valueToSet); err != nil {
-				return nil, fmt.Errorf("failed to set field %s to '%s' from %s: %w", field.Type().Name(), valueToSet, valueSource, err)
+			if err := l.setFieldValue(f.value, tag, valueToSet); err != nil {
+				return fmt.Errorf("failed to set field %s to '%s' from %s: %w", f.name, valueToSet, valueSource, err)
 			}
+		} else if f.isRequired() && f.value.IsZero() {
+			//Slices of structs may already be populated from indexed variables.
+			missing = append(missing, f)
 		}
 	}
+	if len(missing) > 0 {
+		return l.missingError(missing)
+	}
 
-	return c, nil
+	//Templated defaults are resolved last so they can reference any other field.
+	for _, f := range templated {
+		def := f.tag.Get("default")
+		value, err := executeTemplate(def, v.Addr().Interface())
+		if err != nil {
+			return fmt.Errorf("failed to resolve default template '%s' for field %s: %w", def, f.name, err)
+		}
+		if err := l.setFieldValue(f.value, f.tag, value); err != nil {
+			return fmt.Errorf("failed to set field %s to '%s' from default: %w", f.name, value, err)
+		}
+	}
+
+	if len(t.commands) > 0 && len(remaining) > 0 {
+		return l.loadCommand(t.commands, t.name, remaining)
+	}
+	if l.remaining != nil {
+		*l.remaining = positionals.leftover(remaining)
+	}
+	return nil
+}
+
+// executeTemplate executes the text/template text with data.
+func executeTemplate(text string, data any) (string, error) {
+	t, err := template.New("default").Option("missingkey=error").Parse(text)
+	if err != nil {
+		return "", err
+	}
+	var b strings.Builder
+	if err := t.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return b.String(), nil
+}
+
+// field is a configurable leaf of the struct passed to New.
+type field struct {
+	value    reflect.Value
+	tag      reflect.StructTag
+	name     string // Go path of the field, e.g. DB.Host
+	env      string // Environment variable name, empty if not set by env
+	flag     string // Command line flag name, empty if not set by flag
+	alias    string // Env style flag name also accepted, e.g. DB_HOST, empty if none
+	short    string // Single character flag name also accepted, e.g. v, empty if none
+	negation string // Flag name setting a bool to false, e.g. no-cache, empty if none
+	arg      string // Index of the positional argument setting the field, or rest
+	group    string // Group the flag is listed in by the help, empty for the main one
+	key      string // Key in file sources, empty if not set by them
+}
+
+// prefix holds the name segments of the structs enclosing a field. Env names and flag
+// aliases are derived from the `names`, flag names from the `flags`, and file keys from
+// the `keys`. The group is the help group of the fields of the innermost struct.
+type prefix struct {
+	names []string
+	flags []string
+	keys  []string
+	group string
 }
 
-func buildFlagSet[T any](name string, c *T) *flag.FlagSet {
-	flagset := flag.NewFlagSet(name, flag.ContinueOnError)
-	v := reflect.ValueOf(c).Elem()
+func (p prefix) with(name, flag, key string) prefix {
+	return prefix{
+		names: append(slices.Clip(p.names), name),
+		flags: append(slices.Clip(p.flags), flag),
+		keys:  append(slices.Clip(p.keys), key),
+		group: p.group,
+	}
+}
+
+/*
+Walk the struct v and return its leaf fields. Nested structs are walked recursively,
+with their `env` tag, or upper cased field name if untagged, prefixing the env names of
+their fields. Flag names are prefixed by their `flag` tag, or kebab cased field name,
+and file keys are derived like env names, except that `json` tags take precedence over
+`env` tags. Untagged embedded structs add no prefix.
+*/
+func (l *loader) collectFields(v reflect.Value, path string, p prefix) ([]field, error) {
+	var fields []field
 	for i := 0; i < v.NumField(); i++ {
-		field := v.Field(i)
-		tag := v.Type().Field(i).Tag
-		if env := tag.Get("env"); env != "" {
-			def := tag.Get("default")
-			switch field.Kind() {
-			case reflect.Bool:
-				v, err := strconv.ParseBool(def)
-				if err != nil {
-					panic(err)
-				}
-				flagset.Bool(env, v, "")
-			case reflect.Float64:
-				v, err := strconv.ParseFloat(def, 64)
-				if err != nil {
-					panic(err)
-				}
-				flagset.Float64(env, v, "")
-			case reflect.Int:
-				v, err := strconv.Atoi(def)
-				if err != nil {
-					panic(err)
-				}
-				flagset.Int(env, v, "")
-			case reflect.Int64:
-				switch field.Interface().(type) {
-				case time.Duration:
-					v, err := time.ParseDuration(def)
-					if err != nil {
-						panic(err)
-					}
-					flagset.Duration(env, v, "")
-				default:
-					v, err := strconv.ParseInt(def, 10, 64)
-					if err != nil {
-						panic(err)
-					}
-					flagset.Int64(env, v, "")
-				}
-			case reflect.String:
-				flagset.String(env, def, "")
-			case reflect.Uint:
-				v, err := strconv.ParseUint(def, 10, 0)
-				if err != nil {
-					panic(err)
-				}
-				flagset.Uint(env, uint(v), "")
-			case reflect.Uint64:
-				v, err := strconv.ParseUint(def, 10, 64)
-				if err != nil {
-					panic(err)
-				}
-				flagset.Uint64(env, v, "")
+		sf := v.Type().Field(i)
+		if !sf.IsExported() && !(sf.Anonymous && sf.Type.Kind() == reflect.Struct) {
+			continue
+		}
+		if _, ok := sf.Tag.Lookup("cmd"); ok {
+			continue
+		}
+		nested := isStructType(sf.Type) && sf.Tag.Get("format") != "json"
+		if nested || hasKinds(sf.Type) {
+			np := p
+			flagName := cmp.Or(sf.Tag.Get("flag"), kebabCase(sf.Name))
+			if env, ok := sf.Tag.Lookup("env"); ok {
+				np = p.with(env, flagName, cmp.Or(jsonName(sf), env))
+			} else if !sf.Anonymous {
+				name := strings.ToUpper(sf.Name)
+				np = p.with(name, flagName, cmp.Or(jsonName(sf), name))
+			}
+			//Fields are grouped by their top level struct, unless a `group` tag says otherwise.
+			np.group = cmp.Or(sf.Tag.Get("group"), p.group)
+			if np.group == "" && !sf.Anonymous {
+				np.group = sf.Name
+			}
+			var nestedFields []field
+			var err error
+			if nested {
+				nestedFields, err = l.collectFields(v.Field(i), path+sf.Name+".", np)
+			} else {
+				nestedFields, err = l.collectKindFields(v.Field(i), sf, path+sf.Name, np)
+			}
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, nestedFields...)
+			continue
+		}
+		f := field{value: v.Field(i), tag: sf.Tag, name: path + sf.Name, group: cmp.Or(sf.Tag.Get("group"), p.group)}
+		env, key := sf.Tag.Get("env"), cmp.Or(jsonName(sf), sf.Tag.Get("env"))
+		flagName, hasFlag := sf.Tag.Lookup("flag")
+		short, hasShort := sf.Tag.Lookup("short")
+		fp := p.with(env, cmp.Or(flagName, kebabCase(sf.Name)), key)
+		fp.group = f.group
+		names := l.names(fp)
+		if env != "" {
+			f.env, f.alias = names.env, names.alias
+		}
+		if (env != "" || hasFlag || hasShort) && flagName != "-" {
+			f.flag = names.flag
+			if hasShort && (utf8.RuneCountInString(short) != 1 || short == "-") {
+				return nil, fmt.Errorf("field %s has invalid short flag '%s', expected a single character", f.name, short)
+			}
+			f.short = short
+			f.negation = negationFlag(f)
+		}
+		if arg, ok := sf.Tag.Lookup("arg"); ok {
+			f.arg, f.flag, f.alias, f.short, f.negation = arg, "", "", "", ""
+		}
+		if sf.Tag.Get("passthrough") == "true" {
+			f.arg, f.flag, f.alias, f.short, f.negation = argPassthrough, "", "", "", ""
+		}
+		if f.isCount() && !slices.Contains([]reflect.Kind{reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64}, sf.Type.Kind()) {
+			return nil, fmt.Errorf("field %s has a count tag but is not an integer", f.name)
+		}
+		if f.alias == f.flag || flagName == "-" {
+			f.alias = ""
+		}
+		if key != "" {
+			f.key = names.key
+		}
+		fields = append(fields, f)
+		if f.env != "" && isStructSlice(sf.Type) {
+			indexed, err := l.collectIndexedFields(f, fp)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, indexed...)
+		}
+	}
+	return fields, nil
+}
+
+// fieldNames are the names a field is set by.
+type fieldNames struct {
+	env, flag, alias, key string
+}
+
+// names returns the names of a field with the prefix p. Flag names are joined with `-`,
+// and their env style aliases with the flag delimiter.
+func (l *loader) names(p prefix) fieldNames {
+	return fieldNames{
+		env:   strings.Join(p.names, l.envDelimiter),
+		flag:  strings.Join(p.flags, "-"),
+		alias: strings.Join(p.names, l.flagDelimiter),
+		key:   strings.Join(p.keys, l.keyDelimiter),
+	}
+}
+
+// kebabCase converts a Go field name to a flag name, e.g. `HTTPHost` to `http-host`.
+// A new word starts at an upper case letter that follows a lower case letter or digit,
+// or that is followed by a lower case letter, as the last letter of an initialism.
+func kebabCase(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if unicode.IsUpper(r) && i > 0 {
+			prev, _ := utf8.DecodeLastRuneInString(name[:i])
+			next, _ := utf8.DecodeRuneInString(name[i+utf8.RuneLen(r):])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || unicode.IsUpper(prev) && unicode.IsLower(next) {
+				b.WriteByte('-')
 			}
 		}
+		if r == '_' {
+			r = '-'
+		}
+		b.WriteRune(unicode.ToLower(r))
+	}
+	return b.String()
+}
+
+// jsonName returns the name of the struct field from its `json` tag, if any.
+func jsonName(sf reflect.StructField) string {
+	name, _, _ := strings.Cut(sf.Tag.Get("json"), ",")
+	if name == "-" {
+		return ""
+	}
+	return name
+}
+
+var (
+	parsersMu sync.RWMutex
+	parsers   = map[reflect.Type]func(string) (reflect.Value, error){}
+)
+
+/*
+Register a function that New uses to parse values of type T. A registered parser takes
+precedence over the built in parsing for T, and is also used for slice elements and
+pointers of type T.
+
+	config.RegisterParser(func(s string) (MyType, error) { ... })
+*/
+func RegisterParser[T any](parse func(string) (T, error)) {
+	parsersMu.Lock()
+	defer parsersMu.Unlock()
+	parsers[reflect.TypeFor[T]()] = func(s string) (reflect.Value, error) {
+		v, err := parse(s)
+		return reflect.ValueOf(&v).Elem(), err
+	}
+}
+
+func lookupParser(t reflect.Type) func(string) (reflect.Value, error) {
+	parsersMu.RLock()
+	defer parsersMu.RUnlock()
+	return parsers[t]
+}
+
+// isValueType reports whether the struct type t is set from a single value rather than
+// walked as a nested struct.
+func isValueType(t reflect.Type) bool {
+	return lookupParser(t) != nil || isNullType(t) ||
+		t == reflect.TypeOf(url.URL{}) || t == reflect.TypeOf(net.IPNet{}) || t == reflect.TypeOf(mail.Address{}) ||
+		reflect.PointerTo(t).Implements(reflect.TypeFor[flag.Value]()) ||
+		reflect.PointerTo(t).Implements(reflect.TypeFor[encoding.TextUnmarshaler]()) ||
+		reflect.PointerTo(t).Implements(reflect.TypeFor[encoding.BinaryUnmarshaler]())
+}
+
+// isNullType reports whether t is one of the database/sql Null types, such as
+// sql.NullString or sql.Null[T], which hold a value followed by a Valid field.
+func isNullType(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t.NumField() == 2 && t.Field(1).Name == "Valid" &&
+		reflect.PointerTo(t).Implements(reflect.TypeFor[sql.Scanner]())
+}
+
+// isBoolType reports whether t is a bool, or a pointer to or null type of one, which is
+// set by a flag without a value.
+func isBoolType(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if isNullType(t) {
+		t = t.Field(0).Type
+	}
+	return t.Kind() == reflect.Bool
+}
+
+// isStructType reports whether t is a struct type that is walked as a nested struct.
+func isStructType(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && !isValueType(t)
+}
+
+// flagValue is a flag.Value that records the raw command line value, which is then
+// parsed by setFieldValue the same way as environment variables and defaults. The
+// value of a `-no-` flag negates the bool flag it sets instead. Every value set is kept
+// in occurrences, so repeated flags of slices can be appended.
+type flagValue struct {
+	value       string
+	occurrences []string
+	isBool      bool
+	negates     *flagValue
+}
+
+func (v *flagValue) String() string {
+	if v.negates != nil {
+		return v.negates.value
+	}
+	return v.value
+}
+
+func (v *flagValue) Set(s string) error {
+	if v.negates == nil {
+		v.value = s
+		v.occurrences = append(v.occurrences, s)
+		return nil
+	}
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+	v.negates.value = strconv.FormatBool(!b)
+	return nil
+}
+
+func (v *flagValue) IsBoolFlag() bool { return v.isBool }
+
+// count returns the number of times a count flag was given, e.g. 3 for `-v -v -v`.
+// Values given with the flag set the count instead, e.g. `-v=2`, or `-v=false` for 0.
+func (v *flagValue) count() (string, error) {
+	n := 0
+	for _, s := range v.occurrences {
+		if i, err := strconv.Atoi(s); err == nil {
+			n = i
+		} else if b, err := strconv.ParseBool(s); err == nil && b {
+			n++
+		} else if err == nil {
+			n = 0
+		} else {
+			return "", fmt.Errorf("invalid count '%s'", s)
+		}
+	}
+	return strconv.Itoa(n), nil
+}
+
+// isCount reports whether the field has a `count:"true"` tag.
+func (f field) isCount() bool {
+	return f.tag.Get("count") == "true"
+}
+
+// negationFlag returns the name of the `-no-` flag of a bool field that defaults to true,
+// e.g. `no-cache` for `-cache`, or an empty string if it has none.
+func negationFlag(f field) string {
+	if _, ok := f.value.Addr().Interface().(flag.Value); ok || f.flag == "" || !isBoolType(f.value.Type()) {
+		return ""
+	}
+	if on, err := strconv.ParseBool(f.tag.Get("default")); err != nil || !on {
+		return ""
+	}
+	return "no-" + f.flag
+}
+
+// buildFlagSet returns a flag set with the flags of t, which prints the help listing.
+func (l *loader) buildFlagSet(t *target) (*flag.FlagSet, error) {
+	flagset := flag.NewFlagSet(t.name, flag.ContinueOnError)
+	if l.output != nil {
+		flagset.SetOutput(l.output)
+	}
+	flagset.Usage = func() { l.writeUsage(flagset.Output(), t.name, t.fields, t.commands) }
+	if err := registerFlags(flagset, t.fields); err != nil {
+		return nil, err
+	}
+	l.addVersionFlag(flagset)
+	return flagset, nil
+}
+
+// registerFlags defines the flags of fields on flagset. Their names must be unique, and
+// not be defined already, except for aliases, which are left out instead.
+func registerFlags(flagset *flag.FlagSet, fields []field) error {
+	owners := map[string]string{}
+	claim := func(f field, name string) error {
+		if flagset.Lookup(name) != nil && owners[name] == "" {
+			return fmt.Errorf("field %s has the flag name '%s', which is already defined", f.name, name)
+		}
+		if owner, ok := owners[name]; ok {
+			return fmt.Errorf("fields %s and %s have the same flag name '%s'", owner, f.name, name)
+		}
+		owners[name] = f.name
+		return nil
+	}
+	values := make([]flag.Value, len(fields))
+	for i, f := range fields {
+		if f.flag == "" {
+			continue
+		}
+		if err := claim(f, f.flag); err != nil {
+			return err
+		}
+		if v, ok := f.value.Addr().Interface().(flag.Value); ok {
+			values[i] = v
+		} else {
+			values[i] = &flagValue{value: f.tag.Get("default"), isBool: isBoolType(f.value.Type()) || f.isCount()}
+		}
+		flagset.Var(values[i], f.flag, f.usage())
+	}
+	//Short flags share the value of their flag, and must not clash with any other flag.
+	for i, f := range fields {
+		if f.short == "" {
+			continue
+		}
+		if err := claim(f, f.short); err != nil {
+			return err
+		}
+		flagset.Var(values[i], f.short, "")
+	}
+	//Negation flags set the opposite value of their flag, and must not clash either.
+	for i, f := range fields {
+		if f.negation == "" {
+			continue
+		}
+		if err := claim(f, f.negation); err != nil {
+			return err
+		}
+		flagset.Var(&flagValue{isBool: true, negates: values[i].(*flagValue)}, f.negation, "")
+	}
+	//Aliases share the value of their flag, and are left out if another flag has the name.
+	for i, f := range fields {
+		if f.flag == "" || f.alias == "" || flagset.Lookup(f.alias) != nil {
+			continue
+		}
+		owners[f.alias] = f.name
+		flagset.Var(values[i], f.alias, "")
 	}
-	return flagset
+	return nil
 }
 
-func setFieldValue(field reflect.Value, val string) error {
+func (l *loader) setFieldValue(field reflect.Value, tag reflect.StructTag, val string) error {
+	if parse := lookupParser(field.Type()); parse != nil {
+		v, err := parse(val)
+		if err != nil {
+			return err
+		}
+		field.Set(v)
+		return nil
+	}
+	if tag.Get("format") == "json" {
+		return json.Unmarshal([]byte(val), field.Addr().Interface())
+	}
+	if tag.Get("bytes") == "true" {
+		switch field.Kind() {
+		case reflect.Pointer, reflect.Slice, reflect.Map:
+			//The byte size is parsed for the value pointed to or each element.
+		default:
+			return setByteSize(field, val)
+		}
+	}
+	switch field.Interface().(type) {
+	case json.RawMessage:
+		if !json.Valid([]byte(val)) {
+			return fmt.Errorf("invalid JSON '%s'", val)
+		}
+		field.SetBytes([]byte(val))
+		return nil
+	case Path:
+		p, err := l.expandPath(val)
+		if err != nil {
+			return err
+		}
+		field.SetString(string(p))
+		return nil
+	case url.URL:
+		u, err := url.Parse(val)
+		if err != nil {
+			return err
+		}
+		if !u.IsAbs() {
+			return fmt.Errorf("url '%s' is not absolute", val)
+		}
+		field.Set(reflect.ValueOf(*u))
+		return nil
+	case big.Int:
+		if _, ok := field.Addr().Interface().(*big.Int).SetString(val, 0); !ok {
+			return fmt.Errorf("invalid integer '%s'", val)
+		}
+		return nil
+	case big.Float:
+		var prec uint64
+		if p, ok := tag.Lookup("prec"); ok {
+			var err error
+			if prec, err = strconv.ParseUint(p, 10, 32); err != nil {
+				return fmt.Errorf("invalid prec tag '%s': %w", p, err)
+			}
+		}
+		f, _, err := big.ParseFloat(val, 0, uint(prec), big.ToNearestEven)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(f).Elem())
+		return nil
+	case Decimal:
+		d, err := parseDecimalTag(val, tag.Get("scale"))
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(d))
+		return nil
+	case slog.Level:
+		if n, err := strconv.Atoi(val); err == nil {
+			field.SetInt(int64(n))
+			return nil
+		}
+		return field.Addr().Interface().(*slog.Level).UnmarshalText([]byte(val))
+	case mail.Address:
+		a, err := mail.ParseAddress(val)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(*a))
+		return nil
+	case net.IP:
+		ip := net.ParseIP(val)
+		if ip == nil {
+			return fmt.Errorf("invalid IP address '%s'", val)
+		}
+		field.Set(reflect.ValueOf(ip))
+		return nil
+	case net.HardwareAddr:
+		mac, err := net.ParseMAC(val)
+		if err != nil {
+			return err
+		}
+		field.SetBytes(mac)
+		return nil
+	case net.IPNet:
+		_, ipnet, err := net.ParseCIDR(val)
+		if err != nil {
+			return err
+		}
+		field.Set(reflect.ValueOf(*ipnet))
+		return nil
+	}
+	if isNullType(field.Type()) {
+		if err := l.setFieldValue(field.Field(0), tag, val); err != nil {
+			return err
+		}
+		field.Field(1).SetBool(true)
+		return nil
+	}
+	if field.CanAddr() {
+		if v, ok := field.Addr().Interface().(flag.Value); ok {
+			return v.Set(val)
+		}
+		if u, ok := field.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			return u.UnmarshalText([]byte(val))
+		}
+		if u, ok := field.Addr().Interface().(encoding.BinaryUnmarshaler); ok {
+			enc := tag.Get("encoding")
+			if enc == "" {
+				enc = "base64"
+			}
+			b, err := decodeBytes(enc, val)
+			if err != nil {
+				return err
+			}
+			return u.UnmarshalBinary(b)
+		}
+	}
 	switch field.Kind() {
 	case reflect.Bool:
 		v, err := strconv.ParseBool(val)
 		if err != nil {
-			panic(err)
+			return err
 		}
 		field.SetBool(v)
-	case reflect.Float64:
-		v, err := strconv.ParseFloat(val, 64)
+	case reflect.Float32, reflect.Float64:
+		percent := tag.Get("percent") == "true" && strings.HasSuffix(val, "%")
+		if percent {
+			val = strings.TrimSuffix(val, "%")
+		}
+		v, err := strconv.ParseFloat(val, field.Type().Bits())
 		if err != nil {
-			panic(err)
+			return err
+		}
+		if percent {
+			v /= 100
 		}
 		field.SetFloat(v)
+	case reflect.Complex64, reflect.Complex128:
+		v, err := strconv.ParseComplex(val, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetComplex(v)
 	case reflect.Int:
 		v, err := strconv.Atoi(val)
 		if err != nil {
-			panic(err)
+			return err
 		}
 		field.SetInt(int64(v))
+	case reflect.Int8, reflect.Int16, reflect.Int32:
+		v, err := strconv.ParseInt(val, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(v)
 	case reflect.Int64:
 		switch field.Interface().(type) {
 		case time.Duration:
-			v, err := time.ParseDuration(val)
+			v, err := parseDuration(val)
 			if err != nil {
-				panic(err)
+				return err
 			}
 			field.SetInt(int64(v))
 		default:
 			v, err := strconv.ParseInt(val, 10, 64)
 			if err != nil {
-				panic(err)
+				return err
 			}
 			field.SetInt(v)
 		}
 	case reflect.String:
+		if oneof, ok := tag.Lookup("oneof"); ok && !slices.Contains(strings.Split(oneof, ","), val) {
+			return fmt.Errorf("'%s' is not one of %s", val, oneof)
+		}
 		field.SetString(val)
 	case reflect.Uint:
 		v, err := strconv.ParseUint(val, 10, 0)
 		if err != nil {
-			panic(err)
+			return err
+		}
+		field.SetUint(v)
+	case reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		v, err := strconv.ParseUint(val, 10, field.Type().Bits())
+		if err != nil {
+			return err
 		}
 		field.SetUint(v)
 	case reflect.Uint64:
 		v, err := strconv.ParseUint(val, 10, 64)
 		if err != nil {
-			panic(err)
+			return err
 		}
 		field.SetUint(v)
+	case reflect.Slice:
+		if field.Type().Elem().Kind() == reflect.Uint8 {
+			b, err := decodeBytes(tag.Get("encoding"), val)
+			if err != nil {
+				return err
+			}
+			field.SetBytes(b)
+			return nil
+		}
+		if isStructSlice(field.Type()) {
+			return json.Unmarshal([]byte(val), field.Addr().Interface())
+		}
+		parts, err := splitList(val, tag)
+		if err != nil {
+			return err
+		}
+		if tag.Get("ranges") == "true" {
+			if parts, err = expandRanges(parts); err != nil {
+				return err
+			}
+		}
+		slice := reflect.MakeSlice(field.Type(), len(parts), len(parts))
+		for i, part := range parts {
+			if err := l.setFieldValue(slice.Index(i), tag, part); err != nil {
+				return fmt.Errorf("element %d: %w", i, err)
+			}
+		}
+		field.Set(slice)
+	case reflect.Map:
+		pairs, err := splitPairs(val, tag)
+		if err != nil {
+			return err
+		}
+		m := reflect.MakeMap(field.Type())
+		for _, pair := range pairs {
+			k, v := pair[0], pair[1]
+			key := reflect.New(field.Type().Key()).Elem()
+			if err := l.setFieldValue(key, "", k); err != nil {
+				return fmt.Errorf("key '%s': %w", k, err)
+			}
+			value := reflect.New(field.Type().Elem()).Elem()
+			if err := l.setFieldValue(value, tag, v); err != nil {
+				return fmt.Errorf("value for key '%s': %w", k, err)
+			}
+			m.SetMapIndex(key, value)
+		}
+		field.Set(m)
+	case reflect.Pointer:
+		v := reflect.New(field.Type().Elem())
+		if err := l.setFieldValue(v.Elem(), tag, val); err != nil {
+			return err
+		}
+		field.Set(v)
 	default:
-		return fmt.Errorf("unsupported type %s", field.Kind())
+		return fmt.Errorf("unsupported type %s", field.Type())
 	}
 	return nil
 }
+
+// decodeBytes decodes val according to the `encoding` struct tag.
+func decodeBytes(enc string, val string) ([]byte, error) {
+	switch enc {
+	case "", "raw":
+		return []byte(val), nil
+	case "base64":
+		return base64.StdEncoding.DecodeString(val)
+	case "hex":
+		return hex.DecodeString(val)
+	default:
+		return nil, fmt.Errorf("unknown encoding '%s'", enc)
+	}
+}
@@ -1,7 +1,17 @@
 package config
 
 import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"math/big"
+	"net"
+	"net/mail"
+	"net/url"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -20,13 +30,18 @@ type TestStruct struct {
 	NoStructTag string
 }
 
-func TestNew(t *testing.T) {
-	makeLookup := func(m map[string]string) func(string) (string, bool) {
-		return func(key string) (string, bool) {
-			v, ok := m[key]
-			return v, ok
-		}
+func makeLookup(m map[string]string) func(string) (string, bool) {
+	return func(key string) (string, bool) {
+		v, ok := m[key]
+		return v, ok
 	}
+}
+
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestNew(t *testing.T) {
 	type args struct {
 		lookupenv func(string) (string, bool)
 		args      []string
@@ -58,3 +73,1131 @@ func TestNew(t *testing.T) {
 		})
 	}
 }
+
+type Upstream struct {
+	Host string `json:"host"`
+	Port int
+}
+
+type SliceStruct struct {
+	Upstreams []Upstream      `env:"UPSTREAMS"`
+	Backups   []*Upstream     `env:"BACKUPS"`
+	Strings   []string        `env:"STRINGS" default:"a,b,c"`
+	Ints      []int           `env:"INTS" delim:";" default:"1;2;3"`
+	Durations []time.Duration `env:"DURATIONS"`
+	Empty     []string        `env:"EMPTY" default:""`
+}
+
+func TestNewSlices(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		args    []string
+		want    *SliceStruct
+		wantErr bool
+	}{
+		{name: "Defaults", env: map[string]string{}, args: []string{"ConfigTestApp"}, want: &SliceStruct{Strings: []string{"a", "b", "c"}, Ints: []int{1, 2, 3}, Empty: []string{}}},
+		{name: "SetEnv", env: map[string]string{"STRINGS": "x,y"}, args: []string{"ConfigTestApp"}, want: &SliceStruct{Strings: []string{"x", "y"}, Ints: []int{1, 2, 3}, Empty: []string{}}},
+		{name: "SetArg", env: map[string]string{}, args: []string{"ConfigTestApp", "-INTS=4;5", "-DURATIONS=1s,2m"}, want: &SliceStruct{Strings: []string{"a", "b", "c"}, Ints: []int{4, 5}, Durations: []time.Duration{time.Second, 2 * time.Minute}, Empty: []string{}}},
+		{name: "Structs", env: map[string]string{"UPSTREAMS": `[{"host":"a","port":80},{"host":"b"}]`, "BACKUPS": `[{"host":"c"}]`}, args: []string{"ConfigTestApp"}, want: &SliceStruct{Upstreams: []Upstream{{Host: "a", Port: 80}, {Host: "b"}}, Backups: []*Upstream{{Host: "c"}}, Strings: []string{"a", "b", "c"}, Ints: []int{1, 2, 3}, Empty: []string{}}},
+		{name: "InvalidElement", env: map[string]string{"INTS": "1;x"}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+		{name: "InvalidDuration", env: map[string]string{"DURATIONS": "1s,2"}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+		{name: "InvalidStructs", env: map[string]string{"UPSTREAMS": `{"host":"a"}`}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(makeLookup(tt.env), tt.args, &SliceStruct{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type MapStruct struct {
+	Labels     map[string]string        `env:"LABELS" default:"env=prod,team=core"`
+	Headers    map[string]string        `env:"HEADERS" delim:";"`
+	RateLimits map[string]int           `env:"RATE_LIMITS"`
+	Timeouts   map[string]time.Duration `env:"TIMEOUTS"`
+	Weights    map[int]float64          `env:"WEIGHTS"`
+}
+
+func TestNewMaps(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		args    []string
+		want    *MapStruct
+		wantErr bool
+	}{
+		{name: "Defaults", env: map[string]string{}, args: []string{"ConfigTestApp"}, want: &MapStruct{Labels: map[string]string{"env": "prod", "team": "core"}}},
+		{name: "SetEnv", env: map[string]string{"HEADERS": "Accept=text/plain;X-Query=a=b"}, args: []string{"ConfigTestApp"}, want: &MapStruct{Labels: map[string]string{"env": "prod", "team": "core"}, Headers: map[string]string{"Accept": "text/plain", "X-Query": "a=b"}}},
+		{name: "SetArg", env: map[string]string{}, args: []string{"ConfigTestApp", "-LABELS=env=dev"}, want: &MapStruct{Labels: map[string]string{"env": "dev"}}},
+		{name: "TypedValues", env: map[string]string{"RATE_LIMITS": "read=100,write=20", "TIMEOUTS": "read=1s", "WEIGHTS": "1=0.5,2=1.5"}, args: []string{"ConfigTestApp"}, want: &MapStruct{Labels: map[string]string{"env": "prod", "team": "core"}, RateLimits: map[string]int{"read": 100, "write": 20}, Timeouts: map[string]time.Duration{"read": time.Second}, Weights: map[int]float64{1: 0.5, 2: 1.5}}},
+		{name: "Escaped", env: map[string]string{"LABELS": `a\=b=c\,d,"e=f"=",g",h="i\"j"`}, args: []string{"ConfigTestApp"}, want: &MapStruct{Labels: map[string]string{"a=b": "c,d", "e=f": ",g", "h": `i"j`}}},
+		{name: "EmptyValue", env: map[string]string{"LABELS": `a=,b=""`}, args: []string{"ConfigTestApp"}, want: &MapStruct{Labels: map[string]string{"a": "", "b": ""}}},
+		{name: "MissingSeparator", env: map[string]string{"LABELS": "env"}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+		{name: "TrailingBackslash", env: map[string]string{"LABELS": `a=b\`}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+		{name: "UnterminatedQuote", env: map[string]string{"LABELS": `a="b`}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+		{name: "InvalidValue", env: map[string]string{"RATE_LIMITS": "read=fast"}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+		{name: "InvalidKey", env: map[string]string{"WEIGHTS": "one=1"}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(makeLookup(tt.env), tt.args, &MapStruct{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type NestedStruct struct {
+	Name string `env:"NAME" default:"app"`
+	DB   struct {
+		Host string `env:"HOST" default:"localhost"`
+		Port int    `env:"PORT" default:"5432"`
+	}
+	Cache struct {
+		TTL   time.Duration `env:"TTL" default:"1m"`
+		Inner struct {
+			Size int `env:"SIZE" default:"1"`
+		} `env:"IN"`
+	} `env:"C"`
+}
+
+func TestNewNested(t *testing.T) {
+	want := func(f func(*NestedStruct)) *NestedStruct {
+		c := &NestedStruct{Name: "app"}
+		c.DB.Host = "localhost"
+		c.DB.Port = 5432
+		c.Cache.TTL = time.Minute
+		c.Cache.Inner.Size = 1
+		f(c)
+		return c
+	}
+	tests := []struct {
+		name    string
+		env     map[string]string
+		args    []string
+		opts    []Option
+		want    *NestedStruct
+		wantErr bool
+	}{
+		{name: "Defaults", env: map[string]string{}, args: []string{"ConfigTestApp"}, want: want(func(c *NestedStruct) {})},
+		{name: "SetEnv", env: map[string]string{"DB_HOST": "db.internal", "C_IN_SIZE": "5"}, args: []string{"ConfigTestApp"}, want: want(func(c *NestedStruct) { c.DB.Host = "db.internal"; c.Cache.Inner.Size = 5 })},
+		{name: "SetArg", env: map[string]string{}, args: []string{"ConfigTestApp", "-DB_PORT=1234", "-C_TTL=5s"}, want: want(func(c *NestedStruct) { c.DB.Port = 1234; c.Cache.TTL = 5 * time.Second })},
+		{name: "UnprefixedName", env: map[string]string{}, args: []string{"ConfigTestApp", "-HOST=x"}, want: nil, wantErr: true},
+		{name: "Delimiters", env: map[string]string{"DB__HOST": "db.internal"}, args: []string{"ConfigTestApp", "-C.IN.SIZE=3"}, opts: []Option{WithEnvDelimiter("__"), WithFlagDelimiter(".")}, want: want(func(c *NestedStruct) { c.DB.Host = "db.internal"; c.Cache.Inner.Size = 3 })},
+		{name: "DelimitedFlagOnly", env: map[string]string{}, args: []string{"ConfigTestApp", "-DB_PORT=1"}, opts: []Option{WithFlagDelimiter("-")}, want: nil, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(makeLookup(tt.env), tt.args, &NestedStruct{}, tt.opts...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type Common struct {
+	LogLevel string `env:"LOG_LEVEL" default:"info"`
+}
+
+type common struct {
+	Region string `env:"REGION" default:"us"`
+}
+
+type EmbeddedStruct struct {
+	Common
+	common
+	Name   string `env:"NAME" default:"app"`
+	Shared Common `env:"SHARED"`
+}
+
+func TestNewEmbedded(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		args    []string
+		want    *EmbeddedStruct
+		wantErr bool
+	}{
+		{name: "Defaults", env: map[string]string{}, args: []string{"ConfigTestApp"}, want: &EmbeddedStruct{Common: Common{LogLevel: "info"}, common: common{Region: "us"}, Name: "app", Shared: Common{LogLevel: "info"}}},
+		{name: "SetEnv", env: map[string]string{"LOG_LEVEL": "debug", "REGION": "eu"}, args: []string{"ConfigTestApp"}, want: &EmbeddedStruct{Common: Common{LogLevel: "debug"}, common: common{Region: "eu"}, Name: "app", Shared: Common{LogLevel: "info"}}},
+		{name: "SetArg", env: map[string]string{}, args: []string{"ConfigTestApp", "-SHARED_LOG_LEVEL=warn"}, want: &EmbeddedStruct{Common: Common{LogLevel: "info"}, common: common{Region: "us"}, Name: "app", Shared: Common{LogLevel: "warn"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(makeLookup(tt.env), tt.args, &EmbeddedStruct{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type PointerStruct struct {
+	Int      *int           `env:"INT"`
+	Bool     *bool          `env:"BOOL"`
+	Duration *time.Duration `env:"DURATION" default:"1s"`
+}
+
+func TestNewPointers(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		args    []string
+		want    *PointerStruct
+		wantErr bool
+	}{
+		{name: "Unset", env: map[string]string{}, args: []string{"ConfigTestApp"}, want: &PointerStruct{Duration: ptr(time.Second)}},
+		{name: "SetEnvZero", env: map[string]string{"INT": "0", "BOOL": "false"}, args: []string{"ConfigTestApp"}, want: &PointerStruct{Int: ptr(0), Bool: ptr(false), Duration: ptr(time.Second)}},
+		{name: "SetArg", env: map[string]string{}, args: []string{"ConfigTestApp", "-BOOL", "-INT=3"}, want: &PointerStruct{Int: ptr(3), Bool: ptr(true), Duration: ptr(time.Second)}},
+		{name: "Invalid", env: map[string]string{"INT": "x"}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(makeLookup(tt.env), tt.args, &PointerStruct{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type URLStruct struct {
+	Endpoint url.URL  `env:"ENDPOINT" default:"https://api.example.com:8443/v1"`
+	Proxy    *url.URL `env:"PROXY"`
+}
+
+func TestNewURL(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		args    []string
+		want    *URLStruct
+		wantErr bool
+	}{
+		{name: "Defaults", env: map[string]string{}, args: []string{"ConfigTestApp"}, want: &URLStruct{Endpoint: url.URL{Scheme: "https", Host: "api.example.com:8443", Path: "/v1"}}},
+		{name: "SetArg", env: map[string]string{}, args: []string{"ConfigTestApp", "-PROXY=http://proxy:3128"}, want: &URLStruct{Endpoint: url.URL{Scheme: "https", Host: "api.example.com:8443", Path: "/v1"}, Proxy: &url.URL{Scheme: "http", Host: "proxy:3128"}}},
+		{name: "Relative", env: map[string]string{"ENDPOINT": "api.example.com/v1"}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+		{name: "Invalid", env: map[string]string{"PROXY": "http://[::1"}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(makeLookup(tt.env), tt.args, &URLStruct{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type IPStruct struct {
+	BindAddr  net.IP           `env:"BIND_ADDR" default:"0.0.0.0"`
+	AllowCIDR net.IPNet        `env:"ALLOW_CIDR" default:"10.0.0.0/8"`
+	Peers     []net.IP         `env:"PEERS"`
+	MAC       net.HardwareAddr `env:"MAC"`
+}
+
+func TestNewIP(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		args    []string
+		want    *IPStruct
+		wantErr bool
+	}{
+		{name: "Defaults", env: map[string]string{}, args: []string{"ConfigTestApp"}, want: &IPStruct{BindAddr: net.ParseIP("0.0.0.0"), AllowCIDR: net.IPNet{IP: net.IP{10, 0, 0, 0}, Mask: net.CIDRMask(8, 32)}}},
+		{name: "SetArg", env: map[string]string{}, args: []string{"ConfigTestApp", "-BIND_ADDR=::1", "-PEERS=10.0.0.1,10.0.0.2"}, want: &IPStruct{BindAddr: net.ParseIP("::1"), AllowCIDR: net.IPNet{IP: net.IP{10, 0, 0, 0}, Mask: net.CIDRMask(8, 32)}, Peers: []net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("10.0.0.2")}}},
+		{name: "InvalidIP", env: map[string]string{"BIND_ADDR": "localhost"}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+		{name: "InvalidCIDR", env: map[string]string{"ALLOW_CIDR": "10.0.0.0"}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+		{name: "MAC", env: map[string]string{"MAC": "00:00:5e:00:53:01"}, args: []string{"ConfigTestApp"}, want: &IPStruct{BindAddr: net.ParseIP("0.0.0.0"), AllowCIDR: net.IPNet{IP: net.IP{10, 0, 0, 0}, Mask: net.CIDRMask(8, 32)}, MAC: net.HardwareAddr{0, 0, 0x5e, 0, 0x53, 1}}},
+		{name: "InvalidMAC", env: map[string]string{"MAC": "00:00:5e"}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(makeLookup(tt.env), tt.args, &IPStruct{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type Level int
+
+func (l *Level) UnmarshalText(text []byte) error {
+	switch string(text) {
+	case "low":
+		*l = 1
+	case "high":
+		*l = 2
+	default:
+		return fmt.Errorf("unknown level '%s'", text)
+	}
+	return nil
+}
+
+type TextStruct struct {
+	Level  Level      `env:"LEVEL" default:"low"`
+	Levels []Level    `env:"LEVELS"`
+	Since  time.Time  `env:"SINCE" default:"2024-01-02T03:04:05Z"`
+	Until  *time.Time `env:"UNTIL"`
+}
+
+func TestNewTextUnmarshaler(t *testing.T) {
+	since := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name    string
+		env     map[string]string
+		args    []string
+		want    *TextStruct
+		wantErr bool
+	}{
+		{name: "Defaults", env: map[string]string{}, args: []string{"ConfigTestApp"}, want: &TextStruct{Level: 1, Since: since}},
+		{name: "SetArg", env: map[string]string{}, args: []string{"ConfigTestApp", "-LEVELS=high,low", "-UNTIL=2024-01-02T03:04:05Z"}, want: &TextStruct{Level: 1, Levels: []Level{2, 1}, Since: since, Until: &since}},
+		{name: "Invalid", env: map[string]string{"LEVEL": "1"}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(makeLookup(tt.env), tt.args, &TextStruct{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type listValue []string
+
+func (l *listValue) String() string     { return strings.Join(*l, ",") }
+func (l *listValue) Set(s string) error { *l = append(*l, s); return nil }
+
+type FlagValueStruct struct {
+	Tags  listValue `env:"TAGS" default:"a"`
+	Other listValue `env:"OTHER"`
+}
+
+func TestNewFlagValue(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		args    []string
+		want    *FlagValueStruct
+		wantErr bool
+	}{
+		{name: "Defaults", env: map[string]string{}, args: []string{"ConfigTestApp"}, want: &FlagValueStruct{Tags: listValue{"a"}}},
+		{name: "SetEnv", env: map[string]string{"OTHER": "x"}, args: []string{"ConfigTestApp"}, want: &FlagValueStruct{Tags: listValue{"a"}, Other: listValue{"x"}}},
+		{name: "SetArg", env: map[string]string{"TAGS": "ignored"}, args: []string{"ConfigTestApp", "-TAGS=b", "-TAGS=c"}, want: &FlagValueStruct{Tags: listValue{"b", "c"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(makeLookup(tt.env), tt.args, &FlagValueStruct{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type Point struct{ X, Y int }
+
+type ParserStruct struct {
+	Origin Point   `env:"ORIGIN" default:"0:0"`
+	Path   []Point `env:"PATH"`
+}
+
+func TestRegisterParser(t *testing.T) {
+	RegisterParser(func(s string) (Point, error) {
+		var p Point
+		_, err := fmt.Sscanf(s, "%d:%d", &p.X, &p.Y)
+		return p, err
+	})
+	tests := []struct {
+		name    string
+		env     map[string]string
+		args    []string
+		want    *ParserStruct
+		wantErr bool
+	}{
+		{name: "Defaults", env: map[string]string{}, args: []string{"ConfigTestApp"}, want: &ParserStruct{}},
+		{name: "SetArg", env: map[string]string{}, args: []string{"ConfigTestApp", "-ORIGIN=1:2", "-PATH=3:4,5:6"}, want: &ParserStruct{Origin: Point{1, 2}, Path: []Point{{3, 4}, {5, 6}}}},
+		{name: "Invalid", env: map[string]string{"ORIGIN": "1"}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(makeLookup(tt.env), tt.args, &ParserStruct{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type SizedIntStruct struct {
+	Int8  int8  `env:"INT8" default:"-128"`
+	Int16 int16 `env:"INT16" default:"32767"`
+	Int32 int32 `env:"INT32" default:"-2147483648"`
+}
+
+func TestNewSizedInts(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		args    []string
+		want    *SizedIntStruct
+		wantErr bool
+	}{
+		{name: "Defaults", env: map[string]string{}, args: []string{"ConfigTestApp"}, want: &SizedIntStruct{Int8: -128, Int16: 32767, Int32: -2147483648}},
+		{name: "SetArg", env: map[string]string{}, args: []string{"ConfigTestApp", "-INT8=7", "-INT32=1"}, want: &SizedIntStruct{Int8: 7, Int16: 32767, Int32: 1}},
+		{name: "Overflow8", env: map[string]string{"INT8": "128"}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+		{name: "Overflow16", env: map[string]string{"INT16": "32768"}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+		{name: "Overflow32", env: map[string]string{"INT32": "2147483648"}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(makeLookup(tt.env), tt.args, &SizedIntStruct{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type SizedUintStruct struct {
+	Uint8  uint8  `env:"UINT8" default:"255"`
+	Port   uint16 `env:"PORT" default:"8080"`
+	Uint32 uint32 `env:"UINT32" default:"4294967295"`
+}
+
+func TestNewSizedUints(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		args    []string
+		want    *SizedUintStruct
+		wantErr bool
+	}{
+		{name: "Defaults", env: map[string]string{}, args: []string{"ConfigTestApp"}, want: &SizedUintStruct{Uint8: 255, Port: 8080, Uint32: 4294967295}},
+		{name: "SetArg", env: map[string]string{}, args: []string{"ConfigTestApp", "-PORT=65535"}, want: &SizedUintStruct{Uint8: 255, Port: 65535, Uint32: 4294967295}},
+		{name: "Overflow8", env: map[string]string{"UINT8": "256"}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+		{name: "Overflow16", env: map[string]string{"PORT": "70000"}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+		{name: "Overflow32", env: map[string]string{"UINT32": "4294967296"}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+		{name: "Negative", env: map[string]string{"PORT": "-1"}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(makeLookup(tt.env), tt.args, &SizedUintStruct{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type Float32Struct struct {
+	Ratio float32 `env:"RATIO" default:"1.1"`
+}
+
+func TestNewFloat32(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		args    []string
+		want    *Float32Struct
+		wantErr bool
+	}{
+		{name: "Defaults", env: map[string]string{}, args: []string{"ConfigTestApp"}, want: &Float32Struct{Ratio: 1.1}},
+		{name: "SetArg", env: map[string]string{}, args: []string{"ConfigTestApp", "-RATIO=0.3"}, want: &Float32Struct{Ratio: 0.3}},
+		{name: "Overflow", env: map[string]string{"RATIO": "1e39"}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(makeLookup(tt.env), tt.args, &Float32Struct{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type BytesStruct struct {
+	Raw    []byte `env:"RAW" default:"raw"`
+	Base64 []byte `env:"BASE64" encoding:"base64" default:"c2VjcmV0"`
+	Hex    []byte `env:"HEX" encoding:"hex"`
+}
+
+func TestNewBytes(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		args    []string
+		want    *BytesStruct
+		wantErr bool
+	}{
+		{name: "Defaults", env: map[string]string{}, args: []string{"ConfigTestApp"}, want: &BytesStruct{Raw: []byte("raw"), Base64: []byte("secret")}},
+		{name: "SetArg", env: map[string]string{}, args: []string{"ConfigTestApp", "-HEX=deadbeef", "-RAW=a,b"}, want: &BytesStruct{Raw: []byte("a,b"), Base64: []byte("secret"), Hex: []byte{0xde, 0xad, 0xbe, 0xef}}},
+		{name: "InvalidBase64", env: map[string]string{"BASE64": "!!"}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+		{name: "InvalidHex", env: map[string]string{"HEX": "xyz"}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(makeLookup(tt.env), tt.args, &BytesStruct{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type JSONStruct struct {
+	Overrides map[string]int `env:"OVERRIDES" format:"json" default:"{\"a\":1}"`
+	Tenant    struct {
+		Name  string `json:"name"`
+		Limit int    `json:"limit"`
+	} `env:"TENANT" format:"json"`
+	Raw json.RawMessage `env:"RAW"`
+}
+
+func TestNewJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		args    []string
+		want    *JSONStruct
+		wantErr bool
+	}{
+		{name: "Defaults", env: map[string]string{}, args: []string{"ConfigTestApp"}, want: &JSONStruct{Overrides: map[string]int{"a": 1}}},
+		{name: "SetEnv", env: map[string]string{"TENANT": `{"name":"acme","limit":5}`, "RAW": `[1, 2]`}, args: []string{"ConfigTestApp"}, want: func() *JSONStruct {
+			c := &JSONStruct{Overrides: map[string]int{"a": 1}, Raw: json.RawMessage(`[1, 2]`)}
+			c.Tenant.Name = "acme"
+			c.Tenant.Limit = 5
+			return c
+		}()},
+		{name: "InvalidJSON", env: map[string]string{"OVERRIDES": `{"a":`}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+		{name: "InvalidRaw", env: map[string]string{"RAW": `{`}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(makeLookup(tt.env), tt.args, &JSONStruct{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type Format string
+
+type EnumStruct struct {
+	LogFormat Format   `env:"LOG_FORMAT" oneof:"json,text,console" default:"text"`
+	Outputs   []Format `env:"OUTPUTS" oneof:"json,text"`
+}
+
+func TestNewOneOf(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		args    []string
+		want    *EnumStruct
+		wantErr bool
+	}{
+		{name: "Defaults", env: map[string]string{}, args: []string{"ConfigTestApp"}, want: &EnumStruct{LogFormat: "text"}},
+		{name: "SetArg", env: map[string]string{}, args: []string{"ConfigTestApp", "-LOG_FORMAT=console", "-OUTPUTS=json,text"}, want: &EnumStruct{LogFormat: "console", Outputs: []Format{"json", "text"}}},
+		{name: "Invalid", env: map[string]string{"LOG_FORMAT": "xml"}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+		{name: "InvalidElement", env: map[string]string{"OUTPUTS": "json,console"}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(makeLookup(tt.env), tt.args, &EnumStruct{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type BigStruct struct {
+	Supply *big.Int   `env:"SUPPLY" default:"123456789012345678901234567890"`
+	Mask   big.Int    `env:"MASK" default:"0xff"`
+	Ratio  *big.Float `env:"RATIO" prec:"200" default:"0.1"`
+}
+
+func TestNewBig(t *testing.T) {
+	supply, _ := new(big.Int).SetString("123456789012345678901234567890", 10)
+	ratio, _, _ := big.ParseFloat("0.1", 10, 200, big.ToNearestEven)
+	tests := []struct {
+		name    string
+		env     map[string]string
+		args    []string
+		want    *BigStruct
+		wantErr bool
+	}{
+		{name: "Defaults", env: map[string]string{}, args: []string{"ConfigTestApp"}, want: &BigStruct{Supply: supply, Mask: *big.NewInt(255), Ratio: ratio}},
+		{name: "InvalidInt", env: map[string]string{"SUPPLY": "1.5"}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+		{name: "InvalidFloat", env: map[string]string{"RATIO": "abc"}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(makeLookup(tt.env), tt.args, &BigStruct{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got == nil {
+				if tt.want != nil {
+					t.Errorf("New() = nil, want %v", tt.want)
+				}
+				return
+			}
+			if got.Supply.Cmp(tt.want.Supply) != 0 || got.Mask.Cmp(&tt.want.Mask) != 0 || got.Ratio.Cmp(tt.want.Ratio) != 0 || got.Ratio.Prec() != tt.want.Ratio.Prec() {
+				t.Errorf("New() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type MailStruct struct {
+	From *mail.Address `env:"ALERT_FROM" default:"Ops <ops@example.com>"`
+	To   mail.Address  `env:"ALERT_TO"`
+}
+
+func TestNewMailAddress(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		args    []string
+		want    *MailStruct
+		wantErr bool
+	}{
+		{name: "Defaults", env: map[string]string{}, args: []string{"ConfigTestApp"}, want: &MailStruct{From: &mail.Address{Name: "Ops", Address: "ops@example.com"}}},
+		{name: "SetEnv", env: map[string]string{"ALERT_TO": "oncall@example.com"}, args: []string{"ConfigTestApp"}, want: &MailStruct{From: &mail.Address{Name: "Ops", Address: "ops@example.com"}, To: mail.Address{Address: "oncall@example.com"}}},
+		{name: "Invalid", env: map[string]string{"ALERT_TO": "oncall"}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(makeLookup(tt.env), tt.args, &MailStruct{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type TemplateStruct struct {
+	Addr string `env:"ADDR" default:"{{.Host}}:{{.Port}}"`
+	Host string `env:"HOST" default:"localhost"`
+	Port int    `env:"PORT" default:"8080"`
+	URL  string `env:"URL" default:"http://{{.Addr}}/{{.DB.Name}}"`
+	DB   struct {
+		Name string `env:"NAME" default:"app"`
+	}
+}
+
+func TestNewTemplateDefaults(t *testing.T) {
+	want := func(addr, host string, port int, url string) *TemplateStruct {
+		c := &TemplateStruct{Addr: addr, Host: host, Port: port, URL: url}
+		c.DB.Name = "app"
+		return c
+	}
+	tests := []struct {
+		name    string
+		env     map[string]string
+		args    []string
+		want    *TemplateStruct
+		wantErr bool
+	}{
+		{name: "Defaults", env: map[string]string{}, args: []string{"ConfigTestApp"}, want: want("localhost:8080", "localhost", 8080, "http://localhost:8080/app")},
+		{name: "SetEnv", env: map[string]string{"HOST": "example.com"}, args: []string{"ConfigTestApp", "-PORT=80"}, want: want("example.com:80", "example.com", 80, "http://example.com:80/app")},
+		{name: "Overridden", env: map[string]string{"ADDR": "{{.Host}}"}, args: []string{"ConfigTestApp"}, want: want("{{.Host}}", "localhost", 8080, "http://{{.Host}}/app")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(makeLookup(tt.env), tt.args, &TemplateStruct{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type InvalidTemplateStruct struct {
+	Addr string `env:"ADDR" default:"{{.Missing}}"`
+}
+
+func TestNewInvalidTemplateDefault(t *testing.T) {
+	if _, err := New(makeLookup(map[string]string{}), []string{"ConfigTestApp"}, &InvalidTemplateStruct{}); err == nil {
+		t.Errorf("New() error = nil, want error")
+	}
+}
+
+type ComplexStruct struct {
+	Coefficient  complex128   `env:"COEFFICIENT" default:"1+2i"`
+	Small        complex64    `env:"SMALL" default:"(0.5-1i)"`
+	Coefficients []complex128 `env:"COEFFICIENTS"`
+}
+
+func TestNewComplex(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		args    []string
+		want    *ComplexStruct
+		wantErr bool
+	}{
+		{name: "Defaults", env: map[string]string{}, args: []string{"ConfigTestApp"}, want: &ComplexStruct{Coefficient: 1 + 2i, Small: 0.5 - 1i}},
+		{name: "SetArg", env: map[string]string{}, args: []string{"ConfigTestApp", "-COEFFICIENTS=1i,2,3-3i"}, want: &ComplexStruct{Coefficient: 1 + 2i, Small: 0.5 - 1i, Coefficients: []complex128{1i, 2, 3 - 3i}}},
+		{name: "Invalid", env: map[string]string{"COEFFICIENT": "1+"}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(makeLookup(tt.env), tt.args, &ComplexStruct{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type NullStruct struct {
+	Name    sql.NullString          `env:"NAME"`
+	Limit   sql.NullInt64           `env:"LIMIT" default:"10"`
+	Enabled sql.NullBool            `env:"ENABLED"`
+	Since   sql.NullTime            `env:"SINCE"`
+	Timeout sql.Null[time.Duration] `env:"TIMEOUT"`
+}
+
+func TestNewSQLNull(t *testing.T) {
+	since := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name    string
+		env     map[string]string
+		args    []string
+		want    *NullStruct
+		wantErr bool
+	}{
+		{name: "Defaults", env: map[string]string{}, args: []string{"ConfigTestApp"}, want: &NullStruct{Limit: sql.NullInt64{Int64: 10, Valid: true}}},
+		{name: "SetEnv", env: map[string]string{"NAME": "", "ENABLED": "false", "SINCE": "2024-01-02T03:04:05Z"}, args: []string{"ConfigTestApp", "-TIMEOUT=5s", "-ENABLED"}, want: &NullStruct{Name: sql.NullString{Valid: true}, Limit: sql.NullInt64{Int64: 10, Valid: true}, Enabled: sql.NullBool{Bool: true, Valid: true}, Since: sql.NullTime{Time: since, Valid: true}, Timeout: sql.Null[time.Duration]{V: 5 * time.Second, Valid: true}}},
+		{name: "Invalid", env: map[string]string{"LIMIT": "ten"}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(makeLookup(tt.env), tt.args, &NullStruct{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type Key struct {
+	id     byte
+	secret []byte
+}
+
+func (k *Key) UnmarshalBinary(data []byte) error {
+	if len(data) < 2 {
+		return fmt.Errorf("key too short")
+	}
+	k.id, k.secret = data[0], data[1:]
+	return nil
+}
+
+type BinaryStruct struct {
+	Key    Key  `env:"KEY" default:"AWJj"`
+	HexKey *Key `env:"HEX_KEY" encoding:"hex"`
+}
+
+func TestNewBinaryUnmarshaler(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		args    []string
+		want    *BinaryStruct
+		wantErr bool
+	}{
+		{name: "Defaults", env: map[string]string{}, args: []string{"ConfigTestApp"}, want: &BinaryStruct{Key: Key{id: 1, secret: []byte("bc")}}},
+		{name: "SetEnv", env: map[string]string{"HEX_KEY": "0203"}, args: []string{"ConfigTestApp"}, want: &BinaryStruct{Key: Key{id: 1, secret: []byte("bc")}, HexKey: &Key{id: 2, secret: []byte{3}}}},
+		{name: "InvalidEncoding", env: map[string]string{"KEY": "not base64"}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+		{name: "InvalidValue", env: map[string]string{"HEX_KEY": "01"}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(makeLookup(tt.env), tt.args, &BinaryStruct{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type SlogStruct struct {
+	Level slog.Level `env:"LOG_LEVEL" default:"info"`
+}
+
+func TestNewSlogLevel(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		args    []string
+		want    *SlogStruct
+		wantErr bool
+	}{
+		{name: "Defaults", env: map[string]string{}, args: []string{"ConfigTestApp"}, want: &SlogStruct{Level: slog.LevelInfo}},
+		{name: "Name", env: map[string]string{"LOG_LEVEL": "debug"}, args: []string{"ConfigTestApp"}, want: &SlogStruct{Level: slog.LevelDebug}},
+		{name: "Offset", env: map[string]string{}, args: []string{"ConfigTestApp", "-LOG_LEVEL=WARN+2"}, want: &SlogStruct{Level: slog.LevelWarn + 2}},
+		{name: "Number", env: map[string]string{"LOG_LEVEL": "-8"}, args: []string{"ConfigTestApp"}, want: &SlogStruct{Level: -8}},
+		{name: "Invalid", env: map[string]string{"LOG_LEVEL": "verbose"}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(makeLookup(tt.env), tt.args, &SlogStruct{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type PercentStruct struct {
+	SampleRate float64 `env:"SAMPLE_RATE" percent:"true" default:"2.5%"`
+	Ratio      float32 `env:"RATIO" percent:"true" default:"0.5"`
+	Plain      float64 `env:"PLAIN"`
+}
+
+func TestNewPercent(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		args    []string
+		want    *PercentStruct
+		wantErr bool
+	}{
+		{name: "Defaults", env: map[string]string{}, args: []string{"ConfigTestApp"}, want: &PercentStruct{SampleRate: 0.025, Ratio: 0.5}},
+		{name: "SetArg", env: map[string]string{}, args: []string{"ConfigTestApp", "-SAMPLE_RATE=0.1", "-RATIO=150%"}, want: &PercentStruct{SampleRate: 0.1, Ratio: 1.5}},
+		{name: "NotEnabled", env: map[string]string{"PLAIN": "5%"}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+		{name: "Invalid", env: map[string]string{"SAMPLE_RATE": "%"}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(makeLookup(tt.env), tt.args, &PercentStruct{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type FlagNameStruct struct {
+	HTTPHost string `env:"HTTP_HOST" default:"localhost"`
+	Addr     string `env:"LISTEN_ADDR" flag:"addr"`
+	Verbose  bool   `flag:"verbose"`
+	Secret   string `env:"SECRET" flag:"-"`
+	DB       struct {
+		MaxConns int `env:"MAX_CONNS"`
+	} `flag:"database"`
+	Cache struct {
+		TTL time.Duration `env:"TTL"`
+	} `env:"C"`
+}
+
+func TestNewFlagNames(t *testing.T) {
+	want := func(f func(*FlagNameStruct)) *FlagNameStruct {
+		c := &FlagNameStruct{HTTPHost: "localhost"}
+		f(c)
+		return c
+	}
+	tests := []struct {
+		name    string
+		env     map[string]string
+		args    []string
+		want    *FlagNameStruct
+		wantErr bool
+	}{
+		{name: "KebabCase", args: []string{"ConfigTestApp", "-http-host=example.com", "--cache-ttl", "5s"}, want: want(func(c *FlagNameStruct) { c.HTTPHost = "example.com"; c.Cache.TTL = 5 * time.Second })},
+		{name: "EnvStyleAlias", args: []string{"ConfigTestApp", "-HTTP_HOST=example.com", "-C_TTL=5s"}, want: want(func(c *FlagNameStruct) { c.HTTPHost = "example.com"; c.Cache.TTL = 5 * time.Second })},
+		{name: "FlagTag", args: []string{"ConfigTestApp", "-addr=:80", "-database-max-conns=4"}, want: want(func(c *FlagNameStruct) { c.Addr = ":80"; c.DB.MaxConns = 4 })},
+		{name: "FlagTagReplacesKebab", args: []string{"ConfigTestApp", "-db-max-conns=4"}, wantErr: true},
+		{name: "FlagOnly", env: map[string]string{"VERBOSE": "true"}, args: []string{"ConfigTestApp", "-verbose"}, want: want(func(c *FlagNameStruct) { c.Verbose = true })},
+		{name: "NoFlag", env: map[string]string{"SECRET": "s3cret"}, args: []string{"ConfigTestApp"}, want: want(func(c *FlagNameStruct) { c.Secret = "s3cret" })},
+		{name: "NoFlagArg", args: []string{"ConfigTestApp", "-secret=x"}, wantErr: true},
+		{name: "NoFlagAlias", args: []string{"ConfigTestApp", "-SECRET=x"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(makeLookup(tt.env), tt.args, &FlagNameStruct{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewDuplicateFlagNames(t *testing.T) {
+	type C struct {
+		Host   string `env:"HOST"`
+		Server string `env:"SERVER" flag:"host"`
+	}
+	_, err := New(makeLookup(nil), []string{"ConfigTestApp"}, &C{})
+	if err == nil || err.Error() != "fields Host and Server have the same flag name 'host'" {
+		t.Errorf("New() error = %v, want a duplicate flag name error", err)
+	}
+	//An alias gives way to a flag with its name.
+	type D struct {
+		Port     int `env:"port"`
+		HostPort int `env:"HOST_PORT" flag:"PORT"`
+	}
+	got, err := New(makeLookup(nil), []string{"ConfigTestApp", "-port=1", "-PORT=2"}, &D{})
+	if err != nil || got.Port != 1 || got.HostPort != 2 {
+		t.Errorf("New() = %+v, %v, want flags to take precedence over aliases", got, err)
+	}
+}
+
+func TestKebabCase(t *testing.T) {
+	for name, want := range map[string]string{
+		"Host":       "host",
+		"HttpHost":   "http-host",
+		"HTTPHost":   "http-host",
+		"UserID":     "user-id",
+		"ID":         "id",
+		"S3Bucket":   "s3-bucket",
+		"Max_Conns":  "max-conns",
+		"OAuthToken": "o-auth-token",
+	} {
+		if got := kebabCase(name); got != want {
+			t.Errorf("kebabCase(%s) = %s, want %s", name, got, want)
+		}
+	}
+}
+
+func TestNewShortFlags(t *testing.T) {
+	type C struct {
+		Verbose bool   `env:"VERBOSE" short:"v"`
+		Config  string `flag:"config-path" short:"c"`
+		Port    int    `short:"p"`
+	}
+	tests := []struct {
+		name    string
+		args    []string
+		want    *C
+		wantErr bool
+	}{
+		{name: "Short", args: []string{"ConfigTestApp", "-v", "-c", "app.toml", "-p=80"}, want: &C{Verbose: true, Config: "app.toml", Port: 80}},
+		{name: "Long", args: []string{"ConfigTestApp", "-verbose", "-config-path=app.toml", "-port=80"}, want: &C{Verbose: true, Config: "app.toml", Port: 80}},
+		{name: "LastWins", args: []string{"ConfigTestApp", "-p=80", "-port=81"}, want: &C{Port: 81}},
+		{name: "Unknown", args: []string{"ConfigTestApp", "-x"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(makeLookup(nil), tt.args, &C{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	type Invalid struct {
+		Verbose bool `env:"VERBOSE" short:"vv"`
+	}
+	if _, err := New(makeLookup(nil), []string{"ConfigTestApp"}, &Invalid{}); err == nil {
+		t.Error("New() expected an error for a short flag of two characters")
+	}
+	type Clash struct {
+		Verbose bool `env:"VERBOSE" short:"v"`
+		Version bool `env:"VERSION" short:"v"`
+	}
+	_, err := New(makeLookup(nil), []string{"ConfigTestApp"}, &Clash{})
+	if err == nil || err.Error() != "fields Verbose and Version have the same flag name 'v'" {
+		t.Errorf("New() error = %v, want a duplicate flag name error", err)
+	}
+}
+
+func TestNewNegationFlags(t *testing.T) {
+	type C struct {
+		Cache   bool         `env:"CACHE" default:"true"`
+		Color   *bool        `flag:"color" default:"1"`
+		Metrics sql.NullBool `env:"METRICS" default:"true"`
+		Debug   bool         `env:"DEBUG"`
+		Off     bool         `env:"OFF" default:"false"`
+	}
+	tests := []struct {
+		name    string
+		args    []string
+		want    *C
+		wantErr bool
+	}{
+		{name: "Defaults", args: []string{"ConfigTestApp"}, want: &C{Cache: true, Color: ptr(true), Metrics: sql.NullBool{Bool: true, Valid: true}}},
+		{name: "Negated", args: []string{"ConfigTestApp", "-no-cache", "-no-color", "-no-metrics"}, want: &C{Color: ptr(false), Metrics: sql.NullBool{Valid: true}}},
+		{name: "NegatedFalse", args: []string{"ConfigTestApp", "-no-cache=false"}, want: &C{Cache: true, Color: ptr(true), Metrics: sql.NullBool{Bool: true, Valid: true}}},
+		{name: "LastWins", args: []string{"ConfigTestApp", "-no-cache", "-cache"}, want: &C{Cache: true, Color: ptr(true), Metrics: sql.NullBool{Bool: true, Valid: true}}},
+		{name: "NoneForFalseDefault", args: []string{"ConfigTestApp", "-no-debug"}, wantErr: true},
+		{name: "NoneForExplicitFalse", args: []string{"ConfigTestApp", "-no-off"}, wantErr: true},
+		{name: "Invalid", args: []string{"ConfigTestApp", "-no-cache=maybe"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(makeLookup(nil), tt.args, &C{}, WithOutput(io.Discard))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	type Clash struct {
+		Cache   bool `env:"CACHE" default:"true"`
+		NoCache bool `env:"NO_CACHE"`
+	}
+	_, err := New(makeLookup(nil), []string{"ConfigTestApp"}, &Clash{})
+	if err == nil || err.Error() != "fields NoCache and Cache have the same flag name 'no-cache'" {
+		t.Errorf("New() error = %v, want a duplicate flag name error", err)
+	}
+}
+
+func TestNewCountFlags(t *testing.T) {
+	type C struct {
+		Verbosity int   `env:"VERBOSITY" flag:"verbose" short:"v" count:"true"`
+		Quiet     uint8 `flag:"quiet" count:"true" default:"1"`
+	}
+	tests := []struct {
+		name    string
+		env     map[string]string
+		args    []string
+		want    *C
+		wantErr bool
+	}{
+		{name: "Unset", args: []string{"ConfigTestApp"}, want: &C{Quiet: 1}},
+		{name: "Once", args: []string{"ConfigTestApp", "-v"}, want: &C{Verbosity: 1, Quiet: 1}},
+		{name: "Repeated", args: []string{"ConfigTestApp", "-v", "-verbose", "-v", "-quiet", "-quiet"}, want: &C{Verbosity: 3, Quiet: 2}},
+		{name: "Value", args: []string{"ConfigTestApp", "-v=5", "-v"}, want: &C{Verbosity: 6, Quiet: 1}},
+		{name: "Reset", args: []string{"ConfigTestApp", "-v", "-v=false"}, want: &C{Quiet: 1}},
+		{name: "Env", env: map[string]string{"VERBOSITY": "2"}, args: []string{"ConfigTestApp"}, want: &C{Verbosity: 2, Quiet: 1}},
+		{name: "FlagOverridesEnv", env: map[string]string{"VERBOSITY": "2"}, args: []string{"ConfigTestApp", "-v"}, want: &C{Verbosity: 1, Quiet: 1}},
+		{name: "Invalid", args: []string{"ConfigTestApp", "-v=lots"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(makeLookup(tt.env), tt.args, &C{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	type NotInt struct {
+		Verbose bool `flag:"verbose" count:"true"`
+	}
+	if _, err := New(makeLookup(nil), []string{"ConfigTestApp"}, &NotInt{}); err == nil {
+		t.Error("New() expected an error for a count tag on a bool")
+	}
+}
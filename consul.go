@@ -0,0 +1,91 @@
+package config
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ConsulSource describes the keys under a prefix in the Consul KV store, for
+// [WithConsul].
+type ConsulSource struct {
+	//Address is the base URL of the Consul agent. Defaults to `http://127.0.0.1:8500`.
+	Address string
+	//Prefix selects the keys to read, e.g. `app/`.
+	Prefix string
+	//Datacenter to read from. Defaults to the agent's datacenter.
+	Datacenter string
+	//Token is sent as the ACL token, if set.
+	Token string
+	//Timeout limits the request. Defaults to 10 seconds. It is ignored if Client is set.
+	Timeout time.Duration
+	//Client is used to send the request. Defaults to a client with the timeout.
+	Client *http.Client
+}
+
+/*
+WithConsul adds the keys under a prefix in the Consul KV store as a source of values
+below environment variables. The prefix is removed from each key and the remaining `/`
+separated segments are joined with the file key delimiter, so with the prefix `app/` the
+key `app/db/host` sets the `DB_HOST` field. A prefix without keys provides no values.
+*/
+func WithConsul(src ConsulSource) Option {
+	return func(l *loader) {
+		l.sources = append(l.sources, &source{name: "consul " + src.Prefix, open: func(l *loader) (map[string]string, error) {
+			kvs, err := src.fetch()
+			if err != nil {
+				return nil, err
+			}
+			values := make(map[string]string, len(kvs))
+			for k, v := range kvs {
+				values[l.pathKey(strings.TrimPrefix(k, src.Prefix), "/")] = v
+			}
+			return values, nil
+		}})
+	}
+}
+
+func (src ConsulSource) fetch() (map[string]string, error) {
+	address := src.Address
+	if address == "" {
+		address = "http://127.0.0.1:8500"
+	}
+	client := src.Client
+	if client == nil {
+		client = &http.Client{Timeout: src.Timeout}
+		if client.Timeout == 0 {
+			client.Timeout = 10 * time.Second
+		}
+	}
+	query := url.Values{"recurse": {"true"}}
+	if src.Datacenter != "" {
+		query.Set("dc", src.Datacenter)
+	}
+	header := http.Header{}
+	if src.Token != "" {
+		header.Set("X-Consul-Token", src.Token)
+	}
+	var entries []struct {
+		Key   string
+		Value []byte
+	}
+	u := strings.TrimSuffix(address, "/") + "/v1/kv/" + strings.TrimPrefix(src.Prefix, "/") + "?" + query.Encode()
+	err := requestJSON(client, http.MethodGet, u, header, nil, &entries)
+	var statusErr *statusError
+	if errors.As(err, &statusErr) && statusErr.code == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	kvs := make(map[string]string, len(entries))
+	for _, e := range entries {
+		//Folders have no value.
+		if e.Value != nil {
+			kvs[e.Key] = string(e.Value)
+		}
+	}
+	return kvs, nil
+}
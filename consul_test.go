@@ -0,0 +1,63 @@
+package config
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestConsul(t *testing.T) {
+	type C struct {
+		Name string `env:"NAME" default:"default"`
+		DB   struct {
+			Host string `env:"HOST"`
+		}
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Consul-Token") != "secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if r.URL.Query().Get("recurse") != "true" || r.URL.Query().Get("dc") != "eu" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/kv/app/":
+			w.Write([]byte(`[{"Key":"app/","Value":null},{"Key":"app/name","Value":"Y29uc3Vs"},{"Key":"app/db/host","Value":"ZGI="}]`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	tests := []struct {
+		name    string
+		src     ConsulSource
+		want    *C
+		wantErr bool
+	}{
+		{
+			name: "prefix",
+			src:  ConsulSource{Address: srv.URL, Prefix: "app/", Datacenter: "eu", Token: "secret"},
+			want: func() *C {
+				c := &C{Name: "consul"}
+				c.DB.Host = "db"
+				return c
+			}(),
+		},
+		{name: "missing prefix", src: ConsulSource{Address: srv.URL, Prefix: "none/", Datacenter: "eu", Token: "secret"}, want: &C{Name: "default"}},
+		{name: "forbidden", src: ConsulSource{Address: srv.URL, Prefix: "app/", Datacenter: "eu"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(makeLookup(nil), []string{"test"}, &C{}, WithConsul(tt.src))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// CronSpec is a cron expression that is validated when it is set by New. Standard five
+// field expressions (minute, hour, day of month, month, day of week) are accepted, as are
+// six field expressions with a leading seconds field, and the descriptors `@yearly`,
+// `@annually`, `@monthly`, `@weekly`, `@daily`, `@midnight`, and `@hourly`.
+//
+// Each field is a comma separated list of `*`, values, or ranges such as `1-5`, each
+// optionally followed by a step such as `*/15`. Months and days of the week may also be
+// given as three letter names, e.g. `JAN` or `MON`. The day of month and day of week
+// fields also accept `?`.
+type CronSpec string
+
+var cronDescriptors = []string{"@yearly", "@annually", "@monthly", "@weekly", "@daily", "@midnight", "@hourly"}
+
+type cronField struct {
+	name     string
+	min, max int
+	names    []string // Names for the values starting at min, if any
+	any      bool     // Whether ? is accepted
+}
+
+var cronFields = []cronField{
+	{name: "second", min: 0, max: 59},
+	{name: "minute", min: 0, max: 59},
+	{name: "hour", min: 0, max: 23},
+	{name: "day of month", min: 1, max: 31, any: true},
+	{name: "month", min: 1, max: 12, names: []string{"JAN", "FEB", "MAR", "APR", "MAY", "JUN", "JUL", "AUG", "SEP", "OCT", "NOV", "DEC"}},
+	{name: "day of week", min: 0, max: 7, names: []string{"SUN", "MON", "TUE", "WED", "THU", "FRI", "SAT"}, any: true},
+}
+
+// ParseCronSpec validates a cron expression.
+func ParseCronSpec(s string) (CronSpec, error) {
+	spec := strings.TrimSpace(s)
+	if strings.HasPrefix(spec, "@") {
+		for _, d := range cronDescriptors {
+			if spec == d {
+				return CronSpec(spec), nil
+			}
+		}
+		return "", fmt.Errorf("invalid cron expression '%s': unknown descriptor", s)
+	}
+	parts := strings.Fields(spec)
+	fields := cronFields
+	switch len(parts) {
+	case 5:
+		fields = cronFields[1:]
+	case 6:
+	default:
+		return "", fmt.Errorf("invalid cron expression '%s': expected 5 or 6 fields, got %d", s, len(parts))
+	}
+	for i, part := range parts {
+		if err := fields[i].validate(part); err != nil {
+			return "", fmt.Errorf("invalid cron expression '%s': %s field: %w", s, fields[i].name, err)
+		}
+	}
+	return CronSpec(strings.Join(parts, " ")), nil
+}
+
+func (f cronField) validate(s string) error {
+	if s == "?" && f.any {
+		return nil
+	}
+	for _, item := range strings.Split(s, ",") {
+		rng, step, hasStep := strings.Cut(item, "/")
+		if hasStep {
+			if n, err := strconv.Atoi(step); err != nil || n <= 0 {
+				return fmt.Errorf("invalid step '%s'", step)
+			}
+		}
+		if rng == "*" {
+			continue
+		}
+		lo, hi, isRange := strings.Cut(rng, "-")
+		a, err := f.value(lo)
+		if err != nil {
+			return err
+		}
+		if isRange {
+			b, err := f.value(hi)
+			if err != nil {
+				return err
+			}
+			if a > b {
+				return fmt.Errorf("range '%s' is backwards", rng)
+			}
+		}
+	}
+	return nil
+}
+
+func (f cronField) value(s string) (int, error) {
+	for i, name := range f.names {
+		if strings.EqualFold(s, name) {
+			return f.min + i, nil
+		}
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid value '%s'", s)
+	}
+	if n < f.min || n > f.max {
+		return 0, fmt.Errorf("value %d is out of range %d-%d", n, f.min, f.max)
+	}
+	return n, nil
+}
+
+func (c *CronSpec) UnmarshalText(text []byte) error {
+	spec, err := ParseCronSpec(string(text))
+	if err != nil {
+		return err
+	}
+	*c = spec
+	return nil
+}
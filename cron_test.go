@@ -0,0 +1,44 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+type CronStruct struct {
+	Schedule CronSpec `env:"SCHEDULE" default:"*/15 * * * *"`
+}
+
+func TestNewCronSpec(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		args    []string
+		want    *CronStruct
+		wantErr bool
+	}{
+		{name: "Defaults", env: map[string]string{}, args: []string{"ConfigTestApp"}, want: &CronStruct{Schedule: "*/15 * * * *"}},
+		{name: "Seconds", env: map[string]string{"SCHEDULE": "0  30 9-17 ? JAN-jun,dec MON-FRI"}, args: []string{"ConfigTestApp"}, want: &CronStruct{Schedule: "0 30 9-17 ? JAN-jun,dec MON-FRI"}},
+		{name: "Descriptor", env: map[string]string{}, args: []string{"ConfigTestApp", "-SCHEDULE=@daily"}, want: &CronStruct{Schedule: "@daily"}},
+		{name: "List", env: map[string]string{"SCHEDULE": "0,30 0 1,15 * 0,7"}, args: []string{"ConfigTestApp"}, want: &CronStruct{Schedule: "0,30 0 1,15 * 0,7"}},
+		{name: "TooFewFields", env: map[string]string{"SCHEDULE": "* * * *"}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+		{name: "OutOfRange", env: map[string]string{"SCHEDULE": "60 * * * *"}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+		{name: "DayOfMonthZero", env: map[string]string{"SCHEDULE": "* * 0 * *"}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+		{name: "BackwardsRange", env: map[string]string{"SCHEDULE": "* 17-9 * * *"}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+		{name: "InvalidStep", env: map[string]string{"SCHEDULE": "*/0 * * * *"}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+		{name: "QuestionMarkInHour", env: map[string]string{"SCHEDULE": "* ? * * *"}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+		{name: "UnknownDescriptor", env: map[string]string{"SCHEDULE": "@often"}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(makeLookup(tt.env), tt.args, &CronStruct{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package config
+
+import "os/exec"
+
+/*
+WithCUEFile adds the CUE file at path as a source of values below environment variables.
+The file is evaluated by running `cue export --out json`, so unification, defaults, and
+constraints are applied by CUE before the result is mapped onto fields like
+[WithJSONFile]. Values that fail their constraints, or are incomplete, are an error.
+
+This runs the `cue` command, which must be installed. [WithConfigFile] also evaluates
+files with the `.cue` extension this way.
+*/
+func WithCUEFile(path string) Option {
+	return func(l *loader) {
+		l.sources = append(l.sources, &source{name: "file " + path, open: func(l *loader) (map[string]string, error) {
+			return l.exportCUE(path)
+		}})
+	}
+}
+
+func (l *loader) exportCUE(path string) (map[string]string, error) {
+	stdout, err := runCommand(exec.Command("cue", "export", "--out", "json", path))
+	if err != nil {
+		return nil, err
+	}
+	return decoders[".json"](l, stdout)
+}
@@ -0,0 +1,53 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+//fakeCommand installs an executable shell script named name at the front of PATH.
+func fakeCommand(t *testing.T, name, script string) {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("shell scripts are not executable on windows")
+	}
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, name), []byte("#!/bin/sh\n"+script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", dir+string(os.PathListSeparator)+os.Getenv("PATH"))
+}
+
+func TestCUEFile(t *testing.T) {
+	fakeCommand(t, "cue", `
+[ "$1 $2 $3" = "export --out json" ] || exit 2
+case "$4" in
+*bad.cue) echo 'port: invalid value 70000 (out of bound <=65535)' >&2; exit 1 ;;
+*) echo '{"name": "cue", "db": {"port": 5432}}' ;;
+esac
+`)
+	type C struct {
+		Name string `env:"NAME"`
+		DB   struct {
+			Port int `env:"PORT"`
+		}
+	}
+	want := &C{Name: "cue"}
+	want.DB.Port = 5432
+	for _, opt := range []Option{WithCUEFile("config.cue"), WithConfigFiles("config.cue")} {
+		got, err := New(makeLookup(nil), []string{"test"}, &C{}, opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("New() = %+v, want %+v", got, want)
+		}
+	}
+	_, err := New(makeLookup(nil), []string{"test"}, &C{}, WithCUEFile("bad.cue"))
+	if err == nil || err.Error() != "failed to load file bad.cue: cue: port: invalid value 70000 (out of bound <=65535)" {
+		t.Errorf("New() error = %v, want the cue error", err)
+	}
+}
@@ -0,0 +1,118 @@
+package config
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Decimal is an exact fixed-point decimal number, such as a price or fee, that is parsed
+// without the rounding of floating point types. The zero value is 0.
+//
+// When set by New, the `scale` tag sets the number of digits after the decimal point.
+// Values with more digits are rejected rather than rounded, and values with fewer are
+// padded, so `scale:"2"` turns `1.5` into `1.50`.
+type Decimal struct {
+	unscaled *big.Int
+	scale    int
+}
+
+// ParseDecimal parses a decimal number such as `-12.345`. Exponents are not accepted.
+func ParseDecimal(s string) (Decimal, error) {
+	digits := strings.TrimPrefix(strings.TrimPrefix(s, "-"), "+")
+	whole, frac, _ := strings.Cut(digits, ".")
+	if whole == "" && frac == "" || strings.TrimLeft(whole+frac, "0123456789") != "" {
+		return Decimal{}, fmt.Errorf("invalid decimal '%s'", s)
+	}
+	unscaled, ok := new(big.Int).SetString(s[:len(s)-len(digits)]+whole+frac, 10)
+	if !ok {
+		return Decimal{}, fmt.Errorf("invalid decimal '%s'", s)
+	}
+	return Decimal{unscaled: unscaled, scale: len(frac)}, nil
+}
+
+// parseDecimalTag parses val into a Decimal with the scale given by the `scale` tag.
+func parseDecimalTag(val, scaleTag string) (Decimal, error) {
+	d, err := ParseDecimal(val)
+	if err != nil || scaleTag == "" {
+		return d, err
+	}
+	scale, err := strconv.Atoi(scaleTag)
+	if err != nil || scale < 0 {
+		return Decimal{}, fmt.Errorf("invalid scale tag '%s'", scaleTag)
+	}
+	if d.scale > scale {
+		return Decimal{}, fmt.Errorf("decimal '%s' has more than %d digits after the decimal point", val, scale)
+	}
+	return d.Rescale(scale), nil
+}
+
+// Unscaled returns the decimal's digits as an integer, i.e. the decimal multiplied by
+// 10 to the power of its scale.
+func (d Decimal) Unscaled() *big.Int {
+	if d.unscaled == nil {
+		return new(big.Int)
+	}
+	return new(big.Int).Set(d.unscaled)
+}
+
+// Scale returns the number of digits after the decimal point.
+func (d Decimal) Scale() int {
+	return d.scale
+}
+
+// Rescale returns d with scale digits after the decimal point. Increasing the scale is
+// always exact, while decreasing it truncates towards zero.
+func (d Decimal) Rescale(scale int) Decimal {
+	unscaled := d.Unscaled()
+	if scale > d.scale {
+		unscaled.Mul(unscaled, pow10(scale-d.scale))
+	} else if scale < d.scale {
+		unscaled.Quo(unscaled, pow10(d.scale-scale))
+	}
+	return Decimal{unscaled: unscaled, scale: scale}
+}
+
+// Rat returns d as an exact rational number.
+func (d Decimal) Rat() *big.Rat {
+	return new(big.Rat).SetFrac(d.Unscaled(), pow10(d.scale))
+}
+
+// Cmp compares d and e, returning -1, 0, or +1. Decimals with different scales but the
+// same value, such as 1.5 and 1.50, are equal.
+func (d Decimal) Cmp(e Decimal) int {
+	return d.Rat().Cmp(e.Rat())
+}
+
+func (d Decimal) String() string {
+	s := d.Unscaled().String()
+	if d.scale == 0 {
+		return s
+	}
+	sign := ""
+	if strings.HasPrefix(s, "-") {
+		sign, s = "-", s[1:]
+	}
+	if len(s) <= d.scale {
+		s = strings.Repeat("0", d.scale-len(s)+1) + s
+	}
+	return sign + s[:len(s)-d.scale] + "." + s[len(s)-d.scale:]
+}
+
+func (d *Decimal) UnmarshalText(text []byte) error {
+	parsed, err := ParseDecimal(string(text))
+	if err != nil {
+		return err
+	}
+	*d = parsed
+	return nil
+}
+
+func (d Decimal) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func pow10(n int) *big.Int {
+	return new(big.Int).Exp(big.NewInt(10), big.NewInt(int64(n)), nil)
+}
@@ -0,0 +1,79 @@
+package config
+
+import (
+	"testing"
+)
+
+type DecimalStruct struct {
+	Price     Decimal   `env:"PRICE" scale:"2" default:"19.9"`
+	Fee       Decimal   `env:"FEE" default:"0.0001"`
+	Threshold *Decimal  `env:"THRESHOLD"`
+	Tiers     []Decimal `env:"TIERS"`
+}
+
+func TestNewDecimal(t *testing.T) {
+	tests := []struct {
+		name      string
+		env       map[string]string
+		args      []string
+		price     string
+		fee       string
+		threshold string
+		tiers     []string
+		wantErr   bool
+	}{
+		{name: "Defaults", env: map[string]string{}, args: []string{"ConfigTestApp"}, price: "19.90", fee: "0.0001"},
+		{name: "SetEnv", env: map[string]string{"PRICE": "-3", "FEE": "+.5", "THRESHOLD": "123456789012345678901234567890.123456789"}, args: []string{"ConfigTestApp"}, price: "-3.00", fee: "0.5", threshold: "123456789012345678901234567890.123456789"},
+		{name: "SetArg", env: map[string]string{}, args: []string{"ConfigTestApp", "-TIERS=0.1,10,-0.01"}, price: "19.90", fee: "0.0001", tiers: []string{"0.1", "10", "-0.01"}},
+		{name: "TooPrecise", env: map[string]string{"PRICE": "1.999"}, args: []string{"ConfigTestApp"}, wantErr: true},
+		{name: "Exponent", env: map[string]string{"FEE": "1e-3"}, args: []string{"ConfigTestApp"}, wantErr: true},
+		{name: "Empty", env: map[string]string{"FEE": "."}, args: []string{"ConfigTestApp"}, wantErr: true},
+		{name: "DoubleSign", env: map[string]string{"FEE": "--1"}, args: []string{"ConfigTestApp"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(makeLookup(tt.env), tt.args, &DecimalStruct{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.Price.String() != tt.price || got.Fee.String() != tt.fee {
+				t.Errorf("New() = %v, %v, want %v, %v", got.Price, got.Fee, tt.price, tt.fee)
+			}
+			if (got.Threshold == nil) != (tt.threshold == "") || got.Threshold != nil && got.Threshold.String() != tt.threshold {
+				t.Errorf("New() Threshold = %v, want %v", got.Threshold, tt.threshold)
+			}
+			if len(got.Tiers) != len(tt.tiers) {
+				t.Fatalf("New() Tiers = %v, want %v", got.Tiers, tt.tiers)
+			}
+			for i := range got.Tiers {
+				if got.Tiers[i].String() != tt.tiers[i] {
+					t.Errorf("New() Tiers = %v, want %v", got.Tiers, tt.tiers)
+				}
+			}
+		})
+	}
+}
+
+func TestDecimal(t *testing.T) {
+	a, _ := ParseDecimal("1.5")
+	b, _ := ParseDecimal("1.50")
+	if a.Cmp(b) != 0 {
+		t.Errorf("%v.Cmp(%v) = %d, want 0", a, b, a.Cmp(b))
+	}
+	if got := a.Rescale(3).String(); got != "1.500" {
+		t.Errorf("Rescale(3) = %s, want 1.500", got)
+	}
+	if got := b.Rescale(0).String(); got != "1" {
+		t.Errorf("Rescale(0) = %s, want 1", got)
+	}
+	if got := (Decimal{}).String(); got != "0" {
+		t.Errorf("zero value = %s, want 0", got)
+	}
+	if got, _ := ParseDecimal("-0.05"); got.String() != "-0.05" {
+		t.Errorf("ParseDecimal(-0.05) = %s", got)
+	}
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+// Deprecation describes a deprecated setting that was used, as reported to the handler
+// set by [WithDeprecationHandler].
+type Deprecation struct {
+	Field   string // Go path of the field, e.g. DB.Host
+	Source  string // Where the setting was used: `env` or `flag`
+	Name    string // Name of the env var or flag used, e.g. DB_HOST or -db-host
+	Message string // The field's `deprecated` tag, e.g. `use DATABASE_HOST`
+}
+
+// String returns the warning for d, e.g. `DB_HOST is deprecated, use DATABASE_HOST`.
+func (d Deprecation) String() string {
+	if d.Message == "" {
+		return fmt.Sprintf("%s is deprecated", d.Name)
+	}
+	return fmt.Sprintf("%s is deprecated, %s", d.Name, d.Message)
+}
+
+/*
+WithDeprecationHandler sets the func called for each field with a `deprecated` tag that
+is set by its env var or a flag. Fields are still populated as usual, so a setting can
+be renamed by keeping the old field until operators have moved to the new one:
+
+	type C struct {
+		Host    string `env:"HTTP_HOST"`
+		OldHost string `env:"HOST" deprecated:"use HTTP_HOST"`
+	}
+
+	config.WithDeprecationHandler(func(d config.Deprecation) {
+		slog.Warn("deprecated setting", "name", d.Name, "message", d.Message)
+	})
+
+Without a handler, a warning is printed to the output set by [WithOutput].
+*/
+func WithDeprecationHandler(handle func(Deprecation)) Option {
+	return func(l *loader) {
+		l.deprecated = handle
+	}
+}
+
+// warnDeprecated reports that the deprecated field f was set by name from source.
+func (l *loader) warnDeprecated(f field, source, name string) {
+	message, ok := f.tag.Lookup("deprecated")
+	if !ok {
+		return
+	}
+	d := Deprecation{Field: f.name, Source: source, Name: name, Message: message}
+	if l.deprecated != nil {
+		l.deprecated(d)
+		return
+	}
+	w := l.output
+	if w == nil {
+		w = os.Stderr
+	}
+	io.WriteString(w, "warning: "+d.String()+"\n")
+}
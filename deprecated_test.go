@@ -0,0 +1,65 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewDeprecated(t *testing.T) {
+	type C struct {
+		Host    string `env:"HTTP_HOST" usage:"Host to listen on"`
+		OldHost string `env:"HOST" short:"H" deprecated:"use HTTP_HOST" usage:"Not listed"`
+		Legacy  bool   `flag:"legacy" deprecated:""`
+	}
+	tests := []struct {
+		name string
+		env  map[string]string
+		args []string
+		want []Deprecation
+	}{
+		{name: "Unused", env: map[string]string{"HTTP_HOST": "a"}, args: []string{"app"}},
+		{name: "Env", env: map[string]string{"HOST": "a"}, args: []string{"app"}, want: []Deprecation{
+			{Field: "OldHost", Source: "env", Name: "HOST", Message: "use HTTP_HOST"},
+		}},
+		{name: "Flags", args: []string{"app", "-H=a", "-legacy"}, want: []Deprecation{
+			{Field: "OldHost", Source: "flag", Name: "-H", Message: "use HTTP_HOST"},
+			{Field: "Legacy", Source: "flag", Name: "-legacy"},
+		}},
+		{name: "Alias", args: []string{"app", "-HOST=a"}, want: []Deprecation{
+			{Field: "OldHost", Source: "flag", Name: "-HOST", Message: "use HTTP_HOST"},
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []Deprecation
+			c, err := New(makeLookup(tt.env), tt.args, &C{}, WithDeprecationHandler(func(d Deprecation) {
+				got = append(got, d)
+			}))
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() reported %+v, want %+v", got, tt.want)
+			}
+			if len(tt.want) > 0 && tt.want[0].Field == "OldHost" && c.OldHost != "a" {
+				t.Errorf("New() OldHost = %s, want the deprecated field to be populated", c.OldHost)
+			}
+		})
+	}
+
+	var b strings.Builder
+	if _, err := New(makeLookup(map[string]string{"HOST": "a"}), []string{"app"}, &C{}, WithOutput(&b)); err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if want := "warning: HOST is deprecated, use HTTP_HOST\n"; b.String() != want {
+		t.Errorf("New() printed %q, want %q", b.String(), want)
+	}
+	b.Reset()
+	if _, err := New(makeLookup(nil), []string{"app", "-h"}, &C{}, WithOutput(&b)); err != ErrHelp {
+		t.Fatalf("New() error = %v, want ErrHelp", err)
+	}
+	if strings.Contains(b.String(), "Not listed") || strings.Contains(b.String(), "-legacy") {
+		t.Errorf("New() printed %q, want deprecated flags left out", b.String())
+	}
+}
@@ -0,0 +1,90 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+/*
+WithDir adds the directory at path as a source of values below environment variables,
+where the name of each file is a key and its contents are the value. This is the shape
+of a Kubernetes ConfigMap or Secret mounted as a volume, so a file named `DB_HOST` or
+`db.host` sets the `DB_HOST` field. Dots in file names separate the names of nested
+structs, like in [WithPropertiesFile].
+
+A single trailing newline is removed from each value. Subdirectories and names starting
+with `..`, which Kubernetes uses for its atomic updates, are skipped. A missing
+directory is an error.
+*/
+func WithDir(path string) Option {
+	return func(l *loader) {
+		l.sources = append(l.sources, &source{name: "dir " + path, open: func(l *loader) (map[string]string, error) {
+			return l.readDir(path)
+		}})
+	}
+}
+
+/*
+WithCredentials adds the credentials passed to a systemd service with LoadCredential
+or SetCredential as a source of values below environment variables, read like
+[WithDir] from the directory named by the `CREDENTIALS_DIRECTORY` environment variable.
+A credential named `db.password` sets the `DB_PASSWORD` field:
+
+	[Service]
+	LoadCredential=db.password:/etc/app/db-password
+
+If the variable is not set, as when not run by systemd, no values are provided.
+*/
+func WithCredentials() Option {
+	return func(l *loader) {
+		s := &source{name: "credentials"}
+		s.key = func(l *loader) string {
+			dir, _ := l.lookupenv("CREDENTIALS_DIRECTORY")
+			return dir
+		}
+		s.resolve = func(l *loader) {
+			if dir, ok := l.lookupenv("CREDENTIALS_DIRECTORY"); ok && dir != "" {
+				s.name = "credentials " + dir
+			}
+		}
+		s.open = func(l *loader) (map[string]string, error) {
+			dir, ok := l.lookupenv("CREDENTIALS_DIRECTORY")
+			if !ok || dir == "" {
+				return nil, nil
+			}
+			return l.readDir(dir)
+		}
+		l.sources = append(l.sources, s)
+	}
+}
+
+// readDir returns the contents of the files in the directory at path, keyed by their
+// names.
+func (l *loader) readDir(path string) (map[string]string, error) {
+	entries, err := os.ReadDir(path)
+	if err != nil {
+		return nil, err
+	}
+	values := map[string]string{}
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), "..") {
+			continue
+		}
+		//Mounted keys are symlinks into the current `..data` directory.
+		info, err := os.Stat(filepath.Join(path, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		if info.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(path, e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		value := strings.TrimSuffix(strings.TrimSuffix(string(data), "\n"), "\r")
+		values[strings.ReplaceAll(e.Name(), ".", l.keyDelimiter)] = value
+	}
+	return values, nil
+}
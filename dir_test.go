@@ -0,0 +1,88 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDir(t *testing.T) {
+	type C struct {
+		Name     string   `env:"NAME" default:"default"`
+		Password string   `env:"PASSWORD"`
+		Hosts    []string `env:"HOSTS"`
+		DB       struct {
+			Host string `env:"HOST"`
+		}
+	}
+	//Lay the directory out like a mounted ConfigMap, with keys linked into `..data`.
+	dir := t.TempDir()
+	data := filepath.Join(dir, "..2024_01_01_00_00_00.000000000")
+	if err := os.Mkdir(data, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range map[string]string{"PASSWORD": "s3cret\n", "db.host": "db", "hosts": "a,b\r\n"} {
+		if err := os.WriteFile(filepath.Join(data, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Symlink(filepath.Base(data), filepath.Join(dir, "..data")); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"PASSWORD", "db.host", "hosts"} {
+		if err := os.Symlink(filepath.Join("..data", name), filepath.Join(dir, name)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "nested"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := New(makeLookup(map[string]string{"DB_HOST": "env"}), []string{"test"}, &C{}, WithDir(dir))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &C{Name: "default", Password: "s3cret", Hosts: []string{"a", "b"}}
+	want.DB.Host = "env"
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("New() = %+v, want %+v", got, want)
+	}
+
+	if _, err := New(makeLookup(nil), []string{"test"}, &C{}, WithDir(filepath.Join(dir, "missing"))); err == nil {
+		t.Error("New() expected error for missing directory")
+	}
+}
+
+func TestCredentials(t *testing.T) {
+	type C struct {
+		Token string `env:"TOKEN" default:"none"`
+		DB    struct {
+			Password string `env:"PASSWORD"`
+		}
+	}
+	dir := t.TempDir()
+	for name, content := range map[string]string{"db.password": "s3cret\n", "token": "t0ken"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o400); err != nil {
+			t.Fatal(err)
+		}
+	}
+	got, err := New(makeLookup(map[string]string{"CREDENTIALS_DIRECTORY": dir}), []string{"test"}, &C{}, WithCredentials())
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &C{Token: "t0ken"}
+	want.DB.Password = "s3cret"
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("New() = %+v, want %+v", got, want)
+	}
+
+	//Outside of systemd there are no credentials.
+	if got, err := New(makeLookup(nil), []string{"test"}, &C{}, WithCredentials()); err != nil || got.Token != "none" {
+		t.Errorf("New() = %+v, %v, want defaults", got, err)
+	}
+	_, err = New(makeLookup(map[string]string{"CREDENTIALS_DIRECTORY": filepath.Join(dir, "missing")}), []string{"test"}, &C{}, WithCredentials())
+	if err == nil {
+		t.Error("New() expected error for missing credentials directory")
+	}
+}
@@ -0,0 +1,192 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+/*
+WithDotenv loads environment variables from the `.env` files in dir, or the working
+directory if it is empty, for local development. They are layered in this order, with
+variables in later files overriding those in earlier ones:
+
+	.env                  # Shared defaults, committed
+	.env.<profile>        # Profile defaults, committed
+	.env.local            # Local overrides, not committed
+	.env.<profile>.local  # Local profile overrides, not committed
+
+The profile is taken from the `-profile` flag or the `APP_ENV` environment variable, as
+for [WithProfile]. Files that don't exist are skipped.
+
+The variables are only used when the real environment doesn't set them, so deployments
+can override anything, and they are seen by everything that reads the environment
+through New, including the `_FILE` convention and the credentials of secret stores.
+
+Each line holds `NAME=value`, optionally preceded by `export`. Lines starting with `#`
+are comments, as is the rest of an unquoted value after ` #`. Values in single quotes
+are literal, and values in double quotes may span lines and contain the escapes `\n`,
+`\r`, `\t`, `\"`, `\$`, and `\\`. Variables in values are not expanded.
+*/
+func WithDotenv(dir string) Option {
+	return func(l *loader) {
+		l.profile = true
+		l.dotenv = append(l.dotenv, func(l *loader) ([]string, bool, error) {
+			profile, err := l.selectedProfile()
+			if err != nil {
+				return nil, false, err
+			}
+			names := []string{".env", ".env.local"}
+			if profile != "" {
+				names = []string{".env", ".env." + profile, ".env.local", ".env." + profile + ".local"}
+			}
+			paths := make([]string, len(names))
+			for i, name := range names {
+				paths[i] = filepath.Join(dir, name)
+			}
+			return paths, true, nil
+		})
+	}
+}
+
+/*
+WithEnvFileVar loads environment variables from the env file named by the environment
+variable name, such as `ENV_FILE=/etc/app/env`, as some platforms require. It defaults
+to `ENV_FILE` if name is empty. Several files may be given by separating their paths
+with the OS path list separator, with variables in later files overriding those in
+earlier ones.
+
+The file is read like those of [WithDotenv], and its variables are likewise only used
+when the real environment doesn't set them. A missing file is an error, but nothing is
+loaded if the variable is not set.
+*/
+func WithEnvFileVar(name string) Option {
+	if name == "" {
+		name = "ENV_FILE"
+	}
+	return func(l *loader) {
+		l.dotenv = append(l.dotenv, func(l *loader) ([]string, bool, error) {
+			value, _ := l.lookupenv(name)
+			paths := slices.DeleteFunc(filepath.SplitList(value), func(p string) bool { return p == "" })
+			return paths, false, nil
+		})
+	}
+}
+
+// loadDotenv reads the files of the dotenv options, in order, and falls back to their
+// variables when looking up environment variables that are not set. Missing files are
+// skipped if they are optional.
+func (l *loader) loadDotenv() error {
+	vars := map[string]string{}
+	for _, files := range l.dotenv {
+		paths, optional, err := files(l)
+		if err != nil {
+			return err
+		}
+		for _, path := range paths {
+			data, err := os.ReadFile(path)
+			if optional && errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("failed to load dotenv %s: %w", path, err)
+			}
+			values, err := parseDotenv(data)
+			if err != nil {
+				return fmt.Errorf("failed to load dotenv %s: %w", path, err)
+			}
+			for k, v := range values {
+				vars[k] = v
+			}
+		}
+	}
+	if len(vars) == 0 {
+		return nil
+	}
+	lookupenv := l.lookupenv
+	l.lookupenv = func(name string) (string, bool) {
+		if value, ok := lookupenv(name); ok {
+			return value, true
+		}
+		value, ok := vars[name]
+		return value, ok
+	}
+	return nil
+}
+
+func parseDotenv(data []byte) (map[string]string, error) {
+	vars := map[string]string{}
+	rest := strings.ReplaceAll(string(data), "\r\n", "\n")
+	for n := 1; rest != ""; n++ {
+		var line string
+		line, rest, _ = strings.Cut(rest, "\n")
+		line = strings.TrimLeft(line, " \t")
+		if strings.TrimSpace(line) == "" || line[0] == '#' {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+		name, value, ok := strings.Cut(line, "=")
+		name = strings.TrimSpace(name)
+		if !ok || name == "" || strings.ContainsAny(name, " \t") {
+			return nil, fmt.Errorf("line %d: expected NAME=value", n)
+		}
+		value = strings.TrimLeft(value, " \t")
+		switch {
+		case strings.HasPrefix(value, "'"):
+			end := strings.IndexByte(value[1:], '\'')
+			if end < 0 {
+				return nil, fmt.Errorf("line %d: unterminated single quote", n)
+			}
+			value = value[1 : end+1]
+		case strings.HasPrefix(value, `"`):
+			//Double quoted values may continue on the following lines.
+			start := n
+			var b strings.Builder
+			value = value[1:]
+			for {
+				i := strings.IndexAny(value, `"\`)
+				if i < 0 {
+					if rest == "" {
+						return nil, fmt.Errorf("line %d: unterminated double quote", start)
+					}
+					b.WriteString(value + "\n")
+					value, rest, _ = strings.Cut(rest, "\n")
+					n++
+					continue
+				}
+				b.WriteString(value[:i])
+				if value[i] == '"' {
+					break
+				}
+				if i+1 == len(value) {
+					return nil, fmt.Errorf("line %d: trailing backslash", n)
+				}
+				switch c := value[i+1]; c {
+				case 'n':
+					b.WriteByte('\n')
+				case 'r':
+					b.WriteByte('\r')
+				case 't':
+					b.WriteByte('\t')
+				case '"', '\\', '$':
+					b.WriteByte(c)
+				default:
+					b.WriteString(value[i : i+2])
+				}
+				value = value[i+2:]
+			}
+			value = b.String()
+		default:
+			if i := strings.Index(value, " #"); i >= 0 {
+				value = value[:i]
+			}
+			value = strings.TrimSpace(value)
+		}
+		vars[name] = value
+	}
+	return vars, nil
+}
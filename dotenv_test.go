@@ -0,0 +1,142 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDotenv(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		".env":            "NAME=base\nPORT=80\nDEBUG=false\nREGION=us\n",
+		".env.local":      "DEBUG=true\n",
+		".env.prod":       "PORT=443\nDEBUG=false\n",
+		".env.prod.local": "REGION=eu\n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	type C struct {
+		Name   string `env:"NAME"`
+		Port   int    `env:"PORT"`
+		Debug  bool   `env:"DEBUG"`
+		Region string `env:"REGION"`
+	}
+	tests := []struct {
+		name string
+		env  map[string]string
+		args []string
+		want *C
+	}{
+		{name: "no profile", args: []string{"test"}, want: &C{Name: "base", Port: 80, Debug: true, Region: "us"}},
+		{name: "profile", env: map[string]string{"APP_ENV": "prod"}, args: []string{"test"}, want: &C{Name: "base", Port: 443, Debug: true, Region: "eu"}},
+		{name: "profile flag", args: []string{"test", "-profile", "prod"}, want: &C{Name: "base", Port: 443, Debug: true, Region: "eu"}},
+		{name: "env overrides", env: map[string]string{"NAME": "env", "DEBUG": "false"}, args: []string{"test"}, want: &C{Name: "env", Port: 80, Region: "us"}},
+		{name: "flags override", args: []string{"test", "-PORT", "8080"}, want: &C{Name: "base", Port: 8080, Debug: true, Region: "us"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(makeLookup(tt.env), tt.args, &C{}, WithDotenv(dir))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	//The variables are also seen by _FILE lookups.
+	secret := writeFile(t, "secret", "s3cret\n")
+	envDir := filepath.Dir(writeFile(t, ".env", "PASSWORD_FILE="+secret+"\n"))
+	type S struct {
+		Password string `env:"PASSWORD"`
+	}
+	if got, err := New(makeLookup(nil), []string{"test"}, &S{}, WithDotenv(envDir), WithEnvFiles()); err != nil || got.Password != "s3cret" {
+		t.Errorf("New() = %+v, %v, want the password file from .env", got, err)
+	}
+	badDir := filepath.Dir(writeFile(t, ".env", "NAME=ok\nnot a variable\n"))
+	if _, err := New(makeLookup(nil), []string{"test"}, &C{}, WithDotenv(badDir)); err == nil || !strings.HasSuffix(err.Error(), ".env: line 2: expected NAME=value") {
+		t.Errorf("New() error = %v, want a parse error", err)
+	}
+}
+
+func TestParseDotenv(t *testing.T) {
+	data := `# comment
+export A=plain value # comment
+B = 'single # "quoted" \n'
+C="double \"quoted\"\tand\\n escaped \$HOME"
+D="multi
+line  "
+E=
+F=a#b
+`
+	want := map[string]string{
+		"A": "plain value",
+		"B": `single # "quoted" \n`,
+		"C": "double \"quoted\"\tand\\n escaped $HOME",
+		"D": "multi\nline  ",
+		"E": "",
+		"F": "a#b",
+	}
+	got, err := parseDotenv([]byte(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseDotenv() = %q, want %q", got, want)
+	}
+	for data, wantErr := range map[string]string{
+		"A='x":          "line 1: unterminated single quote",
+		"A=1\nB=\"x\ny": "line 2: unterminated double quote",
+		"=x":            "line 1: expected NAME=value",
+	} {
+		if _, err := parseDotenv([]byte(data)); err == nil || err.Error() != wantErr {
+			t.Errorf("parseDotenv(%q) error = %v, want %s", data, err, wantErr)
+		}
+	}
+}
+
+func TestEnvFileVar(t *testing.T) {
+	base := writeFile(t, "base.env", "NAME=base\nPORT=80\n")
+	prod := writeFile(t, "prod.env", "PORT=443\n")
+	type C struct {
+		Name string `env:"NAME" default:"default"`
+		Port int    `env:"PORT"`
+	}
+	tests := []struct {
+		name    string
+		env     map[string]string
+		opt     Option
+		want    *C
+		wantErr string
+	}{
+		{name: "default name", env: map[string]string{"ENV_FILE": base}, opt: WithEnvFileVar(""), want: &C{Name: "base", Port: 80}},
+		{name: "custom name", env: map[string]string{"APP_ENV_FILE": base}, opt: WithEnvFileVar("APP_ENV_FILE"), want: &C{Name: "base", Port: 80}},
+		{name: "list", env: map[string]string{"ENV_FILE": base + string(os.PathListSeparator) + prod}, opt: WithEnvFileVar(""), want: &C{Name: "base", Port: 443}},
+		{name: "env overrides", env: map[string]string{"ENV_FILE": base, "PORT": "8080"}, opt: WithEnvFileVar(""), want: &C{Name: "base", Port: 8080}},
+		{name: "unset", opt: WithEnvFileVar(""), want: &C{Name: "default"}},
+		{name: "missing", env: map[string]string{"ENV_FILE": filepath.Join(t.TempDir(), "missing.env")}, opt: WithEnvFileVar(""), wantErr: "no such file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(makeLookup(tt.env), []string{"test"}, &C{}, tt.opt)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("New() error = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,50 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// parseDuration is time.ParseDuration with the additional units `d` for 24 hours and
+// `w` for 7 days, which are translated into hours before parsing.
+func parseDuration(s string) (time.Duration, error) {
+	if !strings.ContainsAny(s, "dw") {
+		return time.ParseDuration(s)
+	}
+	isDigit := func(c byte) bool { return c == '.' || '0' <= c && c <= '9' }
+	var b strings.Builder
+	i := 0
+	if i < len(s) && (s[i] == '-' || s[i] == '+') {
+		b.WriteByte(s[i])
+		i++
+	}
+	for i < len(s) {
+		j := i
+		for j < len(s) && isDigit(s[j]) {
+			j++
+		}
+		k := j
+		for k < len(s) && !isDigit(s[k]) {
+			k++
+		}
+		number, unit := s[i:j], s[j:k]
+		hours := map[string]float64{"d": 24, "w": 7 * 24}[unit]
+		if hours == 0 {
+			b.WriteString(s[i:k])
+		} else {
+			f, err := strconv.ParseFloat(number, 64)
+			if err != nil {
+				return 0, fmt.Errorf("invalid duration '%s'", s)
+			}
+			b.WriteString(strconv.FormatFloat(f*hours, 'f', -1, 64) + "h")
+		}
+		i = k
+	}
+	d, err := time.ParseDuration(b.String())
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration '%s'", s)
+	}
+	return d, nil
+}
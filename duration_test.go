@@ -0,0 +1,36 @@
+package config
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    time.Duration
+		wantErr bool
+	}{
+		{in: "30s", want: 30 * time.Second},
+		{in: "30d", want: 30 * 24 * time.Hour},
+		{in: "2w", want: 14 * 24 * time.Hour},
+		{in: "1.5d", want: 36 * time.Hour},
+		{in: "1w2d12h30m", want: 9*24*time.Hour + 12*time.Hour + 30*time.Minute},
+		{in: "-1d", want: -24 * time.Hour},
+		{in: "d", wantErr: true},
+		{in: "1dd", wantErr: true},
+		{in: "1y", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.in, func(t *testing.T) {
+			got, err := parseDuration(tt.in)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseDuration() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("parseDuration() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
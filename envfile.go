@@ -0,0 +1,45 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+/*
+WithEnvFiles enables the `_FILE` convention of Docker and Kubernetes secrets. When the
+environment variable of a field is unset, but the same name with a `_FILE` suffix is
+set, the field's value is read from the file it names:
+
+	DB_PASSWORD_FILE=/run/secrets/db_password
+
+A single trailing newline is removed from the value. Setting both variables is an
+error, as is a file that cannot be read.
+*/
+func WithEnvFiles() Option {
+	return func(l *loader) {
+		l.envFiles = true
+	}
+}
+
+// lookupEnv returns the value of the environment variable name, or with WithEnvFiles,
+// the contents of the file named by its `_FILE` variable.
+func (l *loader) lookupEnv(name string) (string, bool, error) {
+	value, ok := l.lookupenv(name)
+	if !l.envFiles {
+		return value, ok, nil
+	}
+	fileVar := name + "_FILE"
+	path, fileOK := l.lookupenv(fileVar)
+	if !fileOK {
+		return value, ok, nil
+	}
+	if ok {
+		return "", false, fmt.Errorf("both %s and %s are set", name, fileVar)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", false, fmt.Errorf("%s: %w", fileVar, err)
+	}
+	return strings.TrimSuffix(strings.TrimSuffix(string(data), "\n"), "\r"), true, nil
+}
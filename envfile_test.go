@@ -0,0 +1,64 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestEnvFiles(t *testing.T) {
+	type C struct {
+		User     string `env:"USER" default:"admin"`
+		Password string `env:"DB_PASSWORD"`
+		Port     int    `env:"PORT"`
+	}
+	secret := writeFile(t, "db_password", "s3cret\n")
+	port := writeFile(t, "port", "5432")
+	tests := []struct {
+		name    string
+		env     map[string]string
+		opts    []Option
+		want    *C
+		wantErr bool
+	}{
+		{name: "files", env: map[string]string{"DB_PASSWORD_FILE": secret, "PORT_FILE": port}, opts: []Option{WithEnvFiles()}, want: &C{User: "admin", Password: "s3cret", Port: 5432}},
+		{name: "plain env", env: map[string]string{"DB_PASSWORD": "plain"}, opts: []Option{WithEnvFiles()}, want: &C{User: "admin", Password: "plain"}},
+		{name: "env delimiter", env: map[string]string{"DB_PASSWORD_FILE": secret}, opts: []Option{WithEnvFiles(), WithEnvDelimiter("__")}, want: &C{User: "admin", Password: "s3cret"}},
+		{name: "disabled", env: map[string]string{"DB_PASSWORD_FILE": secret}, want: &C{User: "admin"}},
+		{name: "both set", env: map[string]string{"DB_PASSWORD": "plain", "DB_PASSWORD_FILE": secret}, opts: []Option{WithEnvFiles()}, wantErr: true},
+		{name: "missing file", env: map[string]string{"DB_PASSWORD_FILE": filepath.Join(t.TempDir(), "missing")}, opts: []Option{WithEnvFiles()}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(makeLookup(tt.env), []string{"test"}, &C{}, tt.opts...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnvFilesKindsAndIndexed(t *testing.T) {
+	RegisterKind[StorageConfig, S3Config]("s3")
+	RegisterKind[StorageConfig, DiskConfig]("disk")
+	kind := writeFile(t, "kind", "s3\n")
+	got, err := New(makeLookup(map[string]string{"STORE_KIND_FILE": kind}), []string{"test"}, &KindStruct{}, WithEnvFiles())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (&KindStruct{Store: &S3Config{Bucket: "default-bucket", Region: "us-east-1"}}); !reflect.DeepEqual(got, want) {
+		t.Errorf("New() = %+v, want %+v", got, want)
+	}
+
+	host := writeFile(t, "host", "a\n")
+	indexed, err := New(makeLookup(map[string]string{"SERVER_0_HOST_FILE": host}), []string{"test"}, &IndexedStruct{}, WithEnvFiles())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (&IndexedStruct{Servers: []Server{{Host: "a", Port: 80}}}); !reflect.DeepEqual(indexed, want) {
+		t.Errorf("New() = %+v, want %+v", indexed, want)
+	}
+}
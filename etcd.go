@@ -0,0 +1,118 @@
+package config
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// EtcdSource describes the keys under a prefix in etcd, for [WithEtcd].
+type EtcdSource struct {
+	//Endpoint is the base URL of an etcd member, e.g. `https://etcd:2379`.
+	Endpoint string
+	//Prefix selects the keys to read, e.g. `/app/`.
+	Prefix string
+	//Username and Password authenticate with etcd, if set.
+	Username string
+	Password string
+	//TLS configures the connection, e.g. with client certificates or a private CA. It is
+	//ignored if Client is set.
+	TLS *tls.Config
+	//Timeout limits each request. Defaults to 10 seconds. It is ignored if Client is set.
+	Timeout time.Duration
+	//Client is used to send requests. Defaults to a client with the timeout and TLS.
+	Client *http.Client
+}
+
+/*
+WithEtcd adds the keys under a prefix in etcd as a source of values below environment
+variables. It uses the JSON gateway of the etcd v3 API, so no client library is needed.
+The prefix is removed from each key and the remaining `/` separated segments are joined
+with the file key delimiter, so with the prefix `/app/` the key `/app/db/host` sets the
+`DB_HOST` field.
+*/
+func WithEtcd(src EtcdSource) Option {
+	return func(l *loader) {
+		l.sources = append(l.sources, &source{name: "etcd " + src.Endpoint + src.Prefix, open: func(l *loader) (map[string]string, error) {
+			kvs, err := src.fetch()
+			if err != nil {
+				return nil, err
+			}
+			values := make(map[string]string, len(kvs))
+			for k, v := range kvs {
+				values[l.pathKey(strings.TrimPrefix(k, src.Prefix), "/")] = v
+			}
+			return values, nil
+		}})
+	}
+}
+
+// pathKey joins the non-empty segments of the path p, split on sep, with the file key
+// delimiter.
+func (l *loader) pathKey(p, sep string) string {
+	var segments []string
+	for _, s := range strings.Split(p, sep) {
+		if s != "" {
+			segments = append(segments, s)
+		}
+	}
+	return strings.Join(segments, l.keyDelimiter)
+}
+
+func (src EtcdSource) fetch() (map[string]string, error) {
+	client := src.Client
+	if client == nil {
+		client = &http.Client{Timeout: src.Timeout}
+		if client.Timeout == 0 {
+			client.Timeout = 10 * time.Second
+		}
+		if src.TLS != nil {
+			client.Transport = &http.Transport{TLSClientConfig: src.TLS}
+		}
+	}
+	endpoint := strings.TrimSuffix(src.Endpoint, "/")
+	header := http.Header{}
+	if src.Username != "" {
+		var auth struct {
+			Token string `json:"token"`
+		}
+		if err := requestJSON(client, http.MethodPost, endpoint+"/v3/auth/authenticate", header, map[string]string{"name": src.Username, "password": src.Password}, &auth); err != nil {
+			return nil, fmt.Errorf("failed to authenticate: %w", err)
+		}
+		header.Set("Authorization", auth.Token)
+	}
+	var resp struct {
+		Kvs []struct {
+			Key   []byte `json:"key"`
+			Value []byte `json:"value"`
+		} `json:"kvs"`
+	}
+	req := map[string][]byte{"key": []byte(src.Prefix), "range_end": prefixEnd(src.Prefix)}
+	if src.Prefix == "" {
+		req["key"] = []byte{0}
+	}
+	if err := requestJSON(client, http.MethodPost, endpoint+"/v3/kv/range", header, req, &resp); err != nil {
+		return nil, err
+	}
+	kvs := make(map[string]string, len(resp.Kvs))
+	for _, kv := range resp.Kvs {
+		kvs[string(kv.Key)] = string(kv.Value)
+	}
+	return kvs, nil
+}
+
+// prefixEnd returns the end of the range of keys starting with prefix, as expected by
+// etcd: the prefix with its last byte incremented.
+func prefixEnd(prefix string) []byte {
+	end := []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	//Every byte is 0xff, or the prefix is empty, so range over all keys.
+	return []byte{0}
+}
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestEtcd(t *testing.T) {
+	type C struct {
+		Name string `env:"NAME"`
+		DB   struct {
+			Host string `env:"HOST"`
+		}
+	}
+	kvs := map[string]string{"/app/name": "etcd", "/app/db/host": "db", "/other/name": "other"}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v3/auth/authenticate":
+			var req map[string]string
+			json.NewDecoder(r.Body).Decode(&req)
+			if req["name"] != "root" || req["password"] != "secret" {
+				http.Error(w, `{"error":"authentication failed"}`, http.StatusBadRequest)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]string{"token": "t0ken"})
+		case "/v3/kv/range":
+			if r.Header.Get("Authorization") != "t0ken" {
+				http.Error(w, `{"error":"user name is empty"}`, http.StatusUnauthorized)
+				return
+			}
+			var req struct {
+				Key      []byte `json:"key"`
+				RangeEnd []byte `json:"range_end"`
+			}
+			json.NewDecoder(r.Body).Decode(&req)
+			type kv struct {
+				Key   []byte `json:"key"`
+				Value []byte `json:"value"`
+			}
+			var resp struct {
+				Kvs []kv `json:"kvs"`
+			}
+			for k, v := range kvs {
+				if k >= string(req.Key) && k < string(req.RangeEnd) {
+					resp.Kvs = append(resp.Kvs, kv{[]byte(k), []byte(v)})
+				}
+			}
+			json.NewEncoder(w).Encode(resp)
+		}
+	}))
+	defer srv.Close()
+
+	got, err := New(makeLookup(nil), []string{"test"}, &C{}, WithEtcd(EtcdSource{Endpoint: srv.URL, Prefix: "/app/", Username: "root", Password: "secret"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &C{Name: "etcd"}
+	want.DB.Host = "db"
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("New() = %+v, want %+v", got, want)
+	}
+
+	tlsSrv := httptest.NewTLSServer(srv.Config.Handler)
+	defer tlsSrv.Close()
+	got, err = New(makeLookup(nil), []string{"test"}, &C{}, WithEtcd(EtcdSource{Endpoint: tlsSrv.URL, Prefix: "/app/", Username: "root", Password: "secret", Client: tlsSrv.Client()}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("New() with client = %+v, want %+v", got, want)
+	}
+
+	_, err = New(makeLookup(nil), []string{"test"}, &C{}, WithEtcd(EtcdSource{Endpoint: srv.URL, Prefix: "/app/", Username: "root", Password: "wrong"}))
+	if err == nil || !strings.Contains(err.Error(), "authenticate") {
+		t.Errorf("New() error = %v, want authentication error", err)
+	}
+}
+
+func TestPrefixEnd(t *testing.T) {
+	tests := []struct {
+		prefix string
+		want   []byte
+	}{
+		{"/app/", []byte("/app0")},
+		{"a\xff", []byte("b")},
+		{"\xff", []byte{0}},
+		{"", []byte{0}},
+	}
+	for _, tt := range tests {
+		if got := prefixEnd(tt.prefix); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("prefixEnd(%q) = %q, want %q", tt.prefix, got, tt.want)
+		}
+	}
+}
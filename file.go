@@ -0,0 +1,188 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+const (
+	configFileFlag = "config"
+	configFileEnv  = "CONFIG_FILE"
+)
+
+// decoders maps config file extensions to the decoder of their format.
+var decoders = map[string]decoder{
+	".json":       document(parseJSON),
+	".jsonc":      document(parseJSONC),
+	".toml":       document(parseTOML),
+	".ini":        document(parseINI),
+	".hcl":        document(parseHCL),
+	".xml":        document(parseXML),
+	".properties": decodeProperties,
+	".plist":      decodePlist,
+}
+
+// extensions lists the config file extensions in the order they are searched for.
+var extensions = []string{".json", ".jsonc", ".toml", ".ini", ".hcl", ".xml", ".properties", ".plist"}
+
+// findFile returns the first existing file named name, with any of the extensions, in
+// dirs. Empty dirs are skipped.
+func findFile(dirs []string, name string) (string, bool) {
+	for _, dir := range dirs {
+		if dir == "" {
+			continue
+		}
+		for _, ext := range extensions {
+			path := filepath.Join(dir, name+ext)
+			if info, err := os.Stat(path); err == nil && !info.IsDir() {
+				return path, true
+			}
+		}
+	}
+	return "", false
+}
+
+// openFile reads the config file at path in the format given by its extension.
+func (l *loader) openFile(path string) (map[string]string, error) {
+	if path == "-" {
+		return l.readStdin()
+	}
+	ext := strings.ToLower(filepath.Ext(path))
+	switch ext {
+	case ".cue":
+		return l.exportCUE(path)
+	case ".age":
+		return l.decryptAge(path, "")
+	case ".json", ".yaml", ".yml":
+		encrypted, err := isSOPSFile(path, ext)
+		if err != nil {
+			return nil, err
+		}
+		if encrypted {
+			return l.decryptSOPS(path)
+		}
+	}
+	decode, err := decoderFor(path)
+	if err != nil {
+		return nil, err
+	}
+	return l.readFile(path, decode)
+}
+
+// decoderFor returns the decoder of the format given by the extension of path.
+func decoderFor(path string) (decoder, error) {
+	ext := strings.ToLower(filepath.Ext(path))
+	decode, ok := decoders[ext]
+	if !ok {
+		return nil, fmt.Errorf("unsupported config file extension '%s'", ext)
+	}
+	return decode, nil
+}
+
+/*
+WithConfigFile adds a config file as a source of values below environment variables.
+Its path is taken from the `-config` flag or the `CONFIG_FILE` environment variable,
+which are resolved before the rest of the struct, and defaults to path:
+
+	c, err := config.New(os.LookupEnv, os.Args, &C{}, config.WithConfigFile("/etc/app/config.toml"))
+
+The format is chosen by the file's extension: `.json`, `.jsonc`, `.toml`, `.ini`, `.hcl`,
+`.xml`, `.properties`, `.plist`, or `.cue` as for [WithCUEFile]. Files ending in `.age`, such as
+`config.toml.age`, are decrypted as for [WithAgeFile], and files encrypted with SOPS
+as for [WithSOPSFile]. The path `-` reads the config from stdin, e.g.
+`app -config - < config.json`, see [WithStdin]. Several files may be given by repeating
+the flag, or by separating their paths with the OS path list separator in the
+environment variable, e.g. `CONFIG_FILE=base.toml:prod.toml`. They are merged in
+order, so values in later files override those in earlier ones.
+
+A missing file is an error if its path was given by the flag or environment variable,
+but not if it is the default. An empty path loads no file.
+*/
+func WithConfigFile(path string) Option {
+	return func(l *loader) {
+		l.configFile = true
+		s := &source{}
+		s.key = func(l *loader) string {
+			paths, _ := l.configFilePaths(path)
+			return strings.Join(paths, string(filepath.ListSeparator))
+		}
+		s.resolve = func(l *loader) {
+			paths, _ := l.configFilePaths(path)
+			s.name = "file " + strings.Join(paths, ", ")
+		}
+		s.open = func(l *loader) (map[string]string, error) {
+			paths, explicit := l.configFilePaths(path)
+			merged := map[string]string{}
+			for _, p := range paths {
+				values, err := l.openFile(p)
+				if !explicit && errors.Is(err, fs.ErrNotExist) {
+					continue
+				}
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", p, err)
+				}
+				for k, v := range values {
+					merged[strings.ToLower(k)] = v
+				}
+			}
+			return merged, nil
+		}
+		l.sources = append(l.sources, s)
+	}
+}
+
+// configFilePaths returns the paths of the config files given by the `-config` flag or
+// the `CONFIG_FILE` environment variable, or path, and whether they were given.
+func (l *loader) configFilePaths(path string) ([]string, bool) {
+	paths, explicit := []string{path}, true
+	if v := l.scanArgsAll(configFileFlag); len(v) > 0 {
+		paths = v
+	} else if v, ok := l.lookupenv(configFileEnv); ok {
+		paths = filepath.SplitList(v)
+	} else {
+		explicit = false
+	}
+	return slices.DeleteFunc(paths, func(p string) bool { return p == "" }), explicit
+}
+
+// WithConfigFiles adds each of the config files at paths as a source of values below
+// environment variables, in the format given by its extension as for [WithConfigFile].
+// Values in later files override those in earlier ones. A missing file is an error.
+func WithConfigFiles(paths ...string) Option {
+	return func(l *loader) {
+		for _, path := range paths {
+			l.sources = append(l.sources, &source{name: "file " + path, open: func(l *loader) (map[string]string, error) {
+				return l.openFile(path)
+			}})
+		}
+	}
+}
+
+// addFileFields adds fields for the `-config` flag used by WithConfigFile and the
+// `-profile` flag used by WithProfile, so they are accepted on the command line. Each is
+// left out if the struct has a field of its own with that flag.
+func (l *loader) addFileFields(fields []field) []field {
+	if l.configFile {
+		fields = addFlagField(fields, "ConfigFile", configFileEnv, configFileFlag, "Path of the config file", "FILE")
+	}
+	if l.profile {
+		fields = addFlagField(fields, "Profile", profileEnv, profileFlag, "Profile selecting the config and env files to load", "NAME")
+	}
+	return fields
+}
+
+func addFlagField(fields []field, name, env, flag, usage, placeholder string) []field {
+	for _, f := range fields {
+		if f.flag == flag || f.alias == flag {
+			return fields
+		}
+	}
+	tag := reflect.StructTag(fmt.Sprintf("usage:%q placeholder:%q", usage, placeholder))
+	return append(fields, field{value: reflect.New(reflect.TypeFor[string]()).Elem(), tag: tag, name: name, env: env, flag: flag, group: generalGroup})
+}
@@ -0,0 +1,131 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestConfigFile(t *testing.T) {
+	type C struct {
+		Name string `env:"NAME" default:"app"`
+		Port int    `env:"PORT"`
+	}
+	dir := t.TempDir()
+	jsonPath := writeFile(t, "config.json", `{"name": "json", "port": 1}`)
+	tomlPath := writeFile(t, "config.toml", "name = \"toml\"\nport = 2\n")
+	propsPath := writeFile(t, "app.properties", "port=3\n")
+	tests := []struct {
+		name    string
+		env     map[string]string
+		args    []string
+		path    string
+		want    *C
+		wantErr bool
+	}{
+		{name: "default path", args: []string{"test"}, path: jsonPath, want: &C{Name: "json", Port: 1}},
+		{name: "missing default path", args: []string{"test"}, path: filepath.Join(dir, "missing.json"), want: &C{Name: "app"}},
+		{name: "no default path", args: []string{"test"}, want: &C{Name: "app"}},
+		{name: "env", env: map[string]string{"CONFIG_FILE": tomlPath}, args: []string{"test"}, path: jsonPath, want: &C{Name: "toml", Port: 2}},
+		{name: "flag", env: map[string]string{"CONFIG_FILE": tomlPath}, args: []string{"test", "-config", propsPath}, path: jsonPath, want: &C{Name: "app", Port: 3}},
+		{name: "flag with other args", args: []string{"test", "--config=" + tomlPath, "-PORT", "9"}, want: &C{Name: "toml", Port: 9}},
+		{name: "missing explicit path", env: map[string]string{"CONFIG_FILE": filepath.Join(dir, "missing.json")}, args: []string{"test"}, wantErr: true},
+		{name: "unsupported extension", args: []string{"test", "-config", filepath.Join(dir, "config.yaml")}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(makeLookup(tt.env), tt.args, &C{}, WithConfigFile(tt.path))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfigFileField(t *testing.T) {
+	type C struct {
+		Config string `env:"CONFIG_FILE"`
+		Name   string `env:"NAME"`
+	}
+	path := writeFile(t, "config.json", `{"name": "json"}`)
+	got, err := New(makeLookup(map[string]string{"CONFIG_FILE": path}), []string{"test"}, &C{}, WithConfigFile(""))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (&C{Config: path, Name: "json"}); !reflect.DeepEqual(got, want) {
+		t.Errorf("New() = %+v, want %+v", got, want)
+	}
+}
+
+func TestConfigFileCaseInsensitiveFlag(t *testing.T) {
+	type C struct {
+		Name string `env:"NAME"`
+	}
+	path := writeFile(t, "config.json", `{"name": "json"}`)
+	for _, args := range [][]string{{"test", "--CONFIG=" + path}, {"test", "--Config", path}} {
+		got, err := New(makeLookup(nil), args, &C{}, WithConfigFile(""), WithCaseInsensitiveFlags())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Name != "json" {
+			t.Errorf("New() with %q Name = %q, want json", args[1:], got.Name)
+		}
+	}
+}
+
+func TestConfigFileArrays(t *testing.T) {
+	type C struct {
+		Tags  []string `env:"TAGS"`
+		Ports []int    `env:"PORTS" delim:";"`
+		Hosts []string `env:"HOSTS" csv:"true"`
+	}
+	path := writeFile(t, "config.json", `{"tags": ["a,b", "c"], "ports": [1, 2], "hosts": ["x \"y\"", "z"]}`)
+	got, err := New(makeLookup(nil), []string{"test"}, &C{}, WithConfigFiles(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &C{Tags: []string{"a,b", "c"}, Ports: []int{1, 2}, Hosts: []string{`x "y"`, "z"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("New() = %+v, want %+v", got, want)
+	}
+}
+
+func TestConfigFiles(t *testing.T) {
+	type C struct {
+		Name  string `env:"NAME"`
+		Port  int    `env:"PORT"`
+		Debug bool   `env:"DEBUG"`
+	}
+	base := writeFile(t, "base.json", `{"name": "base", "port": 1, "debug": true}`)
+	override := writeFile(t, "override.toml", "port = 2\n")
+	local := writeFile(t, "local.ini", "NAME = local\n")
+	tests := []struct {
+		name    string
+		env     map[string]string
+		args    []string
+		opts    []Option
+		want    *C
+		wantErr bool
+	}{
+		{name: "in order", args: []string{"test"}, opts: []Option{WithConfigFiles(base, override, local)}, want: &C{Name: "local", Port: 2, Debug: true}},
+		{name: "reversed", args: []string{"test"}, opts: []Option{WithConfigFiles(local, override, base)}, want: &C{Name: "base", Port: 1, Debug: true}},
+		{name: "env overrides files", env: map[string]string{"PORT": "3"}, args: []string{"test"}, opts: []Option{WithConfigFiles(base, override)}, want: &C{Name: "base", Port: 3, Debug: true}},
+		{name: "missing file", args: []string{"test"}, opts: []Option{WithConfigFiles(base, filepath.Join(t.TempDir(), "missing.json"))}, wantErr: true},
+		{name: "repeated flag", args: []string{"test", "-config", base, "-config", override}, opts: []Option{WithConfigFile("")}, want: &C{Name: "base", Port: 2, Debug: true}},
+		{name: "env list", env: map[string]string{"CONFIG_FILE": base + string(filepath.ListSeparator) + local}, args: []string{"test"}, opts: []Option{WithConfigFile("")}, want: &C{Name: "local", Port: 1, Debug: true}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(makeLookup(tt.env), tt.args, &C{}, tt.opts...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
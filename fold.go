@@ -0,0 +1,77 @@
+package config
+
+import (
+	"flag"
+	"strings"
+	"unicode/utf8"
+)
+
+/*
+WithCaseInsensitiveFlags matches the names of long flags on the command line regardless
+of case and of whether words are separated by `_` or `-`, so the old and new names of a
+flag both work while moving to kebab cased names:
+
+	app -HTTP_HOST=localhost  # Same as -http-host=localhost
+	app --Http_Host localhost
+
+Names that match several flags of different fields must be given exactly. Short flags
+are single characters and are still matched exactly, so `-v` and `-V` may differ.
+*/
+func WithCaseInsensitiveFlags() Option {
+	return func(l *loader) {
+		l.foldFlags = true
+	}
+}
+
+// foldFlag returns the form of the flag name that names differing only in case and
+// separators have in common.
+func foldFlag(name string) string {
+	return strings.ToLower(strings.ReplaceAll(name, "_", "-"))
+}
+
+// foldArgs rewrites the long flags in args that match no flag of flagset exactly to the
+// name of the flag they match by [foldFlag]. Values of flags and arguments after the
+// flags are left alone. With gnu, arguments with a single dash are short flags.
+func foldArgs(flagset *flag.FlagSet, args []string, gnu bool) []string {
+	folded, ambiguous := map[string]*flag.Flag{}, map[string]bool{}
+	flagset.VisitAll(func(f *flag.Flag) {
+		key := foldFlag(f.Name)
+		if other, ok := folded[key]; ok && other.Value != f.Value {
+			ambiguous[key] = true
+		}
+		folded[key] = f
+	})
+	out := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" || !strings.HasPrefix(arg, "-") || arg == "-" {
+			return append(out, args[i:]...)
+		}
+		dashes := arg[:1]
+		if strings.HasPrefix(arg, "--") {
+			dashes = "--"
+		}
+		takesValue := false
+		if gnu && dashes == "-" {
+			takesValue, _ = splitShorts(flagset, arg[1:], nil)
+		} else {
+			name, value, hasValue := strings.Cut(arg[len(dashes):], "=")
+			f := flagset.Lookup(name)
+			if key := foldFlag(name); f == nil && utf8.RuneCountInString(name) > 1 && !ambiguous[key] && folded[key] != nil {
+				f = folded[key]
+				arg = dashes + f.Name
+				if hasValue {
+					arg += "=" + value
+				}
+			}
+			takesValue = f != nil && !hasValue && !isBoolFlag(f)
+		}
+		out = append(out, arg)
+		//A value given as the next argument is copied as is, even if it starts with `-`.
+		if takesValue && i+1 < len(args) {
+			i++
+			out = append(out, args[i])
+		}
+	}
+	return out
+}
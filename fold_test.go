@@ -0,0 +1,72 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type FoldStruct struct {
+	HTTPHost string   `env:"HTTP_HOST"`
+	Verbose  bool     `env:"VERBOSE" short:"v"`
+	Version  bool     `flag:"version" short:"V"`
+	Level    int      `flag:"log_level"`
+	Files    []string `arg:"rest"`
+}
+
+func TestNewCaseInsensitiveFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		opts    []Option
+		want    *FoldStruct
+		wantErr string
+	}{
+		{name: "EnvStyle", args: []string{"app", "-HTTP_HOST=a"}, want: &FoldStruct{HTTPHost: "a"}},
+		{name: "Mixed", args: []string{"app", "--Http_Host", "a", "-VERBOSE"}, want: &FoldStruct{HTTPHost: "a", Verbose: true}},
+		{name: "Separators", args: []string{"app", "-log-level", "2", "-LOG_LEVEL=3"}, want: &FoldStruct{Level: 3}},
+		{name: "Shorts", args: []string{"app", "-V"}, want: &FoldStruct{Version: true}},
+		{name: "BoolValue", args: []string{"app", "-Verbose=false", "a"}, want: &FoldStruct{Files: []string{"a"}}},
+		{name: "Value", args: []string{"app", "-http-host", "-VERBOSE"}, want: &FoldStruct{HTTPHost: "-VERBOSE"}},
+		{name: "Arguments", args: []string{"app", "a", "-VERBOSE"}, want: &FoldStruct{Files: []string{"a", "-VERBOSE"}}},
+		{name: "GNU", args: []string{"app", "--HTTP_HOST", "a", "-vV"}, opts: []Option{WithGNUFlags()}, want: &FoldStruct{HTTPHost: "a", Verbose: true, Version: true}},
+		{name: "Unknown", args: []string{"app", "-Host"}, wantErr: "failed to parse command line arguments: flag provided but not defined: -Host"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			got, err := New(makeLookup(nil), tt.args, &FoldStruct{}, append(tt.opts, WithCaseInsensitiveFlags(), WithOutput(&b))...)
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("New() error = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("Ambiguous", func(t *testing.T) {
+		var b strings.Builder
+		_, err := New(makeLookup(nil), []string{"app", "-Max-Conns=1"}, &struct {
+			MaxConns  int `flag:"max-conns"`
+			MaxConns2 int `flag:"max_conns"`
+		}{}, WithCaseInsensitiveFlags(), WithOutput(&b))
+		if want := "failed to parse command line arguments: flag provided but not defined: -Max-Conns"; err == nil || err.Error() != want {
+			t.Errorf("New() error = %v, want %s", err, want)
+		}
+	})
+
+	t.Run("Exact", func(t *testing.T) {
+		var b strings.Builder
+		_, err := New(makeLookup(nil), []string{"app", "-Http-Host=a"}, &FoldStruct{}, WithOutput(&b))
+		if err == nil {
+			t.Errorf("New() error = nil, want an error without WithCaseInsensitiveFlags")
+		}
+	})
+}
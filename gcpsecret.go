@@ -0,0 +1,179 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// GCPSecretSource describes how to read secrets from Google Cloud Secret Manager, for
+// [WithGCPSecrets].
+type GCPSecretSource struct {
+	//Project and Prefix select the secrets whose IDs start with Prefix in the project,
+	//if Project is set, to be mapped onto fields.
+	Project string
+	Prefix  string
+	//Token is the OAuth 2 access token sent with requests. Defaults to the
+	//GOOGLE_OAUTH_ACCESS_TOKEN environment variable, or else a token of the default
+	//service account from the metadata server, as on GCE and GKE with workload identity.
+	Token string
+	//Endpoint overrides the URL of the Secret Manager API.
+	Endpoint string
+	//MetadataEndpoint overrides the URL of the metadata server.
+	MetadataEndpoint string
+	//Timeout limits each request. Defaults to 10 seconds. It is ignored if Client is set.
+	Timeout time.Duration
+	//Client is used to send requests. Defaults to a client with the timeout.
+	Client *http.Client
+}
+
+/*
+WithGCPSecrets reads secrets from Google Cloud Secret Manager. Fields with a `gcpsecret`
+tag are resolved from the secret it names, using its latest version unless the tag
+names one:
+
+	type C struct {
+		Password string `gcpsecret:"projects/p/secrets/db-password"`
+		Key      string `gcpsecret:"projects/p/secrets/api-key/versions/3"`
+	}
+
+If the source has a Project, every secret in it whose ID starts with Prefix is also
+added as a source of values below environment variables. The prefix is removed from
+the ID and the remaining `-` separated segments are joined with the file key delimiter,
+so with the prefix `app-` the secret `app-db-password` sets the `DB_PASSWORD` field. A
+missing tagged secret is an error.
+*/
+func WithGCPSecrets(src GCPSecretSource) Option {
+	return func(l *loader) {
+		g := &gcpSecrets{src: src}
+		if src.Project != "" {
+			l.sources = append(l.sources, &source{name: "gcpsecret projects/" + src.Project + "/secrets/" + src.Prefix, open: g.list})
+		}
+		l.resolvers = append(l.resolvers, &resolver{tag: "gcpsecret", name: "gcpsecret", resolve: g.resolve})
+	}
+}
+
+type gcpSecrets struct {
+	src    GCPSecretSource
+	mu     sync.Mutex
+	client *http.Client
+	header http.Header
+}
+
+func (g *gcpSecrets) resolve(l *loader, ref string) (string, error) {
+	if !strings.HasPrefix(ref, "projects/") || !strings.Contains(ref, "/secrets/") {
+		return "", fmt.Errorf("invalid secret name '%s', expected projects/<project>/secrets/<secret>", ref)
+	}
+	if !strings.Contains(ref, "/versions/") {
+		ref += "/versions/latest"
+	}
+	return g.access(l, ref)
+}
+
+// list returns the latest versions of the secrets matching the prefix of the source.
+func (g *gcpSecrets) list(l *loader) (map[string]string, error) {
+	values := map[string]string{}
+	query := url.Values{"filter": {"name:" + g.src.Prefix}}
+	for {
+		var resp struct {
+			Secrets []struct {
+				Name string `json:"name"`
+			} `json:"secrets"`
+			NextPageToken string `json:"nextPageToken"`
+		}
+		if err := g.get(l, "projects/"+g.src.Project+"/secrets?"+query.Encode(), &resp); err != nil {
+			return nil, err
+		}
+		for _, s := range resp.Secrets {
+			_, id, _ := strings.Cut(s.Name, "/secrets/")
+			//The filter matches the prefix anywhere in the name.
+			rest, ok := strings.CutPrefix(id, g.src.Prefix)
+			if !ok {
+				continue
+			}
+			value, err := g.access(l, s.Name+"/versions/latest")
+			if err != nil {
+				return nil, err
+			}
+			values[l.pathKey(rest, "-")] = value
+		}
+		if resp.NextPageToken == "" {
+			return values, nil
+		}
+		query.Set("pageToken", resp.NextPageToken)
+	}
+}
+
+// access returns the payload of the secret version name.
+func (g *gcpSecrets) access(l *loader, name string) (string, error) {
+	var resp struct {
+		Payload struct {
+			Data []byte `json:"data"`
+		} `json:"payload"`
+	}
+	if err := g.get(l, name+":access", &resp); err != nil {
+		var statusErr *statusError
+		if errors.As(err, &statusErr) && statusErr.code == http.StatusNotFound {
+			return "", fmt.Errorf("secret '%s' not found", name)
+		}
+		return "", fmt.Errorf("failed to access secret '%s': %w", name, err)
+	}
+	return string(resp.Payload.Data), nil
+}
+
+func (g *gcpSecrets) get(l *loader, path string, out any) error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.header == nil {
+		if err := g.authenticate(l); err != nil {
+			return err
+		}
+	}
+	endpoint := g.src.Endpoint
+	if endpoint == "" {
+		endpoint = "https://secretmanager.googleapis.com"
+	}
+	return requestJSON(g.client, http.MethodGet, strings.TrimSuffix(endpoint, "/")+"/v1/"+path, g.header, nil, out)
+}
+
+func (g *gcpSecrets) authenticate(l *loader) error {
+	g.client = g.src.Client
+	if g.client == nil {
+		g.client = &http.Client{Timeout: g.src.Timeout}
+		if g.client.Timeout == 0 {
+			g.client.Timeout = 10 * time.Second
+		}
+	}
+	token, err := gcpToken(l, g.client, g.src.Token, g.src.MetadataEndpoint)
+	if err != nil {
+		return err
+	}
+	g.header = http.Header{"Authorization": {"Bearer " + token}}
+	return nil
+}
+
+// gcpToken returns token, or else the GOOGLE_OAUTH_ACCESS_TOKEN environment variable, or
+// else a token of the default service account from the metadata server.
+func gcpToken(l *loader, client *http.Client, token, metadata string) (string, error) {
+	if token == "" {
+		token, _ = l.lookupenv("GOOGLE_OAUTH_ACCESS_TOKEN")
+	}
+	if token != "" {
+		return token, nil
+	}
+	if metadata == "" {
+		metadata = "http://metadata.google.internal"
+	}
+	var resp struct {
+		AccessToken string `json:"access_token"`
+	}
+	u := strings.TrimSuffix(metadata, "/") + "/computeMetadata/v1/instance/service-accounts/default/token"
+	if err := requestJSON(client, http.MethodGet, u, http.Header{"Metadata-Flavor": {"Google"}}, nil, &resp); err != nil {
+		return "", fmt.Errorf("failed to get an access token from the metadata server: %w", err)
+	}
+	return resp.AccessToken, nil
+}
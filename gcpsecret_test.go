@@ -0,0 +1,89 @@
+package config
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestGCPSecrets(t *testing.T) {
+	secrets := map[string]string{
+		"projects/p/secrets/app-db-password/versions/latest": "cHc=",
+		"projects/p/secrets/app-name/versions/latest":        "YXBw",
+		"projects/p/secrets/api-key/versions/3":              "azN5",
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Path == "/computeMetadata/v1/instance/service-accounts/default/token":
+			if r.Header.Get("Metadata-Flavor") != "Google" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.Write([]byte(`{"access_token": "meta-token", "expires_in": 3600}`))
+			return
+		case r.Header.Get("Authorization") != "Bearer meta-token":
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		case r.URL.Path == "/v1/projects/p/secrets":
+			if r.URL.Query().Get("filter") != "name:app-" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			if r.URL.Query().Get("pageToken") == "" {
+				w.Write([]byte(`{"secrets": [{"name": "projects/p/secrets/app-db-password"}, {"name": "projects/p/secrets/my-app-x"}], "nextPageToken": "2"}`))
+				return
+			}
+			w.Write([]byte(`{"secrets": [{"name": "projects/p/secrets/app-name"}]}`))
+			return
+		}
+		name, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/v1/"), ":access")
+		data, found := secrets[name]
+		if !ok || !found {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		w.Write([]byte(`{"name": "` + name + `", "payload": {"data": "` + data + `"}}`))
+	}))
+	defer srv.Close()
+	src := GCPSecretSource{Endpoint: srv.URL, MetadataEndpoint: srv.URL}
+
+	type C struct {
+		Name   string `env:"NAME"`
+		APIKey string `gcpsecret:"projects/p/secrets/api-key/versions/3"`
+		DB     struct {
+			Password string `env:"PASSWORD"`
+		}
+	}
+	prefixed := src
+	prefixed.Project, prefixed.Prefix = "p", "app-"
+	got, err := New(makeLookup(nil), []string{"test"}, &C{}, WithGCPSecrets(prefixed))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &C{Name: "app", APIKey: "k3y"}
+	want.DB.Password = "pw"
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("New() = %+v, want %+v", got, want)
+	}
+
+	type Latest struct {
+		Password string `gcpsecret:"projects/p/secrets/app-db-password"`
+	}
+	if got, err := New(makeLookup(nil), []string{"test"}, &Latest{}, WithGCPSecrets(src)); err != nil || got.Password != "pw" {
+		t.Errorf("New() = %+v, %v, want latest version", got, err)
+	}
+	type Missing struct {
+		Password string `gcpsecret:"projects/p/secrets/missing"`
+	}
+	if _, err := New(makeLookup(nil), []string{"test"}, &Missing{}, WithGCPSecrets(src)); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("New() error = %v, want not found", err)
+	}
+	type Invalid struct {
+		Password string `gcpsecret:"db-password"`
+	}
+	if _, err := New(makeLookup(nil), []string{"test"}, &Invalid{}, WithGCPSecrets(src)); err == nil {
+		t.Error("New() expected error for invalid secret name")
+	}
+}
@@ -0,0 +1,98 @@
+package config
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// GitSource describes a config file in a git repository, for [WithGit].
+type GitSource struct {
+	//URL of the repository, in any form accepted by `git clone`.
+	URL string
+	//Ref is the branch, tag, or commit to read the file at. Defaults to the remote's
+	//default branch.
+	Ref string
+	//Path of the config file in the repository, whose extension selects its format as
+	//for [WithConfigFile].
+	Path string
+	//Dir is where the repository is cloned and kept between runs. Defaults to a
+	//directory named after the URL in the user's cache directory.
+	Dir string
+}
+
+/*
+WithGit adds a config file from a git repository as a source of values below
+environment variables. The repository is cloned on first use and fetched on later ones,
+and the file is read from the ref without a working tree, so pinning a commit or tag
+gives reproducible settings:
+
+	config.WithGit(config.GitSource{URL: "https://git.example.com/ops/config.git", Ref: "v1.4.0", Path: "app/prod.toml"})
+
+This runs the `git` command, which must be installed and have access to the repository.
+*/
+func WithGit(src GitSource) Option {
+	return func(l *loader) {
+		l.sources = append(l.sources, &source{name: "git " + src.URL + " " + src.Path, open: func(l *loader) (map[string]string, error) {
+			ext := strings.ToLower(filepath.Ext(src.Path))
+			decode, ok := decoders[ext]
+			if !ok {
+				return nil, fmt.Errorf("unsupported config file extension '%s'", ext)
+			}
+			data, err := src.read()
+			if err != nil {
+				return nil, err
+			}
+			return decode(l, data)
+		}})
+	}
+}
+
+func (src GitSource) read() ([]byte, error) {
+	dir := src.Dir
+	if dir == "" {
+		cache, err := os.UserCacheDir()
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256([]byte(src.URL))
+		dir = filepath.Join(cache, "config-git", hex.EncodeToString(sum[:8]))
+	}
+	if _, err := os.Stat(filepath.Join(dir, "HEAD")); errors.Is(err, os.ErrNotExist) {
+		if err := os.MkdirAll(filepath.Dir(dir), 0o700); err != nil {
+			return nil, err
+		}
+		if _, err := runGit("", "clone", "--bare", "--quiet", "--", src.URL, dir); err != nil {
+			return nil, err
+		}
+	} else if _, err := runGit(dir, "fetch", "--quiet", "--tags", "--force", "--", src.URL, "+refs/heads/*:refs/heads/*"); err != nil {
+		return nil, err
+	}
+	ref := src.Ref
+	if ref == "" {
+		ref = "HEAD"
+	}
+	//The ref and path are given after --end-of-options, as `--` would make them a path.
+	return runGit(dir, "show", "--end-of-options", ref+":"+filepath.ToSlash(src.Path))
+}
+
+// runGit runs git with args in dir and returns its stdout, or an error with its stderr.
+func runGit(dir string, args ...string) ([]byte, error) {
+	name := args[0]
+	if dir != "" {
+		args = append([]string{"-C", dir}, args...)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("git", args...)
+	cmd.Stdout, cmd.Stderr = &stdout, &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("git %s: %w: %s", name, err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
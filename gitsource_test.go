@@ -0,0 +1,78 @@
+package config
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestGit(t *testing.T) {
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not available")
+	}
+	repo := t.TempDir()
+	git := func(args ...string) {
+		t.Helper()
+		cmd := exec.Command("git", append([]string{"-C", repo, "-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)...)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v: %v: %s", args, err, out)
+		}
+	}
+	commit := func(content, tag string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Join(repo, "app"), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(repo, "app", "prod.toml"), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		git("add", "-A")
+		git("commit", "-q", "-m", content)
+		if tag != "" {
+			git("tag", tag)
+		}
+	}
+	git("init", "-q", "-b", "main")
+	commit(`name = "v1"`, "v1")
+
+	type C struct {
+		Name string `env:"NAME"`
+	}
+	cache := filepath.Join(t.TempDir(), "clone")
+	load := func(ref string) (*C, error) {
+		return New(makeLookup(nil), []string{"test"}, &C{}, WithGit(GitSource{URL: repo, Ref: ref, Path: "app/prod.toml", Dir: cache}))
+	}
+	if got, err := load(""); err != nil || !reflect.DeepEqual(got, &C{Name: "v1"}) {
+		t.Fatalf("New() = %+v, %v, want v1", got, err)
+	}
+	//Later loads fetch new commits into the existing clone.
+	commit(`name = "v2"`, "")
+	if got, err := load(""); err != nil || !reflect.DeepEqual(got, &C{Name: "v2"}) {
+		t.Errorf("New() = %+v, %v, want v2", got, err)
+	}
+	if got, err := load("v1"); err != nil || !reflect.DeepEqual(got, &C{Name: "v1"}) {
+		t.Errorf("New() at tag = %+v, %v, want v1", got, err)
+	}
+	if _, err := New(makeLookup(nil), []string{"test"}, &C{}, WithGit(GitSource{URL: repo, Path: "missing.toml", Dir: cache})); err == nil {
+		t.Error("New() expected error for missing file")
+	}
+	//Values starting with `-` are not read as options.
+	out := filepath.Join(t.TempDir(), "out")
+	if err := os.MkdirAll(out+":app", 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := load("--output=" + out); err == nil {
+		t.Error("New() expected error for a ref starting with -")
+	}
+	if _, err := os.Stat(out + ":app/prod.toml"); !os.IsNotExist(err) {
+		t.Error("ref was read as an option")
+	}
+	if _, err := New(makeLookup(nil), []string{"test"}, &C{}, WithGit(GitSource{URL: "--upload-pack=touch " + out + ";", Path: "app/prod.toml", Dir: repo})); err == nil {
+		t.Error("New() expected error for a URL starting with -")
+	}
+	if _, err := os.Stat(out); !os.IsNotExist(err) {
+		t.Error("URL was read as an option")
+	}
+}
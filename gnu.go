@@ -0,0 +1,93 @@
+package config
+
+import (
+	"flag"
+	"strings"
+	"unicode/utf8"
+)
+
+/*
+WithGNUFlags parses the command line the way GNU getopt does, rather than as the `flag`
+package does. Long flags are given with two dashes, as `--name=value` or `--name value`,
+and a single dash introduces short flags, which may be combined:
+
+	app --verbose --output=out.txt  # Long flags
+	app -v -o out.txt               # Short flags
+	app -vvo out.txt                # Combined short flags, with -o taking out.txt
+	app -oout.txt                   # A short flag with its value attached
+
+A single dash is always followed by short flags, so `-verbose` is read as `-v -e -r
+...`. The help listing shows long flags with two dashes to match.
+*/
+func WithGNUFlags() Option {
+	return func(l *loader) {
+		l.gnuFlags = true
+	}
+}
+
+// longFlag returns the prefix of long flags in messages and the help listing.
+func (l *loader) longFlag() string {
+	if l.gnuFlags {
+		return "--"
+	}
+	return "-"
+}
+
+// gnuArgs rewrites the GNU style flags in args into the form the `flag` package parses,
+// splitting combined short flags into one argument each, such as `-vvo out.txt` into
+// `-v -v -o out.txt`. Values of flags and arguments after the flags are left alone.
+func gnuArgs(flagset *flag.FlagSet, args []string) []string {
+	out := make([]string, 0, len(args))
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		if arg == "--" || !strings.HasPrefix(arg, "-") || arg == "-" {
+			return append(out, args[i:]...)
+		}
+		takesValue := false
+		if strings.HasPrefix(arg, "--") {
+			name, _, hasValue := strings.Cut(arg[2:], "=")
+			out = append(out, arg)
+			takesValue = !hasValue && !isBoolFlag(flagset.Lookup(name))
+		} else {
+			takesValue, out = splitShorts(flagset, arg[1:], out)
+		}
+		//A value given as the next argument is copied as is, even if it starts with `-`.
+		if takesValue && i+1 < len(args) {
+			i++
+			out = append(out, args[i])
+		}
+	}
+	return out
+}
+
+// splitShorts appends the combined short flags in shorts to out, and reports whether
+// the last of them takes the next argument as its value. An unknown flag is appended
+// with the rest of shorts left as is, for the `flag` package to report it.
+func splitShorts(flagset *flag.FlagSet, shorts string, out []string) (bool, []string) {
+	for shorts != "" {
+		_, size := utf8.DecodeRuneInString(shorts)
+		name, rest := shorts[:size], shorts[size:]
+		f := flagset.Lookup(name)
+		switch {
+		case f == nil || strings.HasPrefix(rest, "=") && isBoolFlag(f):
+			return false, append(out, "-"+shorts)
+		case isBoolFlag(f):
+			out = append(out, "-"+name)
+			shorts = rest
+		case rest == "":
+			return true, append(out, "-"+name)
+		default:
+			return false, append(out, "-"+name+"="+strings.TrimPrefix(rest, "="))
+		}
+	}
+	return false, out
+}
+
+// isBoolFlag reports whether f is a flag that takes no value, like the `flag` package.
+func isBoolFlag(f *flag.Flag) bool {
+	if f == nil {
+		return false
+	}
+	b, ok := f.Value.(interface{ IsBoolFlag() bool })
+	return ok && b.IsBoolFlag()
+}
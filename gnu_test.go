@@ -0,0 +1,76 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type GNUStruct struct {
+	Verbose int      `flag:"verbose" short:"v" count:"true"`
+	All     bool     `flag:"all" short:"a"`
+	Output  string   `env:"OUTPUT" short:"o"`
+	Cache   bool     `env:"CACHE" default:"true"`
+	Tags    []string `flag:"tag" short:"t"`
+	Files   []string `arg:"rest"`
+}
+
+func TestNewGNUFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    *GNUStruct
+		wantErr string
+	}{
+		{name: "Long", args: []string{"app", "--verbose", "--all", "--output=out.txt"}, want: &GNUStruct{Verbose: 1, All: true, Output: "out.txt", Cache: true}},
+		{name: "LongSeparateValue", args: []string{"app", "--output", "-out.txt", "a"}, want: &GNUStruct{Output: "-out.txt", Cache: true, Files: []string{"a"}}},
+		{name: "Short", args: []string{"app", "-v", "-o", "out.txt"}, want: &GNUStruct{Verbose: 1, Output: "out.txt", Cache: true}},
+		{name: "Combined", args: []string{"app", "-vvva"}, want: &GNUStruct{Verbose: 3, All: true, Cache: true}},
+		{name: "CombinedValue", args: []string{"app", "-avo", "out.txt"}, want: &GNUStruct{Verbose: 1, All: true, Output: "out.txt", Cache: true}},
+		{name: "AttachedValue", args: []string{"app", "-oout.txt", "-o=x", "-t", "-a", "-tb"}, want: &GNUStruct{Output: "x", Cache: true, Tags: []string{"-a", "b"}}},
+		{name: "BoolValue", args: []string{"app", "-a=false", "--all=false"}, want: &GNUStruct{Cache: true}},
+		{name: "Negation", args: []string{"app", "--no-cache"}, want: &GNUStruct{}},
+		{name: "Terminator", args: []string{"app", "-a", "--", "-v"}, want: &GNUStruct{All: true, Cache: true, Files: []string{"-v"}}},
+		{name: "StopsAtArgument", args: []string{"app", "a", "-v"}, want: &GNUStruct{Cache: true, Files: []string{"a", "-v"}}},
+		{name: "Stdin", args: []string{"app", "-"}, want: &GNUStruct{Cache: true, Files: []string{"-"}}},
+		{name: "UnknownShort", args: []string{"app", "-axv"}, wantErr: "failed to parse command line arguments: flag provided but not defined: -xv"},
+		{name: "LongWithOneDash", args: []string{"app", "-all"}, wantErr: "failed to parse command line arguments: flag provided but not defined: -ll"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			got, err := New(makeLookup(nil), tt.args, &GNUStruct{}, WithGNUFlags(), WithOutput(&b))
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("New() error = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewGNUFlagsHelp(t *testing.T) {
+	var b strings.Builder
+	if _, err := New(makeLookup(nil), []string{"app", "-h"}, &GNUStruct{}, WithGNUFlags(), WithOutput(&b)); err != ErrHelp {
+		t.Fatalf("New() error = %v, want ErrHelp", err)
+	}
+	for _, line := range []string{"  -v, --verbose\n", "  -o, --output string", "      --cache, --no-cache", "  -h, --help"} {
+		if !strings.Contains(b.String(), line) {
+			t.Errorf("New() printed\n%s\nwant it to contain %q", b.String(), line)
+		}
+	}
+
+	_, err := New(makeLookup(nil), []string{"app"}, &struct {
+		Token string `flag:"token" required:"true"`
+	}{}, WithGNUFlags())
+	if err == nil || err.Error() != "missing required setting Token (flag --token)" {
+		t.Errorf("New() error = %v, want the long flag with two dashes", err)
+	}
+}
@@ -0,0 +1,264 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// GRPCSource describes a config service implementing the ConfigService contract, for
+// [WithGRPC].
+type GRPCSource struct {
+	//Address is the base URL of the service, e.g. `https://config.internal:8443`. Only
+	//TLS is supported, as the standard library has no client for unencrypted HTTP/2.
+	Address string
+	//Name is sent in requests to select the config, e.g. the name of the application.
+	Name string
+	//Header is sent as request metadata, e.g. an `Authorization` header.
+	Header http.Header
+	//TLS configures the connection, if set.
+	TLS *tls.Config
+	//Timeout limits GetConfig calls. Defaults to 10 seconds.
+	Timeout time.Duration
+	//Client is used to send requests. Defaults to a client using HTTP/2 with the TLS
+	//configuration. It must not have a timeout for [GRPCSource.Watch].
+	Client *http.Client
+}
+
+/*
+WithGRPC adds the values returned by the GetConfig method of a gRPC config service as a
+source of values below environment variables. Keys are matched like those of a
+flattened config file, so the key `db_host` sets the `DB_HOST` field. The service
+implements this contract, which is also in configservice.proto:
+
+	syntax = "proto3";
+
+	package config.v1;
+
+	service ConfigService {
+	  rpc GetConfig(GetConfigRequest) returns (GetConfigResponse);
+	  rpc WatchConfig(GetConfigRequest) returns (stream GetConfigResponse);
+	}
+
+	message GetConfigRequest {
+	  string name = 1;
+	}
+
+	message GetConfigResponse {
+	  map<string, string> values = 1;
+	}
+
+The values are read once. Use [Watch] or [GRPCSource.Watch] to learn when they change
+and call [New] again. A status other than OK is an error.
+*/
+func WithGRPC(src GRPCSource) Option {
+	return func(l *loader) {
+		l.sources = append(l.sources, &source{name: "grpc " + src.Address, open: func(l *loader) (map[string]string, error) {
+			timeout := src.Timeout
+			if timeout == 0 {
+				timeout = 10 * time.Second
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			var values map[string]string
+			err := src.call(ctx, "GetConfig", func(msg []byte) error {
+				var err error
+				values, err = decodeConfigResponse(msg)
+				return err
+			})
+			return values, err
+		}})
+		l.watchers = append(l.watchers, src)
+	}
+}
+
+/*
+Watch calls the WatchConfig method of the service until ctx is done, and calls onChange
+for each response streamed by it. It returns the error that ended the stream, which is
+ctx.Err() if ctx was done. The service may send the current values as the first
+response, which also calls onChange.
+*/
+func (src GRPCSource) Watch(ctx context.Context, onChange func()) error {
+	err := src.call(ctx, "WatchConfig", func(msg []byte) error {
+		if _, err := decodeConfigResponse(msg); err != nil {
+			return err
+		}
+		onChange()
+		return nil
+	})
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return err
+}
+
+// call sends a GetConfigRequest to method and calls onMsg with each response message.
+func (src GRPCSource) call(ctx context.Context, method string, onMsg func([]byte) error) error {
+	client := src.Client
+	if client == nil {
+		client = &http.Client{Transport: &http.Transport{TLSClientConfig: src.TLS, ForceAttemptHTTP2: true}}
+	}
+	//The request is a single length-prefixed, uncompressed message.
+	msg := appendProtoString(nil, 1, src.Name)
+	body := binary.BigEndian.AppendUint32([]byte{0}, uint32(len(msg)))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimSuffix(src.Address, "/")+"/config.v1.ConfigService/"+method, bytes.NewReader(append(body, msg...)))
+	if err != nil {
+		return err
+	}
+	for k, v := range src.Header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Content-Type", "application/grpc")
+	req.Header.Set("TE", "trailers")
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return &statusError{code: resp.StatusCode, status: resp.Status}
+	}
+	//A status in the headers is a response without messages.
+	if err := grpcStatus(resp.Header); err != nil {
+		return err
+	}
+	r := bufio.NewReader(resp.Body)
+	for {
+		var prefix [5]byte
+		if _, err := io.ReadFull(r, prefix[:]); err == io.EOF {
+			break
+		} else if err != nil {
+			return err
+		}
+		if prefix[0] != 0 {
+			return fmt.Errorf("compressed gRPC messages are not supported")
+		}
+		size := binary.BigEndian.Uint32(prefix[1:])
+		if size > grpcMaxMessageSize {
+			return fmt.Errorf("gRPC message of %d bytes exceeds the limit of %d bytes", size, grpcMaxMessageSize)
+		}
+		msg := make([]byte, size)
+		if _, err := io.ReadFull(r, msg); err != nil {
+			return err
+		}
+		if err := onMsg(msg); err != nil {
+			return err
+		}
+	}
+	if resp.Trailer.Get("Grpc-Status") == "" {
+		return fmt.Errorf("gRPC response has no status")
+	}
+	return grpcStatus(resp.Trailer)
+}
+
+// grpcMaxMessageSize limits the size of received messages, like the default of grpc-go.
+const grpcMaxMessageSize = 4 << 20
+
+// grpcCodes are the names of the gRPC status codes.
+var grpcCodes = []string{"OK", "Canceled", "Unknown", "InvalidArgument", "DeadlineExceeded", "NotFound", "AlreadyExists", "PermissionDenied", "ResourceExhausted", "FailedPrecondition", "Aborted", "OutOfRange", "Unimplemented", "Internal", "Unavailable", "DataLoss", "Unauthenticated"}
+
+// grpcStatus returns the error of the status in header, if any.
+func grpcStatus(header http.Header) error {
+	status := header.Get("Grpc-Status")
+	if status == "" || status == "0" {
+		return nil
+	}
+	code, err := strconv.Atoi(status)
+	name := status
+	if err == nil && code >= 0 && code < len(grpcCodes) {
+		name = grpcCodes[code]
+	}
+	msg, err := url.PathUnescape(header.Get("Grpc-Message"))
+	if err != nil {
+		msg = header.Get("Grpc-Message")
+	}
+	return fmt.Errorf("gRPC status %s: %s", name, msg)
+}
+
+// decodeConfigResponse decodes a GetConfigResponse from the protobuf wire format.
+func decodeConfigResponse(msg []byte) (map[string]string, error) {
+	values := map[string]string{}
+	err := decodeProto(msg, func(field int, data []byte) error {
+		if field != 1 {
+			return nil
+		}
+		//Map entries are messages with the key and value in fields 1 and 2.
+		var k, v string
+		err := decodeProto(data, func(field int, data []byte) error {
+			switch field {
+			case 1:
+				k = string(data)
+			case 2:
+				v = string(data)
+			}
+			return nil
+		})
+		values[strings.ToLower(k)] = v
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("invalid GetConfigResponse: %w", err)
+	}
+	return values, nil
+}
+
+// decodeProto calls onField with each length-delimited field of the protobuf message,
+// and skips fields of other wire types.
+func decodeProto(msg []byte, onField func(field int, data []byte) error) error {
+	for len(msg) > 0 {
+		tag, n := binary.Uvarint(msg)
+		if n <= 0 {
+			return errors.New("invalid field tag")
+		}
+		msg = msg[n:]
+		switch tag & 7 {
+		case 0:
+			if _, n = binary.Uvarint(msg); n <= 0 {
+				return errors.New("invalid varint")
+			}
+			msg = msg[n:]
+		case 1, 5:
+			size := 8
+			if tag&7 == 5 {
+				size = 4
+			}
+			if len(msg) < size {
+				return errors.New("truncated field")
+			}
+			msg = msg[size:]
+		case 2:
+			size, n := binary.Uvarint(msg)
+			if n <= 0 || size > uint64(len(msg)-n) {
+				return errors.New("truncated field")
+			}
+			if err := onField(int(tag>>3), msg[n:n+int(size)]); err != nil {
+				return err
+			}
+			msg = msg[n+int(size):]
+		default:
+			return fmt.Errorf("unsupported wire type %d", tag&7)
+		}
+	}
+	return nil
+}
+
+// appendProtoString appends the string field to the protobuf message b, unless it is
+// empty, as proto3 leaves out default values.
+func appendProtoString(b []byte, field int, s string) []byte {
+	if s == "" {
+		return b
+	}
+	b = binary.AppendUvarint(b, uint64(field)<<3|2)
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
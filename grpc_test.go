@@ -0,0 +1,124 @@
+package config
+
+import (
+	"context"
+	"encoding/binary"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// fakeConfigService serves the ConfigService contract from values, keyed by config name.
+func fakeConfigService(t *testing.T, values map[string]map[string]string) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/grpc")
+		w.Header().Set("Trailer", "Grpc-Status, Grpc-Message")
+		body, _ := io.ReadAll(r.Body)
+		var name string
+		if len(body) < 5 || r.ProtoMajor != 2 || r.Header.Get("Authorization") != "Bearer token" {
+			w.Header().Set("Grpc-Status", "16")
+			return
+		}
+		decodeProto(body[5:], func(field int, data []byte) error {
+			name = string(data)
+			return nil
+		})
+		config, ok := values[name]
+		if !ok {
+			w.Header().Set("Grpc-Status", "5")
+			w.Header().Set("Grpc-Message", "config%20"+name+"%20not%20found")
+			return
+		}
+		var msg []byte
+		for k, v := range config {
+			entry := appendProtoString(appendProtoString(nil, 1, k), 2, v)
+			msg = binary.AppendUvarint(append(msg, 1<<3|2), uint64(len(entry)))
+			msg = append(msg, entry...)
+		}
+		frame := binary.BigEndian.AppendUint32([]byte{0}, uint32(len(msg)))
+		n := 1
+		if strings.HasSuffix(r.URL.Path, "/WatchConfig") {
+			n = 2
+		}
+		for range n {
+			w.Write(append(frame, msg...))
+			w.(http.Flusher).Flush()
+		}
+		if n > 1 {
+			<-r.Context().Done()
+			return
+		}
+		w.Header().Set("Grpc-Status", "0")
+	}))
+	srv.EnableHTTP2 = true
+	srv.StartTLS()
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestGRPC(t *testing.T) {
+	srv := fakeConfigService(t, map[string]map[string]string{
+		"app": {"name": "grpc", "DB_PORT": "5432"},
+	})
+	type C struct {
+		Name string `env:"NAME"`
+		DB   struct {
+			Port int `env:"PORT"`
+		}
+	}
+	src := GRPCSource{Address: srv.URL, Name: "app", Header: http.Header{"Authorization": {"Bearer token"}}, Client: srv.Client()}
+	got, err := New(makeLookup(nil), []string{"test"}, &C{}, WithGRPC(src))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &C{Name: "grpc"}
+	want.DB.Port = 5432
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("New() = %+v, want %+v", got, want)
+	}
+
+	missing := src
+	missing.Name = "missing"
+	_, err = New(makeLookup(nil), []string{"test"}, &C{}, WithGRPC(missing))
+	if want := "failed to load grpc " + srv.URL + ": gRPC status NotFound: config missing not found"; err == nil || err.Error() != want {
+		t.Errorf("New() error = %v, want %s", err, want)
+	}
+	unauthenticated := src
+	unauthenticated.Header = nil
+	_, err = New(makeLookup(nil), []string{"test"}, &C{}, WithGRPC(unauthenticated))
+	if err == nil || !strings.Contains(err.Error(), "gRPC status Unauthenticated") {
+		t.Errorf("New() error = %v, want unauthenticated", err)
+	}
+}
+
+func TestGRPCMessageSize(t *testing.T) {
+	srv := fakeConfigService(t, map[string]map[string]string{"app": {"name": strings.Repeat("x", grpcMaxMessageSize)}})
+	type C struct {
+		Name string `env:"NAME"`
+	}
+	src := GRPCSource{Address: srv.URL, Name: "app", Header: http.Header{"Authorization": {"Bearer token"}}, Client: srv.Client()}
+	_, err := New(makeLookup(nil), []string{"test"}, &C{}, WithGRPC(src))
+	if err == nil || !strings.Contains(err.Error(), "exceeds the limit") {
+		t.Errorf("New() error = %v, want message size error", err)
+	}
+}
+
+func TestGRPCWatch(t *testing.T) {
+	srv := fakeConfigService(t, map[string]map[string]string{"app": {"name": "grpc"}})
+	src := GRPCSource{Address: srv.URL, Name: "app", Header: http.Header{"Authorization": {"Bearer token"}}, Client: srv.Client()}
+	ctx, cancel := context.WithCancel(context.Background())
+	changes := 0
+	err := src.Watch(ctx, func() {
+		changes++
+		if changes == 2 {
+			cancel()
+		}
+	})
+	if err != context.Canceled {
+		t.Errorf("Watch() error = %v, want %v", err, context.Canceled)
+	}
+}
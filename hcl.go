@@ -0,0 +1,399 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+/*
+WithHCLFile adds the HCL file at path as a source of values below environment
+variables. Blocks map to nested structs like JSON objects, and each block label adds a
+level of nesting, so this sets the `DB_HOST` and `SERVICE_WEB_PORT` fields:
+
+	db {
+	  host = "localhost"
+	}
+
+	service "web" {
+	  port = 8080
+	}
+
+Unlabeled blocks that are repeated populate slices of structs. Only literal values are
+supported: strings, heredocs, numbers, bools, lists, and objects. Expressions such as
+`${var.name}` interpolation or function calls are not evaluated. A missing file is an
+error.
+*/
+func WithHCLFile(path string) Option {
+	return withFile(path, document(parseHCL))
+}
+
+// hclParser decodes the native syntax of HCL into maps, slices, and scalar values.
+type hclParser struct {
+	data string
+	pos  int
+	line int
+}
+
+func parseHCL(data []byte) (map[string]any, error) {
+	p := &hclParser{data: string(data), line: 1}
+	body, err := p.parseBody(false)
+	if err != nil {
+		return nil, fmt.Errorf("line %d: %w", p.line, err)
+	}
+	return body, nil
+}
+
+// parseBody parses attributes and blocks until the end of the file, or the closing
+// brace of a block.
+func (p *hclParser) parseBody(inBlock bool) (map[string]any, error) {
+	body := map[string]any{}
+	labeled := map[string]bool{}
+	for {
+		p.skip(true)
+		if p.eof() {
+			if inBlock {
+				return nil, fmt.Errorf("unterminated block")
+			}
+			return body, nil
+		}
+		if p.peek() == '}' && inBlock {
+			p.pos++
+			return body, nil
+		}
+		name, err := p.parseIdent()
+		if err != nil {
+			return nil, err
+		}
+		p.skip(false)
+		if p.peek() == '=' {
+			p.pos++
+			p.skip(false)
+			value, err := p.parseValue()
+			if err != nil {
+				return nil, err
+			}
+			if _, ok := body[name]; ok {
+				return nil, fmt.Errorf("duplicate attribute '%s'", name)
+			}
+			body[name] = value
+			if err := p.endLine(); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		var labels []string
+		for p.peek() != '{' {
+			var label string
+			if p.peek() == '"' {
+				p.pos++
+				label, err = p.parseString()
+			} else {
+				label, err = p.parseIdent()
+			}
+			if err != nil {
+				return nil, fmt.Errorf("expected '=' or block after '%s'", name)
+			}
+			labels = append(labels, label)
+			p.skip(false)
+		}
+		p.pos++
+		block, err := p.parseBody(true)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.addBlock(body, labeled, name, labels, block); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// addBlock adds block to body under its name and labels. Unlabeled blocks that are
+// repeated are collected into a list. labeled records the names of labeled blocks.
+func (p *hclParser) addBlock(body map[string]any, labeled map[string]bool, name string, labels []string, block map[string]any) error {
+	if len(labels) == 0 {
+		switch existing := body[name].(type) {
+		case nil:
+			body[name] = block
+		case map[string]any:
+			if labeled[name] {
+				return fmt.Errorf("block '%s' is both labeled and unlabeled", name)
+			}
+			body[name] = []any{existing, block}
+		case []any:
+			body[name] = append(existing, block)
+		default:
+			return fmt.Errorf("block '%s' conflicts with an attribute", name)
+		}
+		return nil
+	}
+	if _, ok := body[name]; ok && !labeled[name] {
+		return fmt.Errorf("block '%s' is both labeled and unlabeled", name)
+	}
+	labeled[name] = true
+	parent, err := descend(body, append([]string{name}, labels[:len(labels)-1]...))
+	if err != nil {
+		return fmt.Errorf("block '%s': %w", name, err)
+	}
+	last := labels[len(labels)-1]
+	if _, ok := parent[last]; ok {
+		return fmt.Errorf("duplicate block '%s %s'", name, strings.Join(labels, " "))
+	}
+	parent[last] = block
+	return nil
+}
+
+func (p *hclParser) parseValue() (any, error) {
+	switch c := p.peek(); {
+	case c == '"':
+		p.pos++
+		return p.parseString()
+	case c == '[':
+		p.pos++
+		return p.parseList()
+	case c == '{':
+		p.pos++
+		return p.parseObject()
+	case strings.HasPrefix(p.data[p.pos:], "<<"):
+		p.pos += 2
+		return p.parseHeredoc()
+	case c == '-' || isDigit(c):
+		start := p.pos
+		p.pos++
+		for !p.eof() && (isDigit(p.peek()) || strings.IndexByte(".eE+-", p.peek()) >= 0) {
+			p.pos++
+		}
+		number := p.data[start:p.pos]
+		if _, err := strconv.ParseFloat(number, 64); err != nil {
+			return nil, fmt.Errorf("invalid number '%s'", number)
+		}
+		return json.Number(number), nil
+	}
+	ident, err := p.parseIdent()
+	if err != nil {
+		return nil, fmt.Errorf("expected a value")
+	}
+	switch ident {
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "null":
+		return nil, nil
+	}
+	return nil, fmt.Errorf("unsupported expression '%s'", ident)
+}
+
+func (p *hclParser) parseList() ([]any, error) {
+	list := []any{}
+	for {
+		p.skip(true)
+		if p.peek() == ']' {
+			p.pos++
+			return list, nil
+		}
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, value)
+		p.skip(true)
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ']':
+		default:
+			return nil, fmt.Errorf("expected ',' or ']' in list")
+		}
+	}
+}
+
+// parseObject parses an object value, whose items are separated by commas or newlines.
+func (p *hclParser) parseObject() (map[string]any, error) {
+	obj := map[string]any{}
+	for {
+		p.skip(true)
+		if p.peek() == '}' {
+			p.pos++
+			return obj, nil
+		}
+		var key string
+		var err error
+		if p.peek() == '"' {
+			p.pos++
+			key, err = p.parseString()
+		} else {
+			key, err = p.parseIdent()
+		}
+		if err != nil {
+			return nil, err
+		}
+		p.skip(false)
+		if c := p.peek(); c != '=' && c != ':' {
+			return nil, fmt.Errorf("expected '=' or ':' after key '%s'", key)
+		}
+		p.pos++
+		p.skip(false)
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		obj[key] = value
+		p.skip(false)
+		if p.peek() == ',' {
+			p.pos++
+		}
+	}
+}
+
+// parseString parses a quoted string, after the opening quote. Template sequences are
+// rejected, except for the `$${` and `%%{` escapes.
+func (p *hclParser) parseString() (string, error) {
+	var b strings.Builder
+	for {
+		if p.eof() || p.peek() == '\n' {
+			return "", fmt.Errorf("unterminated string")
+		}
+		rest := p.data[p.pos:]
+		switch {
+		case rest[0] == '"':
+			p.pos++
+			return b.String(), nil
+		case strings.HasPrefix(rest, "$${"), strings.HasPrefix(rest, "%%{"):
+			b.WriteString(rest[1:3])
+			p.pos += 3
+		case strings.HasPrefix(rest, "${"), strings.HasPrefix(rest, "%{"):
+			return "", fmt.Errorf("template sequences are not supported")
+		case rest[0] == '\\':
+			value, _, tail, err := strconv.UnquoteChar(rest, '"')
+			if err != nil {
+				return "", fmt.Errorf("invalid escape in string")
+			}
+			b.WriteRune(value)
+			p.pos = len(p.data) - len(tail)
+		default:
+			b.WriteByte(rest[0])
+			p.pos++
+		}
+	}
+}
+
+// parseHeredoc parses a `<<EOF` or indented `<<-EOF` heredoc, after the `<<`.
+func (p *hclParser) parseHeredoc() (string, error) {
+	indented := p.peek() == '-'
+	if indented {
+		p.pos++
+	}
+	marker, err := p.parseIdent()
+	if err != nil {
+		return "", fmt.Errorf("expected heredoc marker")
+	}
+	if err := p.endLine(); err != nil {
+		return "", err
+	}
+	var lines []string
+	for !p.eof() {
+		line, _, _ := strings.Cut(p.data[p.pos:], "\n")
+		p.pos += min(len(line)+1, len(p.data)-p.pos)
+		p.line++
+		if strings.TrimSpace(line) == marker {
+			if indented {
+				lines = dedent(lines)
+			}
+			if len(lines) == 0 {
+				return "", nil
+			}
+			return strings.Join(lines, "\n") + "\n", nil
+		}
+		lines = append(lines, strings.TrimSuffix(line, "\r"))
+	}
+	return "", fmt.Errorf("unterminated heredoc '%s'", marker)
+}
+
+// dedent removes the smallest indentation of the non-blank lines from all of them.
+func dedent(lines []string) []string {
+	indent := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		n := len(line) - len(strings.TrimLeft(line, " \t"))
+		if indent < 0 || n < indent {
+			indent = n
+		}
+	}
+	//There is nothing to remove if every line is blank.
+	indent = max(indent, 0)
+	for i, line := range lines {
+		lines[i] = line[min(indent, len(line)-len(strings.TrimLeft(line, " \t"))):]
+	}
+	return lines
+}
+
+func (p *hclParser) parseIdent() (string, error) {
+	start := p.pos
+	for !p.eof() && (isBareKeyChar(p.peek()) || p.pos > start && p.peek() == '.') {
+		p.pos++
+	}
+	if p.pos == start {
+		return "", fmt.Errorf("expected an identifier")
+	}
+	return p.data[start:p.pos], nil
+}
+
+func (p *hclParser) eof() bool {
+	return p.pos >= len(p.data)
+}
+
+func (p *hclParser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.data[p.pos]
+}
+
+// skip skips whitespace and comments, and newlines if newlines is true.
+func (p *hclParser) skip(newlines bool) {
+	for !p.eof() {
+		rest := p.data[p.pos:]
+		switch {
+		case rest[0] == ' ' || rest[0] == '\t' || rest[0] == '\r':
+			p.pos++
+		case rest[0] == '\n' && newlines:
+			p.line++
+			p.pos++
+		case rest[0] == '#' || strings.HasPrefix(rest, "//"):
+			end := strings.IndexByte(rest, '\n')
+			if end < 0 {
+				end = len(rest)
+			}
+			p.pos += end
+		case strings.HasPrefix(rest, "/*"):
+			end := strings.Index(rest, "*/")
+			if end < 0 {
+				end = len(rest) - 2
+			}
+			p.line += strings.Count(rest[:end], "\n")
+			p.pos += end + 2
+		default:
+			return
+		}
+	}
+}
+
+// endLine consumes the rest of the line, which may only hold whitespace and comments.
+func (p *hclParser) endLine() error {
+	p.skip(false)
+	switch p.peek() {
+	case 0:
+	case '\n':
+		p.line++
+		p.pos++
+	case '}':
+		//A block may be closed on the same line as its last attribute.
+	default:
+		return fmt.Errorf("unexpected '%c' at end of line", p.peek())
+	}
+	return nil
+}
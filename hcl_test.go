@@ -0,0 +1,103 @@
+package config
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParseHCL(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    map[string]any
+		wantErr bool
+	}{
+		{
+			name: "attributes and blocks",
+			input: `# comment
+name = "app" // trailing comment
+debug = true
+/* block
+   comment */
+db {
+  host = "localhost"
+  port = 5432
+}
+`,
+			want: map[string]any{"name": "app", "debug": true, "db": map[string]any{"host": "localhost", "port": json.Number("5432")}},
+		},
+		{
+			name: "labeled blocks",
+			input: `service "web" {
+  port = 80
+}
+service "api" { port = 81 }
+`,
+			want: map[string]any{"service": map[string]any{"web": map[string]any{"port": json.Number("80")}, "api": map[string]any{"port": json.Number("81")}}},
+		},
+		{
+			name:  "repeated blocks",
+			input: "upstream {\n  host = \"a\"\n}\nupstream {\n  host = \"b\"\n}\n",
+			want:  map[string]any{"upstream": []any{map[string]any{"host": "a"}, map[string]any{"host": "b"}}},
+		},
+		{
+			name: "lists, objects and escapes",
+			input: `tags = ["a", "b",
+]
+labels = { env = "prod", "team": "core" }
+greeting = "hi\t$${name}"
+`,
+			want: map[string]any{"tags": []any{"a", "b"}, "labels": map[string]any{"env": "prod", "team": "core"}, "greeting": "hi\t${name}"},
+		},
+		{
+			name:  "heredoc",
+			input: "script = <<-EOT\n    echo one\n      echo two\n    EOT\n",
+			want:  map[string]any{"script": "echo one\n  echo two\n"},
+		},
+		{name: "blank heredoc", input: "a = <<-EOF\n\nEOF\n", want: map[string]any{"a": "\n"}},
+		{name: "whitespace heredoc", input: "a = <<-EOF\n  \n\t\nEOF\n", want: map[string]any{"a": "  \n\t\n"}},
+		{name: "interpolation", input: `name = "${var.name}"`, wantErr: true},
+		{name: "expression", input: "name = var.name\n", wantErr: true},
+		{name: "unterminated block", input: "db {\n", wantErr: true},
+		{name: "duplicate attribute", input: "a = 1\na = 2\n", wantErr: true},
+		{name: "mixed labels", input: "db {}\ndb \"x\" {}\n", wantErr: true},
+		{name: "block over empty list", input: "db = []\ndb \"x\" \"y\" {}\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseHCL([]byte(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseHCL() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseHCL() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHCLFile(t *testing.T) {
+	type Upstream struct {
+		Host string `env:"HOST"`
+	}
+	type C struct {
+		Name     string     `env:"NAME"`
+		Upstream []Upstream `env:"UPSTREAM"`
+		Service  struct {
+			Web struct {
+				Port int `env:"PORT"`
+			}
+		}
+	}
+	path := writeFile(t, "config.hcl", "name = \"app\"\nupstream { host = \"a\" }\nupstream { host = \"b\" }\nservice \"web\" {\n  port = 8080\n}\n")
+	got, err := New(makeLookup(nil), []string{"test"}, &C{}, WithHCLFile(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &C{Name: "app", Upstream: []Upstream{{Host: "a"}, {Host: "b"}}}
+	want.Service.Web.Port = 8080
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("New() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,167 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// HTTPSource describes a JSON config document served over HTTP(S), for [WithHTTP].
+type HTTPSource struct {
+	URL string
+	//Header is added to the request, e.g. an `Authorization` header.
+	Header http.Header
+	//Timeout limits the whole request. Defaults to 10 seconds.
+	Timeout time.Duration
+	//Client is used to send the request. Defaults to http.DefaultClient.
+	Client *http.Client
+}
+
+var (
+	httpCacheMu sync.Mutex
+	httpCache   = map[string]httpCacheEntry{}
+)
+
+// httpCacheKey identifies the cached response of req by its URL and headers, so sources
+// sending different credentials to the same URL don't share responses.
+func httpCacheKey(req *http.Request) string {
+	var b bytes.Buffer
+	b.WriteString(req.URL.String())
+	b.WriteByte('\n')
+	req.Header.Write(&b)
+	return b.String()
+}
+
+type httpCacheEntry struct {
+	etag string
+	body []byte
+}
+
+/*
+WithHTTP adds the JSON document fetched from src as a source of values below
+environment variables, decoded like [WithJSONFile]. Any status other than 200 is an
+error.
+
+Responses with an ETag are cached for the life of the process, keyed by the URL and
+headers of the request, and later calls to [New] send it in an If-None-Match header,
+so a 304 Not Modified response reuses the cached document.
+*/
+func WithHTTP(src HTTPSource) Option {
+	return func(l *loader) {
+		l.sources = append(l.sources, &source{name: "url " + src.URL, open: func(l *loader) (map[string]string, error) {
+			body, err := src.fetch()
+			if err != nil {
+				return nil, err
+			}
+			return document(parseJSON)(l, body)
+		}})
+	}
+}
+
+func (src HTTPSource) fetch() ([]byte, error) {
+	timeout := src.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, src.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range src.Header {
+		req.Header[k] = v
+	}
+	req.Header.Set("Accept", "application/json")
+	key := httpCacheKey(req)
+	httpCacheMu.Lock()
+	cached, ok := httpCache[key]
+	httpCacheMu.Unlock()
+	if ok {
+		req.Header.Set("If-None-Match", cached.etag)
+	}
+	client := src.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	switch {
+	case resp.StatusCode == http.StatusNotModified && ok:
+		return cached.body, nil
+	case resp.StatusCode != http.StatusOK:
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+	if etag := resp.Header.Get("ETag"); etag != "" {
+		httpCacheMu.Lock()
+		httpCache[key] = httpCacheEntry{etag: etag, body: body}
+		httpCacheMu.Unlock()
+	}
+	return body, nil
+}
+
+// statusError is returned by requestJSON for responses with an unexpected status.
+type statusError struct {
+	code   int
+	status string
+	body   []byte
+}
+
+func (e *statusError) Error() string {
+	if len(e.body) == 0 {
+		return "unexpected status " + e.status
+	}
+	return fmt.Sprintf("unexpected status %s: %s", e.status, e.body)
+}
+
+// requestJSON sends in, if not nil, as JSON to url and decodes the JSON response into
+// out, if not nil. Responses other than 200 return a *statusError.
+func requestJSON(client *http.Client, method, url string, header http.Header, in, out any) error {
+	var body io.Reader
+	if in != nil {
+		data, err := json.Marshal(in)
+		if err != nil {
+			return err
+		}
+		body = bytes.NewReader(data)
+	}
+	req, err := http.NewRequestWithContext(context.Background(), method, url, body)
+	if err != nil {
+		return err
+	}
+	req.Header = header.Clone()
+	if req.Header == nil {
+		req.Header = http.Header{}
+	}
+	if in != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return &statusError{code: resp.StatusCode, status: resp.Status, body: bytes.TrimSpace(data)}
+	}
+	if out == nil {
+		return nil
+	}
+	return json.Unmarshal(data, out)
+}
@@ -0,0 +1,92 @@
+package config
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestHTTP(t *testing.T) {
+	type C struct {
+		Name string `env:"NAME"`
+		DB   struct {
+			Port int `env:"PORT"`
+		}
+	}
+	requests, notModified := 0, 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		switch r.URL.Path {
+		case "/config":
+			if r.Header.Get("Authorization") != "Bearer token" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			if r.Header.Get("If-None-Match") == `"v1"` {
+				notModified++
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("ETag", `"v1"`)
+			w.Write([]byte(`{"name": "remote", "db": {"port": 5432}}`))
+		case "/slow":
+			time.Sleep(200 * time.Millisecond)
+			w.Write([]byte(`{}`))
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+	auth := http.Header{"Authorization": {"Bearer token"}}
+	want := &C{Name: "remote"}
+	want.DB.Port = 5432
+	for i := 0; i < 2; i++ {
+		got, err := New(makeLookup(nil), []string{"test"}, &C{}, WithHTTP(HTTPSource{URL: srv.URL + "/config", Header: auth}))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("New() = %+v, want %+v", got, want)
+		}
+	}
+	if requests != 2 || notModified != 1 {
+		t.Errorf("requests = %d, not modified = %d, want 2 and 1", requests, notModified)
+	}
+
+	for _, src := range []HTTPSource{
+		{URL: srv.URL + "/config"},
+		{URL: srv.URL + "/missing"},
+		{URL: srv.URL + "/slow", Timeout: 50 * time.Millisecond},
+	} {
+		if _, err := New(makeLookup(nil), []string{"test"}, &C{}, WithHTTP(src)); err == nil {
+			t.Errorf("New() with %s expected error", src.URL)
+		}
+	}
+}
+
+func TestHTTPCacheHeaders(t *testing.T) {
+	type C struct {
+		Name string `env:"NAME"`
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"v1"`)
+		w.Write([]byte(`{"name": "` + r.Header.Get("Authorization") + `"}`))
+	}))
+	defer srv.Close()
+	for _, token := range []string{"alice", "bob", "alice"} {
+		src := HTTPSource{URL: srv.URL + "/shared", Header: http.Header{"Authorization": {token}}}
+		got, err := New(makeLookup(nil), []string{"test"}, &C{}, WithHTTP(src))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got.Name != token {
+			t.Errorf("New() with %s = %q, want %q", token, got.Name, token)
+		}
+	}
+}
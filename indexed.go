@@ -0,0 +1,95 @@
+package config
+
+import (
+	"reflect"
+	"strconv"
+)
+
+// isStructSlice reports whether t is a slice of structs, or of pointers to structs,
+// that are walked as nested structs.
+func isStructSlice(t reflect.Type) bool {
+	if t.Kind() != reflect.Slice {
+		return false
+	}
+	elem := t.Elem()
+	if elem.Kind() == reflect.Pointer {
+		elem = elem.Elem()
+	}
+	return isStructType(elem)
+}
+
+/*
+Populate the slice of structs f from indexed environment variables such as SERVER_0_HOST
+and SERVER_1_HOST. Elements are added for each index, starting from 0, that has at least
+one of its environment variables set, and the fields of those elements are returned. The
+slice is left alone if f has a value of its own, as it would overwrite the elements.
+*/
+func (l *loader) collectIndexedFields(f field, p prefix) ([]field, error) {
+	if _, ok := f.tag.Lookup("default"); ok {
+		return nil, nil
+	}
+	if _, _, ok := l.lookupSources(f.key); ok {
+		return nil, nil
+	}
+	if _, ok, err := l.lookupEnv(f.env); err != nil || ok {
+		return nil, err
+	}
+	if _, ok := l.scanArgs(f.flag, f.alias, f.short); ok {
+		return nil, nil
+	}
+	elemType := f.value.Type().Elem()
+	isPointer := elemType.Kind() == reflect.Pointer
+	if isPointer {
+		elemType = elemType.Elem()
+	}
+	n := 0
+	for ; ; n++ {
+		fields, err := l.collectFields(reflect.New(elemType).Elem(), "", p.with(strconv.Itoa(n), strconv.Itoa(n), strconv.Itoa(n)))
+		if err != nil {
+			return nil, err
+		}
+		set, err := l.anySet(fields)
+		if err != nil {
+			return nil, err
+		}
+		if !set {
+			break
+		}
+	}
+	if n == 0 {
+		return nil, nil
+	}
+	slice := reflect.MakeSlice(f.value.Type(), n, n)
+	var fields []field
+	for i := 0; i < n; i++ {
+		elem := slice.Index(i)
+		if isPointer {
+			elem.Set(reflect.New(elemType))
+			elem = elem.Elem()
+		}
+		elemFields, err := l.collectFields(elem, f.name+"["+strconv.Itoa(i)+"].", p.with(strconv.Itoa(i), strconv.Itoa(i), strconv.Itoa(i)))
+		if err != nil {
+			return nil, err
+		}
+		fields = append(fields, elemFields...)
+	}
+	f.value.Set(slice)
+	return fields, nil
+}
+
+// anySet reports whether any of fields has its environment variable set or a value in
+// a source.
+func (l *loader) anySet(fields []field) (bool, error) {
+	for _, f := range fields {
+		if _, _, ok := l.lookupSources(f.key); ok {
+			return true, nil
+		}
+		if f.env == "" {
+			continue
+		}
+		if _, ok, err := l.lookupEnv(f.env); err != nil || ok {
+			return ok, err
+		}
+	}
+	return false, nil
+}
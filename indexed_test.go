@@ -0,0 +1,44 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+type Server struct {
+	Host string `env:"HOST"`
+	Port int    `env:"PORT" default:"80"`
+}
+
+type IndexedStruct struct {
+	Servers []Server  `env:"SERVER"`
+	Backups []*Server `env:"BACKUP"`
+}
+
+func TestNewIndexed(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		args    []string
+		want    *IndexedStruct
+		wantErr bool
+	}{
+		{name: "None", env: map[string]string{}, args: []string{"ConfigTestApp"}, want: &IndexedStruct{}},
+		{name: "Indexed", env: map[string]string{"SERVER_0_HOST": "a", "SERVER_0_PORT": "8080", "SERVER_1_HOST": "b", "SERVER_3_HOST": "gap"}, args: []string{"ConfigTestApp"}, want: &IndexedStruct{Servers: []Server{{Host: "a", Port: 8080}, {Host: "b", Port: 80}}}},
+		{name: "Pointers", env: map[string]string{"BACKUP_0_PORT": "1"}, args: []string{"ConfigTestApp", "-BACKUP_0_HOST=c"}, want: &IndexedStruct{Backups: []*Server{{Host: "c", Port: 1}}}},
+		{name: "JSONTakesPrecedence", env: map[string]string{"SERVER": `[{"Host":"j"}]`, "SERVER_0_HOST": "a"}, args: []string{"ConfigTestApp"}, want: &IndexedStruct{Servers: []Server{{Host: "j"}}}},
+		{name: "InvalidElement", env: map[string]string{"SERVER_0_PORT": "http"}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(makeLookup(tt.env), tt.args, &IndexedStruct{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
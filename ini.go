@@ -0,0 +1,73 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+/*
+WithINIFile adds the INI file at path as a source of values below environment
+variables. Sections map to nested structs, and dotted section names to deeper ones, so
+this sets the `DB_HOST` and `DB_REPLICA_HOST` fields:
+
+	[db]
+	host = localhost
+
+	[db.replica]
+	host = replica
+
+Keys and values are separated by `=` or `:`, and lines starting with `;` or `#` are
+comments. Values may be wrapped in double quotes to keep leading or trailing spaces.
+Keys before the first section belong to the top level struct. A missing file is an
+error.
+*/
+func WithINIFile(path string) Option {
+	return withFile(path, document(parseINI))
+}
+
+func parseINI(data []byte) (map[string]any, error) {
+	root := map[string]any{}
+	section := root
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || line[0] == ';' || line[0] == '#' {
+			continue
+		}
+		if line[0] == '[' {
+			name, ok := strings.CutSuffix(line[1:], "]")
+			if !ok || strings.TrimSpace(name) == "" {
+				return nil, fmt.Errorf("line %d: invalid section '%s'", n, line)
+			}
+			var err error
+			section, err = descend(root, strings.Split(strings.TrimSpace(name), "."))
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", n, err)
+			}
+			continue
+		}
+		i := strings.IndexAny(line, "=:")
+		if i <= 0 {
+			return nil, fmt.Errorf("line %d: expected key = value", n)
+		}
+		key, value := strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+		if len(value) >= 2 && value[0] == '"' && value[len(value)-1] == '"' {
+			unquoted, err := strconv.Unquote(value)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: invalid quoted value %s", n, value)
+			}
+			value = unquoted
+		}
+		if _, ok := section[key].(map[string]any); ok {
+			return nil, fmt.Errorf("line %d: key '%s' is a section", n, key)
+		}
+		section[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return root, nil
+}
@@ -0,0 +1,77 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseINI(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    map[string]any
+		wantErr bool
+	}{
+		{
+			name: "sections",
+			input: `; comment
+name = app
+
+[db]
+host: localhost
+port = 5432
+# comment
+
+[db.replica]
+host = "  replica  "
+`,
+			want: map[string]any{
+				"name": "app",
+				"db":   map[string]any{"host": "localhost", "port": "5432", "replica": map[string]any{"host": "  replica  "}},
+			},
+		},
+		{
+			name:  "value with separators",
+			input: "url = http://example.com/?a=b\n",
+			want:  map[string]any{"url": "http://example.com/?a=b"},
+		},
+		{name: "unterminated section", input: "[db\n", wantErr: true},
+		{name: "missing separator", input: "host\n", wantErr: true},
+		{name: "repeated section", input: "[db]\n[top]\n[db]\n", want: map[string]any{"db": map[string]any{}, "top": map[string]any{}}},
+		{name: "section over key", input: "db = 1\n[db]\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseINI([]byte(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseINI() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseINI() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestINIFile(t *testing.T) {
+	type C struct {
+		Name string `env:"NAME"`
+		DB   struct {
+			Host    string `env:"HOST"`
+			Port    int    `env:"PORT"`
+			Replica struct {
+				Host string `env:"HOST"`
+			}
+		}
+	}
+	path := writeFile(t, "settings.ini", "name = app\n[db]\nhost = db\nport = 5432\n[db.replica]\nhost = replica\n")
+	got, err := New(makeLookup(map[string]string{"DB_PORT": "6543"}), []string{"test"}, &C{}, WithINIFile(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &C{Name: "app"}
+	want.DB.Host, want.DB.Port, want.DB.Replica.Host = "db", 6543, "replica"
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("New() = %+v, want %+v", got, want)
+	}
+}
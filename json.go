@@ -0,0 +1,38 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+)
+
+/*
+WithJSONFile adds the JSON file at path as a source of values below environment
+variables. The file must contain an object, whose keys are matched case insensitively
+against the `json` tag of each field, or its `env` tag if it has none. Nested objects
+map to nested structs, so both of these set the `DB_HOST` field:
+
+	{"db": {"host": "localhost"}}
+	{"DB_HOST": "localhost"}
+
+A missing file is an error.
+*/
+func WithJSONFile(path string) Option {
+	return withFile(path, document(parseJSON))
+}
+
+// parseJSON decodes a JSON object, keeping numbers as written. Data after the object is
+// an error.
+func parseJSON(data []byte) (map[string]any, error) {
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	var doc map[string]any
+	if err := d.Decode(&doc); err != nil {
+		return nil, err
+	}
+	if _, err := d.Token(); err != io.EOF {
+		return nil, errors.New("unexpected data after the top level object")
+	}
+	return doc, nil
+}
@@ -0,0 +1,137 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestJSONFile(t *testing.T) {
+	type Server struct {
+		Host string `env:"HOST"`
+	}
+	type C struct {
+		Name    string            `env:"NAME" default:"app"`
+		Port    int               `env:"PORT" default:"80"`
+		Region  string            `json:"region"`
+		Tags    []string          `env:"TAGS"`
+		Labels  map[string]string `env:"LABELS"`
+		Servers []Server          `env:"SERVERS"`
+		DB      struct {
+			Host string `env:"HOST" default:"localhost"`
+			Port int    `json:"port"`
+		} `json:"database"`
+	}
+	tests := []struct {
+		name    string
+		file    string
+		env     map[string]string
+		args    []string
+		opts    []Option
+		want    *C
+		wantErr bool
+	}{
+		{
+			name: "nested objects and tags",
+			file: `{"name": "api", "region": "eu", "database": {"host": "db", "port": 5432}}`,
+			args: []string{"test"},
+			want: func() *C {
+				c := &C{Name: "api", Port: 80, Region: "eu"}
+				c.DB.Host, c.DB.Port = "db", 5432
+				return c
+			}(),
+		},
+		{
+			name: "flat env names",
+			file: `{"NAME": "api", "DATABASE_HOST": "db"}`,
+			args: []string{"test"},
+			want: func() *C {
+				c := &C{Name: "api", Port: 80}
+				c.DB.Host = "db"
+				return c
+			}(),
+		},
+		{
+			name: "env and args take precedence",
+			file: `{"name": "api", "port": 8080}`,
+			env:  map[string]string{"NAME": "env"},
+			args: []string{"test", "-PORT", "9090"},
+			want: func() *C {
+				c := &C{Name: "env", Port: 9090}
+				c.DB.Host = "localhost"
+				return c
+			}(),
+		},
+		{
+			name: "arrays and objects",
+			file: `{"tags": ["a", "b"], "labels": {"env": "prod"}, "servers": [{"HOST": "x"}, {"HOST": "y"}]}`,
+			args: []string{"test"},
+			want: func() *C {
+				c := &C{Name: "app", Port: 80, Tags: []string{"a", "b"}, Labels: map[string]string{"env": "prod"}, Servers: []Server{{Host: "x"}, {Host: "y"}}}
+				c.DB.Host = "localhost"
+				return c
+			}(),
+		},
+		{
+			name: "key delimiter",
+			file: `{"database.host": "db"}`,
+			args: []string{"test"},
+			opts: []Option{WithFileKeyDelimiter(".")},
+			want: func() *C {
+				c := &C{Name: "app", Port: 80}
+				c.DB.Host = "db"
+				return c
+			}(),
+		},
+		{
+			name:    "invalid value",
+			file:    `{"port": "http"}`,
+			args:    []string{"test"},
+			wantErr: true,
+		},
+		{
+			name:    "invalid JSON",
+			file:    `{"port": `,
+			args:    []string{"test"},
+			wantErr: true,
+		},
+		{
+			name:    "trailing data",
+			file:    `{"port": 8080} garbage`,
+			args:    []string{"test"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			opts := append([]Option{WithJSONFile(writeFile(t, "config.json", tt.file))}, tt.opts...)
+			got, err := New(makeLookup(tt.env), tt.args, &C{}, opts...)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJSONFileMissing(t *testing.T) {
+	type C struct {
+		Name string `env:"NAME"`
+	}
+	_, err := New(makeLookup(nil), []string{"test"}, &C{}, WithJSONFile(filepath.Join(t.TempDir(), "missing.json")))
+	if err == nil {
+		t.Fatal("New() expected error for missing file")
+	}
+}
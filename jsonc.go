@@ -0,0 +1,86 @@
+package config
+
+// WithJSONCFile adds the JSONC file at path as a source of values below environment
+// variables, like [WithJSONFile] but allowing the `//` and `/* */` comments and the
+// trailing commas of human edited files:
+//
+//	{
+//		// Overridden by DB_HOST in production.
+//		"db": {
+//			"host": "localhost",
+//		},
+//	}
+//
+// [WithConfigFile] decodes files with the `.jsonc` extension this way. A missing file
+// is an error.
+func WithJSONCFile(path string) Option {
+	return withFile(path, document(parseJSONC))
+}
+
+// parseJSONC decodes a JSON object that may contain comments and trailing commas.
+func parseJSONC(data []byte) (map[string]any, error) {
+	return parseJSON(stripJSONC(data))
+}
+
+// stripJSONC returns a copy of data with comments, and commas followed only by
+// whitespace and comments before a closing `}` or `]`, replaced by spaces. Newlines are
+// kept, so the offsets in errors still match the original data.
+func stripJSONC(data []byte) []byte {
+	out := make([]byte, len(data))
+	copy(out, data)
+	blank := func(start, end int) {
+		for i := start; i < end; i++ {
+			if out[i] != '\n' {
+				out[i] = ' '
+			}
+		}
+	}
+	//comma is the offset of a comma that follows a value, and prev the last byte that is
+	//not whitespace or in a comment.
+	comma, prev := -1, byte(0)
+	for i := 0; i < len(out); i++ {
+		c := out[i]
+		switch {
+		case c == '"':
+			comma = -1
+			for i++; i < len(out) && out[i] != '"'; i++ {
+				if out[i] == '\\' {
+					i++
+				}
+			}
+		case c == '/' && i+1 < len(out) && out[i+1] == '/':
+			end := i
+			for end < len(out) && out[end] != '\n' {
+				end++
+			}
+			blank(i, end)
+			i = end - 1
+			continue
+		case c == '/' && i+1 < len(out) && out[i+1] == '*':
+			end := i + 2
+			for end+1 < len(out) && !(out[end] == '*' && out[end+1] == '/') {
+				end++
+			}
+			end = min(end+2, len(out))
+			blank(i, end)
+			i = end - 1
+			continue
+		case c == ' ' || c == '\t' || c == '\r' || c == '\n':
+			continue
+		case c == ',':
+			comma = -1
+			if prev != 0 && prev != ',' && prev != '[' && prev != '{' {
+				comma = i
+			}
+		case c == '}' || c == ']':
+			if comma >= 0 {
+				out[comma] = ' '
+			}
+			comma = -1
+		default:
+			comma = -1
+		}
+		prev = c
+	}
+	return out
+}
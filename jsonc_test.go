@@ -0,0 +1,44 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestJSONCFile(t *testing.T) {
+	path := writeFile(t, "config.jsonc", `{
+	// The name of the service.
+	"name": "a // not a comment, /* nor this */",
+	/* Connection
+	   settings */
+	"db": {
+		"hosts": ["a", "b",], // trailing commas
+		"port": 5432,
+	},
+}
+`)
+	type C struct {
+		Name string `env:"NAME"`
+		DB   struct {
+			Hosts []string `env:"HOSTS"`
+			Port  int      `env:"PORT"`
+		}
+	}
+	want := &C{Name: "a // not a comment, /* nor this */"}
+	want.DB.Hosts = []string{"a", "b"}
+	want.DB.Port = 5432
+	for _, opt := range []Option{WithJSONCFile(path), WithConfigFiles(path)} {
+		got, err := New(makeLookup(nil), []string{"test"}, &C{}, opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("New() = %+v, want %+v", got, want)
+		}
+	}
+	for _, data := range []string{`{"a": 1,, }`, `{"a": [,]}`, `{"a": 1 /* unterminated`} {
+		if _, err := parseJSONC([]byte(data)); err == nil {
+			t.Errorf("parseJSONC(%s) succeeded, want an error", data)
+		}
+	}
+}
@@ -0,0 +1,180 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+var (
+	kindsMu sync.RWMutex
+	kinds   = map[reflect.Type]map[string]reflect.Type{}
+)
+
+/*
+Register the struct type T as the concrete type of interface I for the discriminator
+value kind. Fields of type I are then set to a new *T, selected by the field's `_KIND`
+setting, and the fields of T are populated as if it were a nested struct:
+
+	type StorageConfig interface{ Open() (Store, error) }
+
+	type S3Config struct {
+		Bucket string `env:"BUCKET"`
+	}
+
+	config.RegisterKind[StorageConfig, S3Config]("s3")
+
+	type C struct {
+		Store StorageConfig `env:"STORE" default:"s3"` // STORE_KIND=s3 selects S3Config, set by STORE_BUCKET
+	}
+
+The `default` tag of the interface field is the default kind. If no kind is provided
+the field is left nil. RegisterKind panics if I is not an interface, T is not a struct,
+or *T does not implement I.
+*/
+func RegisterKind[I any, T any](kind string) {
+	iface, concrete := reflect.TypeFor[I](), reflect.TypeFor[T]()
+	if iface.Kind() != reflect.Interface {
+		panic(fmt.Sprintf("config.RegisterKind: %s is not an interface", iface))
+	}
+	if concrete.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("config.RegisterKind: %s is not a struct", concrete))
+	}
+	if !reflect.PointerTo(concrete).Implements(iface) {
+		panic(fmt.Sprintf("config.RegisterKind: *%s does not implement %s", concrete, iface))
+	}
+	kindsMu.Lock()
+	defer kindsMu.Unlock()
+	if kinds[iface] == nil {
+		kinds[iface] = map[string]reflect.Type{}
+	}
+	kinds[iface][kind] = concrete
+}
+
+// hasKinds reports whether t is an interface type with kinds registered by RegisterKind.
+func hasKinds(t reflect.Type) bool {
+	kindsMu.RLock()
+	defer kindsMu.RUnlock()
+	return len(kinds[t]) > 0
+}
+
+func lookupKind(t reflect.Type, kind string) (reflect.Type, bool) {
+	kindsMu.RLock()
+	defer kindsMu.RUnlock()
+	concrete, ok := kinds[t][kind]
+	return concrete, ok
+}
+
+/*
+Resolve the kind of the interface field v, set it to a new value of the registered
+concrete type, and return the fields of that value along with the field holding the
+kind itself. The kind has to be known before the flag set is built, so it is looked up
+in the command line arguments ahead of parsing them.
+*/
+func (l *loader) collectKindFields(v reflect.Value, sf reflect.StructField, path string, p prefix) ([]field, error) {
+	kindField := field{value: reflect.New(reflect.TypeFor[string]()).Elem(), name: path + "Kind", group: p.group}
+	names := l.names(p.with("KIND", "kind", "KIND"))
+	kindField.env, kindField.flag, kindField.alias, kindField.key = names.env, names.flag, names.alias, names.key
+	kind, ok := sf.Tag.Lookup("default")
+	if ok {
+		kindField.tag = reflect.StructTag(fmt.Sprintf("default:%q", kind))
+	}
+	if value, _, ok := l.lookupSources(kindField.key); ok {
+		kind = value
+	}
+	value, ok, err := l.lookupEnv(kindField.env)
+	if err != nil {
+		return nil, err
+	}
+	if ok {
+		kind = value
+	}
+	if value, ok := l.scanArgs(kindField.flag, kindField.alias); ok {
+		kind = value
+	}
+	fields := []field{kindField}
+	if kind == "" {
+		return fields, nil
+	}
+	concrete, ok := lookupKind(sf.Type, kind)
+	if !ok {
+		return nil, fmt.Errorf("unknown kind '%s' for field %s", kind, path)
+	}
+	ptr := reflect.New(concrete)
+	v.Set(ptr)
+	nested, err := l.collectFields(ptr.Elem(), path+".", p)
+	if err != nil {
+		return nil, err
+	}
+	return append(fields, nested...), nil
+}
+
+// scanArgs returns the last value given for any of the flag names in the command line
+// arguments, without parsing any other flags. It accepts the same `-name=value`,
+// `--name=value`, and `-name value` forms as the flag package, and stops at a `--`
+// terminator. Empty names are ignored. Names are matched as by the flag parsing options,
+// ignoring case with [WithCaseInsensitiveFlags], and as combined short flags after a
+// single dash with [WithGNUFlags].
+func (l *loader) scanArgs(names ...string) (string, bool) {
+	values := l.scanArgsAll(names...)
+	if len(values) == 0 {
+		return "", false
+	}
+	return values[len(values)-1], true
+}
+
+// scanArgsAll returns every value given for the flag names in the command line
+// arguments, in order, like scanArgs.
+func (l *loader) scanArgsAll(names ...string) []string {
+	names = slices.DeleteFunc(slices.Clone(names), func(n string) bool { return n == "" })
+	match := func(name string) bool {
+		return slices.ContainsFunc(names, func(n string) bool {
+			return n == name || l.foldFlags && utf8.RuneCountInString(n) > 1 && foldFlag(n) == foldFlag(name)
+		})
+	}
+	var values []string
+	for i := 0; i < len(l.args); i++ {
+		arg := l.args[i]
+		if arg == "--" {
+			break
+		}
+		if !strings.HasPrefix(arg, "-") {
+			continue
+		}
+		var name, value string
+		var hasValue bool
+		if l.gnuFlags && !strings.HasPrefix(arg, "--") {
+			name, value, hasValue = scanShorts(arg[1:], match)
+		} else {
+			name, value, hasValue = strings.Cut(strings.TrimPrefix(arg[1:], "-"), "=")
+		}
+		if name == "" || !match(name) {
+			continue
+		}
+		if hasValue {
+			values = append(values, value)
+		} else if i+1 < len(l.args) {
+			values = append(values, l.args[i+1])
+			i++
+		}
+	}
+	return values
+}
+
+// scanShorts returns the first of the combined short flags in shorts that matches,
+// with the rest of shorts as its value, if any. The flags before it are taken to have
+// no value, since their types are not known yet.
+func scanShorts(shorts string, match func(string) bool) (name, value string, hasValue bool) {
+	for i, r := range shorts {
+		if name := string(r); match(name) {
+			if rest := shorts[i+len(name):]; rest != "" {
+				return name, strings.TrimPrefix(rest, "="), true
+			}
+			return name, "", false
+		}
+	}
+	return "", "", false
+}
@@ -0,0 +1,112 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+type StorageConfig interface {
+	Kind() string
+}
+
+type S3Config struct {
+	Bucket string `env:"BUCKET" default:"default-bucket"`
+	Region string `env:"REGION" default:"us-east-1"`
+}
+
+func (*S3Config) Kind() string { return "s3" }
+
+type DiskConfig struct {
+	Dir string `env:"DIR" default:"/var/lib/app"`
+}
+
+func (DiskConfig) Kind() string { return "disk" }
+
+type KindStruct struct {
+	Store  StorageConfig `env:"STORE" default:"disk"`
+	Backup StorageConfig
+}
+
+func TestRegisterKind(t *testing.T) {
+	RegisterKind[StorageConfig, S3Config]("s3")
+	RegisterKind[StorageConfig, DiskConfig]("disk")
+	tests := []struct {
+		name    string
+		env     map[string]string
+		args    []string
+		want    *KindStruct
+		wantErr bool
+	}{
+		{name: "Defaults", env: map[string]string{}, args: []string{"ConfigTestApp"}, want: &KindStruct{Store: &DiskConfig{Dir: "/var/lib/app"}}},
+		{name: "SetEnv", env: map[string]string{"STORE_KIND": "s3", "STORE_BUCKET": "b", "BACKUP_KIND": "disk"}, args: []string{"ConfigTestApp"}, want: &KindStruct{Store: &S3Config{Bucket: "b", Region: "us-east-1"}, Backup: &DiskConfig{Dir: "/var/lib/app"}}},
+		{name: "SetArg", env: map[string]string{"STORE_KIND": "disk"}, args: []string{"ConfigTestApp", "-STORE_KIND", "s3", "-STORE_REGION=eu-west-1"}, want: &KindStruct{Store: &S3Config{Bucket: "default-bucket", Region: "eu-west-1"}}},
+		{name: "UnknownKind", env: map[string]string{"STORE_KIND": "gcs"}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+		{name: "FieldOfOtherKind", env: map[string]string{}, args: []string{"ConfigTestApp", "-STORE_BUCKET=b"}, want: nil, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(makeLookup(tt.env), tt.args, &KindStruct{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanArgs(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		want      string
+		wantFound bool
+	}{
+		{name: "Equals", args: []string{"-A=1", "-NAME=x"}, want: "x", wantFound: true},
+		{name: "DoubleDash", args: []string{"--NAME=y"}, want: "y", wantFound: true},
+		{name: "Separate", args: []string{"-NAME", "z", "rest"}, want: "z", wantFound: true},
+		{name: "LastWins", args: []string{"-NAME=a", "-NAME=b"}, want: "b", wantFound: true},
+		{name: "Terminator", args: []string{"--", "-NAME=x"}, want: "", wantFound: false},
+		{name: "Missing", args: []string{"-OTHER=x"}, want: "", wantFound: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, found := (&loader{args: tt.args}).scanArgs("NAME")
+			if got != tt.want || found != tt.wantFound {
+				t.Errorf("scanArgs() = %v, %v, want %v, %v", got, found, tt.want, tt.wantFound)
+			}
+		})
+	}
+}
+
+func TestScanArgsOptions(t *testing.T) {
+	tests := []struct {
+		name      string
+		args      []string
+		opts      []Option
+		want      string
+		wantFound bool
+	}{
+		{name: "Folded", args: []string{"--CONFIG_FILE=x"}, opts: []Option{WithCaseInsensitiveFlags()}, want: "x", wantFound: true},
+		{name: "FoldedSeparate", args: []string{"-Config-File", "y"}, opts: []Option{WithCaseInsensitiveFlags()}, want: "y", wantFound: true},
+		{name: "NotFolded", args: []string{"--CONFIG_FILE=x"}, want: "", wantFound: false},
+		{name: "ShortNotFolded", args: []string{"-C=x"}, opts: []Option{WithCaseInsensitiveFlags()}, want: "", wantFound: false},
+		{name: "GNULong", args: []string{"--config-file", "x"}, opts: []Option{WithGNUFlags()}, want: "x", wantFound: true},
+		{name: "GNUShort", args: []string{"-c", "x"}, opts: []Option{WithGNUFlags()}, want: "x", wantFound: true},
+		{name: "GNUCombined", args: []string{"-vc", "x"}, opts: []Option{WithGNUFlags()}, want: "x", wantFound: true},
+		{name: "GNUAttached", args: []string{"-vcx.json"}, opts: []Option{WithGNUFlags()}, want: "x.json", wantFound: true},
+		{name: "GNUOneDashLong", args: []string{"-config-file=x"}, opts: []Option{WithGNUFlags()}, want: "onfig-file=x", wantFound: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := newLoader(makeLookup(nil), tt.opts)
+			l.args = tt.args
+			got, found := l.scanArgs("config-file", "c")
+			if got != tt.want || found != tt.wantFound {
+				t.Errorf("scanArgs() = %v, %v, want %v, %v", got, found, tt.want, tt.wantFound)
+			}
+		})
+	}
+}
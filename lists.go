@@ -0,0 +1,235 @@
+package config
+
+import (
+	"bytes"
+	"encoding"
+	"encoding/csv"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// splitList splits the value of a slice or map field into its elements, according to
+// the field's `delim` and `csv` tags. An empty value has no elements. A JSON array of
+// scalars, as provided for slices by config files, is split into its elements.
+func splitList(val string, tag reflect.StructTag) ([]string, error) {
+	delim := Delimiter
+	if d, ok := tag.Lookup("delim"); ok {
+		delim = d
+	}
+	if val == "" {
+		return nil, nil
+	}
+	if strings.HasPrefix(val, "[") && json.Valid([]byte(val)) {
+		if elems, ok := arrayElems(val); ok {
+			return elems, nil
+		}
+	}
+	if tag.Get("csv") != "true" {
+		return strings.Split(val, delim), nil
+	}
+	comma, size := utf8.DecodeRuneInString(delim)
+	if size == 0 || size != len(delim) {
+		return nil, fmt.Errorf("csv delimiter '%s' must be a single character", delim)
+	}
+	r := csv.NewReader(strings.NewReader(val))
+	r.Comma = comma
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) != 1 {
+		return nil, fmt.Errorf("csv value has %d lines, expected 1", len(records))
+	}
+	return records[0], nil
+}
+
+/*
+splitPairs splits the value of a map field into key/value pairs. With the `csv` tag the
+elements of splitList are split on their first `=`. Otherwise a backslash escapes the
+next character, and double quotes make the delimiter and `=` literal until the closing
+quote. A JSON object, as provided for maps by config files, is split into its members.
+*/
+func splitPairs(val string, tag reflect.StructTag) ([][2]string, error) {
+	if strings.HasPrefix(val, "{") && json.Valid([]byte(val)) {
+		return objectPairs(val)
+	}
+	if tag.Get("csv") == "true" {
+		parts, err := splitList(val, tag)
+		if err != nil {
+			return nil, err
+		}
+		pairs := make([][2]string, len(parts))
+		for i, part := range parts {
+			k, v, ok := strings.Cut(part, "=")
+			if !ok {
+				return nil, fmt.Errorf("invalid key=value pair '%s'", part)
+			}
+			pairs[i] = [2]string{k, v}
+		}
+		return pairs, nil
+	}
+	delim := Delimiter
+	if d, ok := tag.Lookup("delim"); ok {
+		delim = d
+	}
+	if val == "" {
+		return nil, nil
+	}
+	var pairs [][2]string
+	var key, value strings.Builder
+	current := &key
+	quoted, sawEquals, start := false, false, 0
+	endPair := func(end int) error {
+		if !sawEquals {
+			return fmt.Errorf("invalid key=value pair '%s'", val[start:end])
+		}
+		pairs = append(pairs, [2]string{key.String(), value.String()})
+		key.Reset()
+		value.Reset()
+		current, sawEquals = &key, false
+		return nil
+	}
+	for i := 0; i < len(val); i++ {
+		switch c := val[i]; {
+		case c == '\\':
+			if i+1 == len(val) {
+				return nil, fmt.Errorf("trailing backslash in '%s'", val)
+			}
+			i++
+			current.WriteByte(val[i])
+		case c == '"':
+			quoted = !quoted
+		case quoted:
+			current.WriteByte(c)
+		case c == '=' && !sawEquals:
+			current, sawEquals = &value, true
+		case strings.HasPrefix(val[i:], delim):
+			if err := endPair(i); err != nil {
+				return nil, err
+			}
+			i += len(delim) - 1
+			start = i + 1
+		default:
+			current.WriteByte(c)
+		}
+	}
+	if quoted {
+		return nil, fmt.Errorf("unterminated quote in '%s'", val)
+	}
+	if err := endPair(len(val)); err != nil {
+		return nil, err
+	}
+	return pairs, nil
+}
+
+// maxRangeElems is the most elements the ranges of a value may expand to, so a huge
+// range fails rather than exhausting memory.
+const maxRangeElems = 1 << 16
+
+// expandRanges replaces the inclusive integer ranges in parts, such as `0-3`, with the
+// integers they contain. The start of a range may be negative, e.g. `-2-2`.
+func expandRanges(parts []string) ([]string, error) {
+	var expanded []string
+	for _, part := range parts {
+		i := strings.Index(strings.TrimPrefix(part, "-"), "-")
+		if i == -1 {
+			expanded = append(expanded, part)
+			continue
+		}
+		i += len(part) - len(strings.TrimPrefix(part, "-"))
+		lo, err := strconv.ParseInt(part[:i], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid range '%s': %w", part, err)
+		}
+		hi, err := strconv.ParseInt(part[i+1:], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid range '%s': %w", part, err)
+		}
+		if lo > hi {
+			return nil, fmt.Errorf("invalid range '%s': start is greater than end", part)
+		}
+		//The difference is computed unsigned, as it may not fit in an int64.
+		if size := uint64(hi) - uint64(lo); size >= maxRangeElems || len(expanded)+int(size) >= maxRangeElems {
+			return nil, fmt.Errorf("invalid range '%s': ranges expand to more than %d elements", part, maxRangeElems)
+		}
+		for n := lo; ; n++ {
+			expanded = append(expanded, strconv.FormatInt(n, 10))
+			if n == hi {
+				break
+			}
+		}
+	}
+	return expanded, nil
+}
+
+// arrayElems returns the elements of the JSON array val, or false if any of them is not
+// a scalar.
+func arrayElems(val string) ([]string, bool) {
+	d := json.NewDecoder(bytes.NewReader([]byte(val)))
+	d.UseNumber()
+	var array []any
+	if err := d.Decode(&array); err != nil {
+		return nil, false
+	}
+	elems := make([]string, len(array))
+	for i, e := range array {
+		s, ok := scalarString(e)
+		if !ok {
+			return nil, false
+		}
+		elems[i] = s
+	}
+	return elems, true
+}
+
+// objectPairs returns the members of the JSON object val, whose values must be scalars.
+func objectPairs(val string) ([][2]string, error) {
+	d := json.NewDecoder(bytes.NewReader([]byte(val)))
+	d.UseNumber()
+	var obj map[string]any
+	if err := d.Decode(&obj); err != nil {
+		return nil, err
+	}
+	pairs := make([][2]string, 0, len(obj))
+	for k, v := range obj {
+		s, ok := scalarString(v)
+		if !ok {
+			return nil, fmt.Errorf("invalid value for key '%s' in JSON object", k)
+		}
+		pairs = append(pairs, [2]string{k, s})
+	}
+	return pairs, nil
+}
+
+// isRepeatable reports whether occurrences of the flag of the field with type t and
+// tag are appended to each other, which is the case for slices of elements.
+func isRepeatable(t reflect.Type, tag reflect.StructTag) bool {
+	if t.Kind() != reflect.Slice || t.Elem().Kind() == reflect.Uint8 || tag.Get("format") == "json" || lookupParser(t) != nil {
+		return false
+	}
+	switch reflect.New(t).Interface().(type) {
+	case flag.Value, encoding.TextUnmarshaler, encoding.BinaryUnmarshaler:
+		return false
+	}
+	return true
+}
+
+// setRepeated sets the slice field to the elements of each of values in turn, as given
+// by repeating its flag, e.g. `-tag a -tag b,c` for `[a b c]`.
+func (l *loader) setRepeated(field reflect.Value, tag reflect.StructTag, values []string) error {
+	slice := reflect.MakeSlice(field.Type(), 0, len(values))
+	for _, val := range values {
+		elems := reflect.New(field.Type()).Elem()
+		if err := l.setFieldValue(elems, tag, val); err != nil {
+			return fmt.Errorf("'%s': %w", val, err)
+		}
+		slice = reflect.AppendSlice(slice, elems)
+	}
+	field.Set(slice)
+	return nil
+}
@@ -0,0 +1,153 @@
+package config
+
+import (
+	"net"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type CSVStruct struct {
+	Tags   []string          `env:"TAGS" csv:"true"`
+	Paths  []string          `env:"PATHS" csv:"true" delim:";"`
+	Labels map[string]string `env:"LABELS" csv:"true"`
+	Bad    []string          `env:"BAD" csv:"true" delim:"::"`
+}
+
+func TestNewCSV(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		args    []string
+		want    *CSVStruct
+		wantErr bool
+	}{
+		{name: "Quoted", env: map[string]string{"TAGS": `"a,b",c,"say ""hi"""`}, args: []string{"ConfigTestApp"}, want: &CSVStruct{Tags: []string{"a,b", "c", `say "hi"`}}},
+		{name: "Delimiter", env: map[string]string{"PATHS": `/a;"/b;c"`}, args: []string{"ConfigTestApp"}, want: &CSVStruct{Paths: []string{"/a", "/b;c"}}},
+		{name: "Map", env: map[string]string{}, args: []string{"ConfigTestApp", `-LABELS="team=a,b",env=prod`}, want: &CSVStruct{Labels: map[string]string{"team": "a,b", "env": "prod"}}},
+		{name: "Unquoted", env: map[string]string{"TAGS": "a,b"}, args: []string{"ConfigTestApp"}, want: &CSVStruct{Tags: []string{"a", "b"}}},
+		{name: "UnterminatedQuote", env: map[string]string{"TAGS": `"a,b`}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+		{name: "MultipleLines", env: map[string]string{"TAGS": "a\nb"}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+		{name: "InvalidDelimiter", env: map[string]string{"BAD": "a::b"}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(makeLookup(tt.env), tt.args, &CSVStruct{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type RangeStruct struct {
+	CPUs   []int    `env:"CPUS" ranges:"true" default:"0-3,8,10-11"`
+	Shards []uint16 `env:"SHARDS" ranges:"true"`
+	Plain  []int    `env:"PLAIN"`
+}
+
+func TestNewRanges(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		args    []string
+		want    *RangeStruct
+		wantErr bool
+	}{
+		{name: "Defaults", env: map[string]string{}, args: []string{"ConfigTestApp"}, want: &RangeStruct{CPUs: []int{0, 1, 2, 3, 8, 10, 11}}},
+		{name: "Negative", env: map[string]string{"CPUS": "-2-0,-5"}, args: []string{"ConfigTestApp"}, want: &RangeStruct{CPUs: []int{-2, -1, 0, -5}, Shards: nil}},
+		{name: "SingleElementRange", env: map[string]string{"SHARDS": "3-3"}, args: []string{"ConfigTestApp"}, want: &RangeStruct{CPUs: []int{0, 1, 2, 3, 8, 10, 11}, Shards: []uint16{3}}},
+		{name: "Backwards", env: map[string]string{"CPUS": "3-0"}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+		{name: "Overflow", env: map[string]string{"SHARDS": "65530-65536"}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+		{name: "NotEnabled", env: map[string]string{"PLAIN": "0-3"}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+		{name: "Huge", env: map[string]string{"CPUS": "0-9223372036854775807"}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+		{name: "Widest", env: map[string]string{"CPUS": "-9223372036854775808-9223372036854775807"}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+		{name: "TooManyInTotal", env: map[string]string{"CPUS": "0-40000,0-40000"}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(makeLookup(tt.env), tt.args, &RangeStruct{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type RepeatedStruct struct {
+	Tags    []string        `env:"TAGS" flag:"tag" default:"x"`
+	Ports   []int           `env:"PORTS" ranges:"true"`
+	Backoff []time.Duration `env:"BACKOFF"`
+	IP      net.IP          `env:"IP"`
+	Name    string          `env:"NAME"`
+}
+
+func TestNewRepeatedFlags(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		want    *RepeatedStruct
+		wantErr bool
+	}{
+		{name: "Single", args: []string{"ConfigTestApp", "-tag=a,b"}, want: &RepeatedStruct{Tags: []string{"a", "b"}}},
+		{name: "Repeated", args: []string{"ConfigTestApp", "-tag", "a", "-tag", "b,c", "-TAGS=d"}, want: &RepeatedStruct{Tags: []string{"a", "b", "c", "d"}}},
+		{name: "Ranges", args: []string{"ConfigTestApp", "-ports=1-3", "-ports=8"}, want: &RepeatedStruct{Tags: []string{"x"}, Ports: []int{1, 2, 3, 8}}},
+		{name: "Durations", args: []string{"ConfigTestApp", "-backoff=1s", "-backoff=1m"}, want: &RepeatedStruct{Tags: []string{"x"}, Backoff: []time.Duration{time.Second, time.Minute}}},
+		{name: "Invalid", args: []string{"ConfigTestApp", "-ports=1", "-ports=x"}, wantErr: true},
+		{name: "ScalarSliceLastWins", args: []string{"ConfigTestApp", "-ip=10.0.0.1", "-ip=10.0.0.2"}, want: &RepeatedStruct{Tags: []string{"x"}, IP: net.ParseIP("10.0.0.2")}},
+		{name: "ScalarLastWins", args: []string{"ConfigTestApp", "-name=a", "-name=b"}, want: &RepeatedStruct{Tags: []string{"x"}, Name: "b"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(makeLookup(nil), tt.args, &RepeatedStruct{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+type JSONArrayStruct struct {
+	Tags  []string `env:"TAGS"`
+	Ports []int    `env:"PORTS"`
+}
+
+func TestNewJSONArrays(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		args    []string
+		want    *JSONArrayStruct
+		wantErr bool
+	}{
+		{name: "Env", env: map[string]string{"TAGS": `["x","y"]`}, args: []string{"ConfigTestApp"}, want: &JSONArrayStruct{Tags: []string{"x", "y"}}},
+		{name: "Flag", env: map[string]string{}, args: []string{"ConfigTestApp", `-TAGS=["a,b","c"]`}, want: &JSONArrayStruct{Tags: []string{"a,b", "c"}}},
+		{name: "Numbers", env: map[string]string{"PORTS": `[80, 443]`}, args: []string{"ConfigTestApp"}, want: &JSONArrayStruct{Ports: []int{80, 443}}},
+		{name: "InvalidJSON", env: map[string]string{"TAGS": `[a,b]`}, args: []string{"ConfigTestApp"}, want: &JSONArrayStruct{Tags: []string{"[a", "b]"}}},
+		{name: "NotScalars", env: map[string]string{"TAGS": `[{"a":1}]`}, args: []string{"ConfigTestApp"}, want: &JSONArrayStruct{Tags: []string{`[{"a":1}]`}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(makeLookup(tt.env), tt.args, &JSONArrayStruct{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
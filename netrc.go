@@ -0,0 +1,135 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+/*
+WithNetrc resolves fields with a `netrc` tag from the credentials in a `.netrc` file,
+so command line tools can reuse the logins stored for curl, git, and others. The tag
+holds the machine and the token to read from its entry, `login`, `password`, or
+`account`, separated by `#`:
+
+	type C struct {
+		User     string `netrc:"api.example.com#login"`
+		Password string `netrc:"api.example.com#password"`
+	}
+
+The file is read from path, or if it is empty, from the `NETRC` environment variable
+or `$HOME/.netrc`. A machine without an entry uses the `default` entry, if any. Fields
+are left to their other sources if the file, entry, or token is missing. Environment
+variables and command line arguments for the field still take precedence.
+*/
+func WithNetrc(path string) Option {
+	return func(l *loader) {
+		n := &netrcFile{path: path}
+		l.resolvers = append(l.resolvers, &resolver{tag: "netrc", name: "netrc", resolve: n.resolve})
+	}
+}
+
+type netrcFile struct {
+	path    string
+	once    sync.Once
+	entries map[string]map[string]string
+	err     error
+}
+
+func (n *netrcFile) resolve(l *loader, ref string) (string, error) {
+	machine, token, ok := strings.Cut(ref, "#")
+	if !ok || machine == "" || (token != "login" && token != "password" && token != "account") {
+		return "", fmt.Errorf("invalid netrc reference '%s', expected machine#login, machine#password, or machine#account", ref)
+	}
+	n.once.Do(func() { n.entries, n.err = n.read(l) })
+	if n.err != nil {
+		return "", n.err
+	}
+	entry, ok := n.entries[machine]
+	if !ok {
+		entry, ok = n.entries[""]
+	}
+	value, found := entry[token]
+	if !ok || !found {
+		return "", errNoValue
+	}
+	return value, nil
+}
+
+// read returns the entries of the file keyed by machine, with the default entry under
+// the empty name. A missing file has no entries.
+func (n *netrcFile) read(l *loader) (map[string]map[string]string, error) {
+	path := n.path
+	if path == "" {
+		path, _ = l.lookupenv("NETRC")
+	}
+	if path == "" {
+		home, ok := l.lookupenv("HOME")
+		if !ok || home == "" {
+			return nil, nil
+		}
+		path = filepath.Join(home, ".netrc")
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	entries, err := parseNetrc(string(data))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return entries, nil
+}
+
+func parseNetrc(data string) (map[string]map[string]string, error) {
+	entries := map[string]map[string]string{}
+	var entry map[string]string
+	lines := strings.Split(strings.ReplaceAll(data, "\r\n", "\n"), "\n")
+	for i := 0; i < len(lines); i++ {
+		//Lines starting with `#` are comments, but passwords may contain it.
+		fields := strings.Fields(lines[i])
+		if len(fields) > 0 && strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		for j := 0; j < len(fields); j++ {
+			switch token := fields[j]; token {
+			case "default":
+				entry = map[string]string{}
+				entries[""] = entry
+			case "machine", "login", "password", "account":
+				if j+1 == len(fields) {
+					return nil, fmt.Errorf("line %d: '%s' has no value", i+1, token)
+				}
+				j++
+				if token == "machine" {
+					entry = map[string]string{}
+					//The first entry for a machine is used, as by curl.
+					if _, ok := entries[fields[j]]; !ok {
+						entries[fields[j]] = entry
+					}
+					continue
+				}
+				if entry == nil {
+					return nil, fmt.Errorf("line %d: '%s' outside of a machine entry", i+1, token)
+				}
+				entry[token] = fields[j]
+			case "macdef":
+				//Macros run until the next blank line.
+				for i+1 < len(lines) && strings.TrimSpace(lines[i+1]) != "" {
+					i++
+				}
+				j = len(fields)
+			default:
+				return nil, fmt.Errorf("line %d: unexpected '%s'", i+1, token)
+			}
+		}
+	}
+	return entries, nil
+}
@@ -0,0 +1,73 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNetrc(t *testing.T) {
+	path := writeFile(t, ".netrc", `# Credentials
+machine api.example.com
+  login alice
+  password p#ss
+
+machine api.example.com login ignored password ignored
+macdef init
+  cd /pub
+  login mallory
+
+machine other.example.com login bob password hunter2 account acct
+default login anonymous password guest
+`)
+	type C struct {
+		User     string `netrc:"api.example.com#login"`
+		Password string `netrc:"api.example.com#password"`
+		Account  string `netrc:"other.example.com#account"`
+		Fallback string `netrc:"unknown.example.com#login"`
+		Missing  string `netrc:"api.example.com#account" default:"none"`
+	}
+	want := &C{User: "alice", Password: "p#ss", Account: "acct", Fallback: "anonymous", Missing: "none"}
+	for name, tt := range map[string]struct {
+		env  map[string]string
+		path string
+	}{
+		"path":  {path: path},
+		"NETRC": {env: map[string]string{"NETRC": path}},
+		"HOME":  {env: map[string]string{"HOME": filepath.Dir(path)}},
+	} {
+		got, err := New(makeLookup(tt.env), []string{"test"}, &C{}, WithNetrc(tt.path))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: New() = %+v, want %+v", name, got, want)
+		}
+	}
+
+	type U struct {
+		User string `env:"USER" netrc:"api.example.com#login" default:"nobody"`
+	}
+	if got, err := New(makeLookup(map[string]string{"USER": "env"}), []string{"test"}, &U{}, WithNetrc(path)); err != nil || got.User != "env" {
+		t.Errorf("New() = %+v, %v, want env to take precedence", got, err)
+	}
+	if got, err := New(makeLookup(nil), []string{"test"}, &U{}, WithNetrc(filepath.Join(t.TempDir(), "missing"))); err != nil || got.User != "nobody" {
+		t.Errorf("New() = %+v, %v, want the default for a missing file", got, err)
+	}
+	type Bad struct {
+		User string `netrc:"api.example.com#user"`
+	}
+	if _, err := New(makeLookup(nil), []string{"test"}, &Bad{}, WithNetrc(path)); err == nil || !strings.Contains(err.Error(), "invalid netrc reference") {
+		t.Errorf("New() error = %v, want an invalid reference", err)
+	}
+	for data, wantErr := range map[string]string{
+		"login alice":         "line 1: 'login' outside of a machine entry",
+		"machine a\npassword": "line 2: 'password' has no value",
+		"machine a port 21":   "line 1: unexpected 'port'",
+	} {
+		if _, err := parseNetrc(data); err == nil || err.Error() != wantErr {
+			t.Errorf("parseNetrc(%q) error = %v, want %s", data, err, wantErr)
+		}
+	}
+}
@@ -0,0 +1,222 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ObjectSource describes a config file stored as an object in Amazon S3 or Google
+// Cloud Storage, for [WithObject].
+type ObjectSource struct {
+	//URL names the object, as `s3://bucket/key` or `gs://bucket/object`.
+	URL string
+	//SHA256 is the expected hex encoded SHA-256 digest of the object, if set.
+	SHA256 string
+	//AWS holds the region and credentials used for S3 objects.
+	AWS AWSConfig
+	//GCS holds the credentials used for Cloud Storage objects.
+	GCS GCSConfig
+}
+
+// GCSConfig holds the credentials used to call Google Cloud Storage.
+type GCSConfig struct {
+	//Token is the OAuth 2 access token sent with requests. Defaults to the
+	//GOOGLE_OAUTH_ACCESS_TOKEN environment variable, or else a token of the default
+	//service account from the metadata server, as for [GCPSecretSource].
+	Token string
+	//Endpoint overrides the URL of the Cloud Storage API.
+	Endpoint string
+	//MetadataEndpoint overrides the URL of the metadata server.
+	MetadataEndpoint string
+	//Timeout limits each request. Defaults to 10 seconds.
+	Timeout time.Duration
+	//Client is used to send requests. Defaults to a client with the timeout.
+	Client *http.Client
+}
+
+/*
+WithObject adds a config file downloaded from Amazon S3 or Google Cloud Storage as a
+source of values below environment variables. Its format is chosen by the extension
+of the object's key as for [WithConfigFile]:
+
+	config.WithObject(config.ObjectSource{URL: "s3://fleet-config/prod/app.toml"})
+
+The download is verified against the checksums the store reports for the object, the
+SHA-256 checksum of S3 objects uploaded with one and the MD5 and CRC32C hashes of
+Cloud Storage objects, as well as against the SHA256 of the source if it is set. A
+missing object is an error.
+*/
+func WithObject(src ObjectSource) Option {
+	return func(l *loader) {
+		l.sources = append(l.sources, &source{name: "object " + src.URL, open: func(l *loader) (map[string]string, error) {
+			u, err := url.Parse(src.URL)
+			if err != nil {
+				return nil, err
+			}
+			key := strings.TrimPrefix(u.Path, "/")
+			if u.Host == "" || key == "" {
+				return nil, fmt.Errorf("invalid object URL '%s', expected s3://bucket/key or gs://bucket/object", src.URL)
+			}
+			decode, err := decoderFor(key)
+			if err != nil {
+				return nil, err
+			}
+			var data []byte
+			switch u.Scheme {
+			case "s3":
+				data, err = src.getS3(l, u.Host, key)
+			case "gs":
+				data, err = src.getGCS(l, u.Host, key)
+			default:
+				return nil, fmt.Errorf("unsupported object URL scheme '%s'", u.Scheme)
+			}
+			if err != nil {
+				return nil, err
+			}
+			if src.SHA256 != "" {
+				sum := sha256.Sum256(data)
+				if !strings.EqualFold(hex.EncodeToString(sum[:]), src.SHA256) {
+					return nil, fmt.Errorf("SHA-256 checksum mismatch")
+				}
+			}
+			return decode(l, data)
+		}})
+	}
+}
+
+// getS3 downloads the object key in bucket from S3, verifying its SHA-256 checksum if
+// the object has one.
+func (src ObjectSource) getS3(l *loader, bucket, key string) ([]byte, error) {
+	aws, err := src.AWS.resolve(l)
+	if err != nil {
+		return nil, err
+	}
+	var u string
+	if aws.Endpoint != "" {
+		//Custom endpoints, such as LocalStack or MinIO, use path style addressing.
+		u = strings.TrimSuffix(aws.Endpoint, "/") + "/" + bucket + "/" + escapeKey(key)
+	} else {
+		u = fmt.Sprintf("https://%s.s3.%s.amazonaws.com/%s", bucket, aws.Region, escapeKey(key))
+	}
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	emptyHash := sha256.Sum256(nil)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(emptyHash[:]))
+	req.Header.Set("X-Amz-Checksum-Mode", "ENABLED")
+	aws.sign(req, "s3", nil, time.Now())
+	data, header, err := download(aws.Client, req)
+	if err != nil {
+		var statusErr *statusError
+		if errors.As(err, &statusErr) {
+			var s3Err struct {
+				Code    string
+				Message string
+			}
+			if xml.Unmarshal(statusErr.body, &s3Err) == nil && s3Err.Code != "" {
+				return nil, &awsError{statusError: *statusErr, name: s3Err.Code, message: s3Err.Message}
+			}
+		}
+		return nil, err
+	}
+	//Checksums of multipart uploads, such as `abc=-3`, are of the parts' checksums.
+	if want := header.Get("X-Amz-Checksum-Sha256"); want != "" && !strings.Contains(want, "-") {
+		sum := sha256.Sum256(data)
+		if base64.StdEncoding.EncodeToString(sum[:]) != want {
+			return nil, fmt.Errorf("SHA-256 checksum mismatch")
+		}
+	}
+	return data, nil
+}
+
+// getGCS downloads the object in bucket from Cloud Storage, verifying the hashes it
+// reports. Composite objects only have a CRC32C hash.
+func (src ObjectSource) getGCS(l *loader, bucket, object string) ([]byte, error) {
+	client := src.GCS.Client
+	if client == nil {
+		client = &http.Client{Timeout: src.GCS.Timeout}
+		if client.Timeout == 0 {
+			client.Timeout = 10 * time.Second
+		}
+	}
+	token, err := gcpToken(l, client, src.GCS.Token, src.GCS.MetadataEndpoint)
+	if err != nil {
+		return nil, err
+	}
+	endpoint := src.GCS.Endpoint
+	if endpoint == "" {
+		endpoint = "https://storage.googleapis.com"
+	}
+	u := strings.TrimSuffix(endpoint, "/") + "/storage/v1/b/" + url.PathEscape(bucket) + "/o/" + url.PathEscape(object) + "?alt=media"
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	data, header, err := download(client, req)
+	if err != nil {
+		return nil, err
+	}
+	//The header holds a comma separated list of hashes, e.g. `crc32c=n03x6A==,md5=...`.
+	for _, hash := range header.Values("X-Goog-Hash") {
+		for _, h := range strings.Split(hash, ",") {
+			name, want, _ := strings.Cut(strings.TrimSpace(h), "=")
+			var got []byte
+			switch name {
+			case "md5":
+				sum := md5.Sum(data)
+				got = sum[:]
+			case "crc32c":
+				got = binary.BigEndian.AppendUint32(nil, crc32.Checksum(data, crc32.MakeTable(crc32.Castagnoli)))
+			default:
+				continue
+			}
+			if base64.StdEncoding.EncodeToString(got) != want {
+				return nil, fmt.Errorf("%s checksum mismatch", strings.ToUpper(name))
+			}
+		}
+	}
+	return data, nil
+}
+
+// download sends req and returns the body and header of a 200 response. Any other
+// status is returned as a *statusError.
+func download(client *http.Client, req *http.Request) ([]byte, http.Header, error) {
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, &statusError{code: resp.StatusCode, status: resp.Status, body: bytes.TrimSpace(data)}
+	}
+	return data, resp.Header, nil
+}
+
+// escapeKey escapes each `/` separated segment of an S3 object key.
+func escapeKey(key string) string {
+	segments := strings.Split(key, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,84 @@
+package config
+
+import (
+	"crypto/md5"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestObject(t *testing.T) {
+	const body = `{"name": "fleet", "db": {"port": 5432}}`
+	sha := sha256.Sum256([]byte(body))
+	md := md5.Sum([]byte(body))
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.EscapedPath() {
+		case "/bucket/prod/app%20config.json":
+			if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") || r.Header.Get("X-Amz-Content-Sha256") == "" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.Header().Set("X-Amz-Checksum-Sha256", base64.StdEncoding.EncodeToString(sha[:]))
+			w.Write([]byte(body))
+		case "/bucket/corrupt.json":
+			w.Header().Set("X-Amz-Checksum-Sha256", base64.StdEncoding.EncodeToString(sha[:]))
+			w.Write([]byte(`{}`))
+		case "/storage/v1/b/bucket/o/prod%2Fapp.json":
+			if r.URL.Query().Get("alt") != "media" || r.Header.Get("Authorization") != "Bearer gcs-token" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			w.Header().Set("X-Goog-Hash", "crc32c=M86DyA==,md5="+base64.StdEncoding.EncodeToString(md[:]))
+			w.Write([]byte(body))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			w.Write([]byte(`<?xml version="1.0" encoding="UTF-8"?><Error><Code>NoSuchKey</Code><Message>The specified key does not exist.</Message></Error>`))
+		}
+	}))
+	defer srv.Close()
+	aws := AWSConfig{Region: "us-east-1", AccessKeyID: "AKID", SecretAccessKey: "secret", Endpoint: srv.URL}
+	gcs := GCSConfig{Token: "gcs-token", Endpoint: srv.URL}
+
+	type C struct {
+		Name string `env:"NAME"`
+		DB   struct {
+			Port int `env:"PORT"`
+		}
+	}
+	want := &C{Name: "fleet"}
+	want.DB.Port = 5432
+	for _, src := range []ObjectSource{
+		{URL: "s3://bucket/prod/app config.json", AWS: aws, SHA256: hex.EncodeToString(sha[:])},
+		{URL: "gs://bucket/prod/app.json", GCS: gcs},
+	} {
+		got, err := New(makeLookup(nil), []string{"test"}, &C{}, WithObject(src))
+		if err != nil {
+			t.Fatalf("%s: %v", src.URL, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("%s: New() = %+v, want %+v", src.URL, got, want)
+		}
+	}
+
+	for _, tt := range []struct {
+		src  ObjectSource
+		want string
+	}{
+		{ObjectSource{URL: "s3://bucket/corrupt.json", AWS: aws}, "SHA-256 checksum mismatch"},
+		{ObjectSource{URL: "s3://bucket/prod/app config.json", AWS: aws, SHA256: "00"}, "SHA-256 checksum mismatch"},
+		{ObjectSource{URL: "s3://bucket/missing.json", AWS: aws}, "NoSuchKey: The specified key does not exist."},
+		{ObjectSource{URL: "s3://bucket/config.yaml", AWS: aws}, "unsupported config file extension '.yaml'"},
+		{ObjectSource{URL: "http://bucket/config.json"}, "unsupported object URL scheme 'http'"},
+		{ObjectSource{URL: "s3://bucket"}, "invalid object URL 's3://bucket', expected s3://bucket/key or gs://bucket/object"},
+	} {
+		_, err := New(makeLookup(nil), []string{"test"}, &C{}, WithObject(tt.src))
+		if want := "failed to load object " + tt.src.URL + ": " + tt.want; err == nil || err.Error() != want {
+			t.Errorf("New() error = %v, want %s", err, want)
+		}
+	}
+}
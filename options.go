@@ -0,0 +1,66 @@
+package config
+
+import "io"
+
+// loader holds the settings used by New to resolve and parse values.
+type loader struct {
+	lookupenv     func(string) (string, bool)
+	args          []string
+	pathBase      string
+	envDelimiter  string
+	flagDelimiter string
+	keyDelimiter  string
+	sources       []*source
+	resolvers     []*resolver
+	watchers      []Watcher
+	configFile    bool
+	profile       bool
+	envFiles      bool
+	dotenv        []func(l *loader) (paths []string, optional bool, err error)
+	stdin         io.Reader
+	output        io.Writer
+	deprecated    func(Deprecation)
+	argFiles      bool
+	gnuFlags      bool
+	interspersed  bool
+	foldFlags     bool
+	versionFlag   bool
+	version       string
+	remaining     *[]string
+}
+
+// Option configures optional behavior of New.
+type Option func(*loader)
+
+// WithPathBase sets the directory that relative [Path] values are resolved against.
+// Defaults to the working directory.
+func WithPathBase(dir string) Option {
+	return func(l *loader) {
+		l.pathBase = dir
+	}
+}
+
+// WithEnvDelimiter sets the separator used to join the env names of nested struct
+// fields. Defaults to `_`.
+func WithEnvDelimiter(delim string) Option {
+	return func(l *loader) {
+		l.envDelimiter = delim
+	}
+}
+
+// WithFlagDelimiter sets the separator used to join the env style flag names of nested
+// struct fields, e.g. `-` or `.`. Defaults to `_`, so they match env names. Kebab cased
+// flag names are always joined with `-`.
+func WithFlagDelimiter(delim string) Option {
+	return func(l *loader) {
+		l.flagDelimiter = delim
+	}
+}
+
+// WithFileKeyDelimiter sets the separator used to join the keys of nested objects in
+// config files, and the file keys of nested struct fields. Defaults to `_`.
+func WithFileKeyDelimiter(delim string) Option {
+	return func(l *loader) {
+		l.keyDelimiter = delim
+	}
+}
@@ -0,0 +1,52 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Path is a file system path that is expanded when it is set by New. See the package
+// documentation for the expansion rules.
+type Path string
+
+// expandPath expands a leading `~` and the variables in val, and makes it absolute.
+func (l *loader) expandPath(val string) (Path, error) {
+	if val == "" {
+		return "", nil
+	}
+	original := val
+	if val == "~" || strings.HasPrefix(val, "~/") {
+		home, ok := l.lookupenv("HOME")
+		if !ok || home == "" {
+			var err error
+			if home, err = os.UserHomeDir(); err != nil {
+				return "", err
+			}
+		}
+		val = home + val[1:]
+	}
+	var unset []string
+	val = os.Expand(val, func(name string) string {
+		v, ok := l.lookupenv(name)
+		if !ok {
+			unset = append(unset, name)
+		}
+		return v
+	})
+	if len(unset) > 0 {
+		return "", fmt.Errorf("path '%s' uses the unset variable '%s'", original, unset[0])
+	}
+	if !filepath.IsAbs(val) {
+		base := l.pathBase
+		if base == "" {
+			var err error
+			if base, err = os.Getwd(); err != nil {
+				return "", err
+			}
+		}
+		val = filepath.Join(base, val)
+	}
+	return Path(filepath.Clean(val)), nil
+}
@@ -0,0 +1,48 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+type PathStruct struct {
+	Certs   Path   `env:"CERTS" default:"~/certs"`
+	DataDir Path   `env:"DATA_DIR" default:"data"`
+	Logs    Path   `env:"LOGS" default:"$LOG_ROOT/app"`
+	Extra   []Path `env:"EXTRA"`
+}
+
+func TestNewPath(t *testing.T) {
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		env     map[string]string
+		args    []string
+		opts    []Option
+		want    *PathStruct
+		wantErr bool
+	}{
+		{name: "Defaults", env: map[string]string{"HOME": "/home/user", "LOG_ROOT": "/var/log"}, args: []string{"ConfigTestApp"}, want: &PathStruct{Certs: "/home/user/certs", DataDir: Path(filepath.Join(wd, "data")), Logs: "/var/log/app"}},
+		{name: "PathBase", env: map[string]string{"HOME": "/home/user", "LOG_ROOT": "/var/log"}, args: []string{"ConfigTestApp", "-EXTRA=a,../b,${HOME}/c"}, opts: []Option{WithPathBase("/srv/app")}, want: &PathStruct{Certs: "/home/user/certs", DataDir: "/srv/app/data", Logs: "/var/log/app", Extra: []Path{"/srv/app/a", "/srv/b", "/home/user/c"}}},
+		{name: "OtherUser", env: map[string]string{"HOME": "/home/user", "CERTS": "~other/certs", "LOG_ROOT": "/var/log"}, args: []string{"ConfigTestApp"}, opts: []Option{WithPathBase("/srv")}, want: &PathStruct{Certs: "/srv/~other/certs", DataDir: "/srv/data", Logs: "/var/log/app"}},
+		{name: "Unset", env: map[string]string{"HOME": "/home/user"}, args: []string{"ConfigTestApp"}, wantErr: true},
+		{name: "Empty", env: map[string]string{"HOME": "/home/user", "LOG_ROOT": ""}, args: []string{"ConfigTestApp"}, opts: []Option{WithPathBase("/srv")}, want: &PathStruct{Certs: "/home/user/certs", DataDir: "/srv/data", Logs: "/app"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(makeLookup(tt.env), tt.args, &PathStruct{}, tt.opts...)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
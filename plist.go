@@ -0,0 +1,184 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+/*
+WithPlistFile adds the property list file at path as a source of values below
+environment variables. Its top level dictionary maps to fields like a JSON object, so
+this sets the `DB_HOST` field:
+
+	<plist version="1.0">
+	<dict>
+		<key>db</key>
+		<dict>
+			<key>host</key>
+			<string>localhost</string>
+		</dict>
+	</dict>
+	</plist>
+
+Binary property lists are converted to XML by running `plutil`, which is only available
+on macOS. Data values are given as base64. A missing file is an error.
+*/
+func WithPlistFile(path string) Option {
+	return withFile(path, decodePlist)
+}
+
+/*
+WithDefaults adds the settings of a macOS user defaults domain, such as
+`com.example.app`, as a source of values below environment variables, decoded like
+[WithPlistFile]. They are read by running `defaults export domain -`, so this only works
+on macOS.
+*/
+func WithDefaults(domain string) Option {
+	return func(l *loader) {
+		l.sources = append(l.sources, &source{name: "defaults " + domain, open: func(l *loader) (map[string]string, error) {
+			out, err := runCommand(exec.Command("defaults", "export", domain, "-"))
+			if err != nil {
+				return nil, err
+			}
+			return decodePlist(l, out)
+		}})
+	}
+}
+
+func decodePlist(l *loader, data []byte) (map[string]string, error) {
+	if bytes.HasPrefix(data, []byte("bplist")) {
+		var err error
+		cmd := exec.Command("plutil", "-convert", "xml1", "-o", "-", "-")
+		cmd.Stdin = bytes.NewReader(data)
+		if data, err = runCommand(cmd); err != nil {
+			return nil, fmt.Errorf("failed to convert binary plist: %w", err)
+		}
+	}
+	return document(parsePlist)(l, data)
+}
+
+// parsePlist decodes an XML property list whose top level value is a dictionary.
+func parsePlist(data []byte) (map[string]any, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	inPlist := false
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return nil, fmt.Errorf("expected a plist element: %w", err)
+		}
+		start, ok := token.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if !inPlist {
+			if start.Name.Local != "plist" {
+				return nil, fmt.Errorf("expected a plist element, got '%s'", start.Name.Local)
+			}
+			inPlist = true
+			continue
+		}
+		value, err := parsePlistValue(d, start)
+		if err != nil {
+			return nil, err
+		}
+		doc, ok := value.(map[string]any)
+		if !ok {
+			return nil, fmt.Errorf("expected a top level dict, got '%s'", start.Name.Local)
+		}
+		return doc, nil
+	}
+}
+
+// parsePlistValue parses the value element started by start, up to its end element.
+func parsePlistValue(d *xml.Decoder, start xml.StartElement) (any, error) {
+	switch start.Name.Local {
+	case "dict":
+		dict := map[string]any{}
+		key := ""
+		for {
+			token, err := plistToken(d)
+			if err != nil {
+				return nil, err
+			}
+			switch token := token.(type) {
+			case xml.EndElement:
+				if key != "" {
+					return nil, fmt.Errorf("key '%s' has no value", key)
+				}
+				return dict, nil
+			case xml.StartElement:
+				if key == "" {
+					if token.Name.Local != "key" {
+						return nil, fmt.Errorf("expected a key in dict, got '%s'", token.Name.Local)
+					}
+					var text string
+					if err := d.DecodeElement(&text, &token); err != nil {
+						return nil, err
+					}
+					key = text
+					continue
+				}
+				value, err := parsePlistValue(d, token)
+				if err != nil {
+					return nil, fmt.Errorf("%s: %w", key, err)
+				}
+				dict[key], key = value, ""
+			}
+		}
+	case "array":
+		array := []any{}
+		for {
+			token, err := plistToken(d)
+			if err != nil {
+				return nil, err
+			}
+			switch token := token.(type) {
+			case xml.EndElement:
+				return array, nil
+			case xml.StartElement:
+				value, err := parsePlistValue(d, token)
+				if err != nil {
+					return nil, err
+				}
+				array = append(array, value)
+			}
+		}
+	case "true", "false":
+		if err := d.Skip(); err != nil {
+			return nil, err
+		}
+		return start.Name.Local == "true", nil
+	}
+	var text string
+	if err := d.DecodeElement(&text, &start); err != nil {
+		return nil, err
+	}
+	switch start.Name.Local {
+	case "string", "date":
+		return text, nil
+	case "integer", "real":
+		return json.Number(strings.TrimSpace(text)), nil
+	case "data":
+		//Data is base64 that may be wrapped over several lines.
+		return strings.Join(strings.Fields(text), ""), nil
+	}
+	return nil, fmt.Errorf("unsupported plist element '%s'", start.Name.Local)
+}
+
+// plistToken returns the next start or end element, skipping text and comments.
+func plistToken(d *xml.Decoder) (xml.Token, error) {
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch token.(type) {
+		case xml.StartElement, xml.EndElement:
+			return token, nil
+		}
+	}
+}
@@ -0,0 +1,94 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const testPlist = `<?xml version="1.0" encoding="UTF-8"?>
+<!DOCTYPE plist PUBLIC "-//Apple//DTD PLIST 1.0//EN" "http://www.apple.com/DTDs/PropertyList-1.0.dtd">
+<plist version="1.0">
+<dict>
+	<key>Name</key>
+	<string>plist</string>
+	<key>Debug</key>
+	<true/>
+	<key>Ratio</key>
+	<real>0.5</real>
+	<key>Key</key>
+	<data>
+	c2VjcmV0
+	</data>
+	<key>db</key>
+	<dict>
+		<key>hosts</key>
+		<array>
+			<string>a</string>
+			<string>b</string>
+		</array>
+		<key>port</key>
+		<integer>5432</integer>
+	</dict>
+</dict>
+</plist>
+`
+
+type plistConfig struct {
+	Name  string  `env:"NAME"`
+	Debug bool    `env:"DEBUG"`
+	Ratio float64 `env:"RATIO"`
+	Key   []byte  `env:"KEY" encoding:"base64"`
+	DB    struct {
+		Hosts []string `env:"HOSTS"`
+		Port  int      `env:"PORT"`
+	}
+}
+
+func TestPlistFile(t *testing.T) {
+	path := writeFile(t, "config.plist", testPlist)
+	want := &plistConfig{Name: "plist", Debug: true, Ratio: 0.5, Key: []byte("secret")}
+	want.DB.Hosts = []string{"a", "b"}
+	want.DB.Port = 5432
+	for _, opt := range []Option{WithPlistFile(path), WithConfigFiles(path)} {
+		got, err := New(makeLookup(nil), []string{"test"}, &plistConfig{}, opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("New() = %+v, want %+v", got, want)
+		}
+	}
+
+	for data, wantErr := range map[string]string{
+		`<dict></dict>`:                                  "expected a plist element, got 'dict'",
+		`<plist><array></array></plist>`:                 "expected a top level dict, got 'array'",
+		`<plist><dict><string>x</string></dict></plist>`: "expected a key in dict, got 'string'",
+		`<plist><dict><key>a</key></dict></plist>`:       "key 'a' has no value",
+		`<plist><dict><key>a</key><set/></dict></plist>`: "a: unsupported plist element 'set'",
+	} {
+		if _, err := parsePlist([]byte(data)); err == nil || err.Error() != wantErr {
+			t.Errorf("parsePlist(%s) error = %v, want %s", data, err, wantErr)
+		}
+	}
+}
+
+func TestDefaults(t *testing.T) {
+	fakeCommand(t, "defaults", `
+[ "$1 $3" = "export -" ] || exit 2
+[ "$2" = "com.example.app" ] || { echo "Domain $2 does not exist" >&2; exit 1; }
+cat <<'EOF'
+`+testPlist+`EOF
+`)
+	got, err := New(makeLookup(nil), []string{"test"}, &plistConfig{}, WithDefaults("com.example.app"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Name != "plist" || got.DB.Port != 5432 {
+		t.Errorf("New() = %+v, want the exported defaults", got)
+	}
+	_, err = New(makeLookup(nil), []string{"test"}, &plistConfig{}, WithDefaults("com.example.missing"))
+	if err == nil || !strings.HasSuffix(err.Error(), "defaults: Domain com.example.missing does not exist") {
+		t.Errorf("New() error = %v, want the defaults error", err)
+	}
+}
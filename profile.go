@@ -0,0 +1,90 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	profileFlag = "profile"
+	profileEnv  = "APP_ENV"
+)
+
+/*
+WithProfile adds the config file at path, and the file for the selected profile next to
+it, as a source of values below environment variables. The profile is taken from the
+`-profile` flag or the `APP_ENV` environment variable, and its file is named by
+inserting the profile before the extension, so with `APP_ENV=prod` these are merged,
+with values in the second overriding those in the first:
+
+	config.toml
+	config.prod.toml
+
+The formats are chosen by extension as for [WithConfigFile]. A missing config file is
+an error, but a missing profile file is not, since profiles often need no overrides.
+No profile file is loaded if no profile is selected.
+*/
+func WithProfile(path string) Option {
+	return func(l *loader) {
+		l.profile = true
+		s := &source{name: "file " + path}
+		s.key = func(l *loader) string {
+			profile, _ := l.selectedProfile()
+			return profile
+		}
+		s.resolve = func(l *loader) {
+			if profile, err := l.selectedProfile(); err == nil && profile != "" {
+				s.name = "file " + path + ", " + profilePath(path, profile)
+			}
+		}
+		s.open = func(l *loader) (map[string]string, error) {
+			values, err := l.openFile(path)
+			if err != nil {
+				return nil, err
+			}
+			profile, err := l.selectedProfile()
+			if err != nil || profile == "" {
+				return values, err
+			}
+			profilePath := profilePath(path, profile)
+			overrides, err := l.openFile(profilePath)
+			if errors.Is(err, fs.ErrNotExist) {
+				return values, nil
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", profilePath, err)
+			}
+			merged := make(map[string]string, len(values)+len(overrides))
+			for _, m := range []map[string]string{values, overrides} {
+				for k, v := range m {
+					merged[strings.ToLower(k)] = v
+				}
+			}
+			return merged, nil
+		}
+		l.sources = append(l.sources, s)
+	}
+}
+
+// profilePath returns the path of the profile file for the config file at path, e.g.
+// `config.prod.toml` for `config.toml`.
+func profilePath(path, profile string) string {
+	ext := filepath.Ext(path)
+	return strings.TrimSuffix(path, ext) + "." + profile + ext
+}
+
+// selectedProfile returns the profile given by the `-profile` flag or the `APP_ENV`
+// environment variable, which may not contain path separators.
+func (l *loader) selectedProfile() (string, error) {
+	profile, ok := l.scanArgs(profileFlag)
+	if !ok {
+		profile, _ = l.lookupenv(profileEnv)
+	}
+	if strings.ContainsAny(profile, `/\`) || profile == "." || profile == ".." {
+		return "", fmt.Errorf("invalid profile '%s'", profile)
+	}
+	return profile, nil
+}
@@ -0,0 +1,82 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestProfile(t *testing.T) {
+	dir := t.TempDir()
+	for name, content := range map[string]string{
+		"config.toml":        "name = \"base\"\nport = 80\n",
+		"config.prod.toml":   "port = 443\n",
+		"config.broken.toml": "port = \n",
+	} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	path := filepath.Join(dir, "config.toml")
+	type C struct {
+		Name string `env:"NAME"`
+		Port int    `env:"PORT"`
+	}
+	tests := []struct {
+		name    string
+		env     map[string]string
+		args    []string
+		path    string
+		want    *C
+		wantErr string
+	}{
+		{name: "no profile", args: []string{"test"}, want: &C{Name: "base", Port: 80}},
+		{name: "env", env: map[string]string{"APP_ENV": "prod"}, args: []string{"test"}, want: &C{Name: "base", Port: 443}},
+		{name: "flag", env: map[string]string{"APP_ENV": "dev"}, args: []string{"test", "-profile", "prod"}, want: &C{Name: "base", Port: 443}},
+		{name: "missing profile file", env: map[string]string{"APP_ENV": "dev"}, args: []string{"test"}, want: &C{Name: "base", Port: 80}},
+		{name: "invalid profile file", args: []string{"test", "-profile=broken"}, wantErr: "config.broken.toml: line 1"},
+		{name: "invalid profile", args: []string{"test", "-profile=../x"}, wantErr: "invalid profile '../x'"},
+		{name: "missing file", path: filepath.Join(dir, "missing.toml"), args: []string{"test"}, wantErr: "no such file"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := path
+			if tt.path != "" {
+				p = tt.path
+			}
+			got, err := New(makeLookup(tt.env), tt.args, &C{}, WithProfile(p))
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("New() error = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProfileField(t *testing.T) {
+	type C struct {
+		Env  string `env:"APP_ENV"`
+		Port int    `env:"PORT"`
+	}
+	base := writeFile(t, "config.json", `{"port": 80}`)
+	if err := os.WriteFile(filepath.Join(filepath.Dir(base), "config.prod.json"), []byte(`{"port": 443}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	got, err := New(makeLookup(map[string]string{"APP_ENV": "prod"}), []string{"test"}, &C{}, WithProfile(base))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (&C{Env: "prod", Port: 443}); !reflect.DeepEqual(got, want) {
+		t.Errorf("New() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,127 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+/*
+WithPropertiesFile adds the Java properties file at path as a source of values below
+environment variables. The dots in keys separate the names of nested structs, so
+`db.host=localhost` sets the `DB_HOST` field.
+
+Keys and values are separated by `=`, `:`, or whitespace, and lines starting with `#`
+or `!` are comments. A backslash at the end of a line continues the value on the next
+line, and the escapes `\t`, `\n`, `\r`, `\f`, and `\uXXXX` are supported. A missing
+file is an error.
+*/
+func WithPropertiesFile(path string) Option {
+	return withFile(path, decodeProperties)
+}
+
+// decodeProperties replaces the dots in property keys with the file key delimiter.
+func decodeProperties(l *loader, data []byte) (map[string]string, error) {
+	props, err := parseProperties(data)
+	if err != nil {
+		return nil, err
+	}
+	values := make(map[string]string, len(props))
+	for k, v := range props {
+		values[strings.ReplaceAll(k, ".", l.keyDelimiter)] = v
+	}
+	return values, nil
+}
+
+func parseProperties(data []byte) (map[string]string, error) {
+	props := map[string]string{}
+	lines := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+	for n := 0; n < len(lines); n++ {
+		start := n + 1
+		line := strings.TrimLeft(lines[n], " \t\f")
+		if line == "" || line[0] == '#' || line[0] == '!' {
+			continue
+		}
+		//A line ending in an odd number of backslashes continues on the next line.
+		for continues(line) && n+1 < len(lines) {
+			n++
+			line = line[:len(line)-1] + strings.TrimLeft(lines[n], " \t\f")
+		}
+		key, value, err := splitProperty(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", start, err)
+		}
+		props[key] = value
+	}
+	return props, nil
+}
+
+func continues(line string) bool {
+	n := len(line) - len(strings.TrimRight(line, `\`))
+	return n%2 == 1
+}
+
+// splitProperty splits a logical line into its unescaped key and value.
+func splitProperty(line string) (string, string, error) {
+	end := len(line)
+	for i := 0; i < len(line); i++ {
+		if line[i] == '\\' {
+			i++
+			continue
+		}
+		if strings.IndexByte("=: \t\f", line[i]) >= 0 {
+			end = i
+			break
+		}
+	}
+	rest := strings.TrimLeft(line[end:], " \t\f")
+	if rest != "" && (rest[0] == '=' || rest[0] == ':') {
+		rest = strings.TrimLeft(rest[1:], " \t\f")
+	}
+	key, err := unescapeProperty(line[:end])
+	if err != nil {
+		return "", "", err
+	}
+	value, err := unescapeProperty(rest)
+	if err != nil {
+		return "", "", err
+	}
+	return key, value, nil
+}
+
+func unescapeProperty(s string) (string, error) {
+	if !strings.Contains(s, `\`) {
+		return s, nil
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		switch s[i] {
+		case 't':
+			b.WriteByte('\t')
+		case 'n':
+			b.WriteByte('\n')
+		case 'r':
+			b.WriteByte('\r')
+		case 'f':
+			b.WriteByte('\f')
+		case 'u':
+			if i+5 > len(s) {
+				return "", fmt.Errorf("invalid unicode escape")
+			}
+			r, err := strconv.ParseUint(s[i+1:i+5], 16, 16)
+			if err != nil {
+				return "", fmt.Errorf("invalid unicode escape '\\u%s'", s[i+1:i+5])
+			}
+			b.WriteRune(rune(r))
+			i += 4
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,70 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseProperties(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name: "separators and comments",
+			input: `# comment
+! comment
+db.host=localhost
+db.port : 5432
+name app
+empty=
+url = http://example.com/?a=b
+`,
+			want: map[string]string{"db.host": "localhost", "db.port": "5432", "name": "app", "empty": "", "url": "http://example.com/?a=b"},
+		},
+		{
+			name:  "continuation lines",
+			input: "hosts = a,\\\n    b,\\\n    c\npath = C:\\\\\n",
+			want:  map[string]string{"hosts": "a,b,c", "path": `C:\`},
+		},
+		{
+			name:  "escapes",
+			input: `key\ with\=chars = tab\there caf\u00e9`,
+			want:  map[string]string{"key with=chars": "tab\there café"},
+		},
+		{name: "invalid unicode escape", input: `a = \u00g1`, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseProperties([]byte(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseProperties() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseProperties() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPropertiesFile(t *testing.T) {
+	type C struct {
+		Level string `env:"LEVEL"`
+		DB    struct {
+			Host string `env:"HOST"`
+			Port int    `env:"PORT"`
+		}
+	}
+	path := writeFile(t, "app.properties", "level=info\ndb.host=db\ndb.port=5432\n")
+	got, err := New(makeLookup(nil), []string{"test"}, &C{}, WithPropertiesFile(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &C{Level: "info"}
+	want.DB.Host, want.DB.Port = "db", 5432
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("New() = %+v, want %+v", got, want)
+	}
+}
@@ -0,0 +1,282 @@
+package config
+
+import (
+	"bufio"
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RedisSource describes settings stored in Redis, for [WithRedis].
+type RedisSource struct {
+	//Addr is the host and port of the server. Defaults to `localhost:6379`.
+	Addr string
+	//Username and Password authenticate with the server, if Password is set.
+	Username string
+	Password string
+	//DB is the number of the database to select.
+	DB int
+	//TLS enables TLS with the given configuration, if set.
+	TLS *tls.Config
+	//Hash is the key of a hash whose fields are the settings.
+	Hash string
+	//Prefix selects the string keys starting with it as the settings, if Hash is empty.
+	Prefix string
+	//Channel is the pub/sub channel announcing changes to the settings, for Watch.
+	Channel string
+	//Timeout limits connecting and each command. Defaults to 10 seconds.
+	Timeout time.Duration
+}
+
+/*
+WithRedis adds settings stored in Redis as a source of values below environment
+variables. They are either the fields of a hash, or the string keys under a prefix,
+with the prefix removed. Keys are matched like those of a flattened config file, so the
+hash field `db_host` sets the `DB_HOST` field. A missing hash or prefix provides no
+values.
+
+The settings are read once. If Channel is set, use [Watch] or [RedisSource.Watch] to
+learn when they change and call [New] again.
+*/
+func WithRedis(src RedisSource) Option {
+	return func(l *loader) {
+		name := "redis " + src.Hash
+		if src.Hash == "" {
+			name = "redis " + src.Prefix + "*"
+		}
+		l.sources = append(l.sources, &source{name: name, open: func(l *loader) (map[string]string, error) {
+			c, err := src.dial()
+			if err != nil {
+				return nil, err
+			}
+			defer c.Close()
+			if src.Hash != "" {
+				return c.hgetall(src.Hash)
+			}
+			return c.scanPrefix(src.Prefix)
+		}})
+		if src.Channel != "" {
+			l.watchers = append(l.watchers, src)
+		}
+	}
+}
+
+/*
+Watch listens on the pub/sub channel given by Channel until ctx is done, and calls
+onChange for each message published to it. It returns the error that ended the
+subscription, which is ctx.Err() if ctx was done. Publishing to the channel is up to the
+writer of the settings, or keyspace notifications can be used with a channel such as
+`__keyspace@0__:app`.
+*/
+func (src RedisSource) Watch(ctx context.Context, onChange func()) error {
+	if src.Channel == "" {
+		return errors.New("no channel to watch")
+	}
+	c, err := src.dial()
+	if err != nil {
+		return err
+	}
+	defer c.Close()
+	stop := context.AfterFunc(ctx, func() { c.conn.Close() })
+	defer stop()
+	if _, err := c.do("SUBSCRIBE", src.Channel); err != nil {
+		return err
+	}
+	//Replies to SUBSCRIBE have no timeout, as messages may be far apart.
+	c.conn.SetDeadline(time.Time{})
+	for {
+		reply, err := c.read()
+		if err != nil {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return err
+		}
+		if msg, ok := reply.([]any); ok && len(msg) == 3 && msg[0] == "message" {
+			onChange()
+		}
+	}
+}
+
+// redisConn is a connection speaking the Redis serialization protocol.
+type redisConn struct {
+	conn    net.Conn
+	r       *bufio.Reader
+	timeout time.Duration
+}
+
+func (src RedisSource) dial() (*redisConn, error) {
+	addr := src.Addr
+	if addr == "" {
+		addr = "localhost:6379"
+	}
+	timeout := src.Timeout
+	if timeout == 0 {
+		timeout = 10 * time.Second
+	}
+	dialer := &net.Dialer{Timeout: timeout}
+	var conn net.Conn
+	var err error
+	if src.TLS != nil {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, src.TLS)
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return nil, err
+	}
+	c := &redisConn{conn: conn, r: bufio.NewReader(conn), timeout: timeout}
+	if src.Password != "" {
+		args := []string{"AUTH", src.Password}
+		if src.Username != "" {
+			args = []string{"AUTH", src.Username, src.Password}
+		}
+		if _, err := c.do(args...); err != nil {
+			c.Close()
+			return nil, fmt.Errorf("failed to authenticate: %w", err)
+		}
+	}
+	if src.DB != 0 {
+		if _, err := c.do("SELECT", strconv.Itoa(src.DB)); err != nil {
+			c.Close()
+			return nil, err
+		}
+	}
+	return c, nil
+}
+
+func (c *redisConn) Close() error {
+	return c.conn.Close()
+}
+
+func (c *redisConn) hgetall(key string) (map[string]string, error) {
+	reply, err := c.do("HGETALL", key)
+	if err != nil {
+		return nil, err
+	}
+	fields, _ := reply.([]any)
+	values := make(map[string]string, len(fields)/2)
+	for i := 0; i+1 < len(fields); i += 2 {
+		k, _ := fields[i].(string)
+		v, _ := fields[i+1].(string)
+		values[k] = v
+	}
+	return values, nil
+}
+
+// scanPrefix returns the string keys starting with prefix, with the prefix removed.
+func (c *redisConn) scanPrefix(prefix string) (map[string]string, error) {
+	var keys []string
+	cursor := "0"
+	for {
+		reply, err := c.do("SCAN", cursor, "MATCH", escapeGlob(prefix)+"*", "COUNT", "1000")
+		if err != nil {
+			return nil, err
+		}
+		page, ok := reply.([]any)
+		if !ok || len(page) != 2 {
+			return nil, fmt.Errorf("unexpected SCAN reply")
+		}
+		cursor, _ = page[0].(string)
+		batch, _ := page[1].([]any)
+		for _, k := range batch {
+			if k, ok := k.(string); ok {
+				keys = append(keys, k)
+			}
+		}
+		if cursor == "0" {
+			break
+		}
+	}
+	values := make(map[string]string, len(keys))
+	if len(keys) == 0 {
+		return values, nil
+	}
+	reply, err := c.do(append([]string{"MGET"}, keys...)...)
+	if err != nil {
+		return nil, err
+	}
+	results, _ := reply.([]any)
+	for i, v := range results {
+		//Keys that are not strings, or were deleted since the scan, are nil.
+		if v, ok := v.(string); ok && i < len(keys) {
+			values[strings.TrimPrefix(keys[i], prefix)] = v
+		}
+	}
+	return values, nil
+}
+
+func escapeGlob(s string) string {
+	var b strings.Builder
+	for _, c := range s {
+		if strings.ContainsRune(`*?[]\`, c) {
+			b.WriteByte('\\')
+		}
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// do sends a command and returns its reply.
+func (c *redisConn) do(args ...string) (any, error) {
+	c.conn.SetDeadline(time.Now().Add(c.timeout))
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%d\r\n", len(args))
+	for _, arg := range args {
+		fmt.Fprintf(&b, "$%d\r\n%s\r\n", len(arg), arg)
+	}
+	if _, err := io.WriteString(c.conn, b.String()); err != nil {
+		return nil, err
+	}
+	return c.read()
+}
+
+// read reads a reply. Bulk and simple strings are returned as strings, integers as
+// int64, arrays as []any, and nulls as nil. Error replies are returned as errors.
+func (c *redisConn) read() (any, error) {
+	line, err := c.r.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	line = strings.TrimSuffix(line, "\r\n")
+	if line == "" {
+		return nil, fmt.Errorf("invalid reply")
+	}
+	switch line[0] {
+	case '+':
+		return line[1:], nil
+	case '-':
+		return nil, errors.New(line[1:])
+	case ':':
+		return strconv.ParseInt(line[1:], 10, 64)
+	case '$':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		buf := make([]byte, n+2)
+		if _, err := io.ReadFull(c.r, buf); err != nil {
+			return nil, err
+		}
+		return string(buf[:n]), nil
+	case '*':
+		n, err := strconv.Atoi(line[1:])
+		if err != nil || n < 0 {
+			return nil, err
+		}
+		array := make([]any, n)
+		for i := range array {
+			if array[i], err = c.read(); err != nil {
+				return nil, err
+			}
+		}
+		return array, nil
+	}
+	return nil, fmt.Errorf("invalid reply '%s'", line)
+}
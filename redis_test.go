@@ -0,0 +1,158 @@
+package config
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+//fakeRedis serves the commands used by RedisSource from fixed data.
+func fakeRedis(t *testing.T, hashes map[string]map[string]string, strs map[string]string) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go serveRedis(conn, hashes, strs)
+		}
+	}()
+	return ln.Addr().String()
+}
+
+func serveRedis(conn net.Conn, hashes map[string]map[string]string, strs map[string]string) {
+	defer conn.Close()
+	c := &redisConn{conn: conn, r: bufio.NewReader(conn)}
+	bulk := func(s string) string { return fmt.Sprintf("$%d\r\n%s\r\n", len(s), s) }
+	authed := false
+	for {
+		reply, err := c.read()
+		if err != nil {
+			return
+		}
+		var args []string
+		for _, a := range reply.([]any) {
+			args = append(args, a.(string))
+		}
+		var out strings.Builder
+		switch {
+		case args[0] == "AUTH":
+			if args[len(args)-1] != "secret" {
+				out.WriteString("-WRONGPASS invalid username-password pair\r\n")
+				break
+			}
+			authed = true
+			out.WriteString("+OK\r\n")
+		case !authed:
+			out.WriteString("-NOAUTH Authentication required.\r\n")
+		case args[0] == "SELECT":
+			out.WriteString("+OK\r\n")
+		case args[0] == "HGETALL":
+			h := hashes[args[1]]
+			fmt.Fprintf(&out, "*%d\r\n", len(h)*2)
+			for k, v := range h {
+				out.WriteString(bulk(k) + bulk(v))
+			}
+		case args[0] == "SCAN":
+			prefix := strings.TrimSuffix(args[3], "*")
+			var keys []string
+			for k := range strs {
+				if strings.HasPrefix(k, prefix) {
+					keys = append(keys, k)
+				}
+			}
+			fmt.Fprintf(&out, "*2\r\n%s*%d\r\n", bulk("0"), len(keys))
+			for _, k := range keys {
+				out.WriteString(bulk(k))
+			}
+		case args[0] == "MGET":
+			fmt.Fprintf(&out, "*%d\r\n", len(args)-1)
+			for _, k := range args[1:] {
+				if v, ok := strs[k]; ok {
+					out.WriteString(bulk(v))
+				} else {
+					out.WriteString("$-1\r\n")
+				}
+			}
+		case args[0] == "SUBSCRIBE":
+			out.WriteString("*3\r\n" + bulk("subscribe") + bulk(args[1]) + ":1\r\n")
+			for i := 0; i < 2; i++ {
+				out.WriteString("*3\r\n" + bulk("message") + bulk(args[1]) + bulk("changed"))
+			}
+		default:
+			out.WriteString("-ERR unknown command\r\n")
+		}
+		conn.Write([]byte(out.String()))
+	}
+}
+
+func TestRedis(t *testing.T) {
+	addr := fakeRedis(t,
+		map[string]map[string]string{"app": {"name": "hash", "db_port": "5432"}},
+		map[string]string{"app:name": "prefix", "app:db_port": "6543", "other": "x"},
+	)
+	type C struct {
+		Name string `env:"NAME" default:"default"`
+		DB   struct {
+			Port int `env:"PORT"`
+		}
+	}
+	withPort := func(name string, port int) *C {
+		c := &C{Name: name}
+		c.DB.Port = port
+		return c
+	}
+	tests := []struct {
+		name    string
+		src     RedisSource
+		want    *C
+		wantErr bool
+	}{
+		{name: "hash", src: RedisSource{Addr: addr, Password: "secret", DB: 2, Hash: "app"}, want: withPort("hash", 5432)},
+		{name: "prefix", src: RedisSource{Addr: addr, Username: "app", Password: "secret", Prefix: "app:"}, want: withPort("prefix", 6543)},
+		{name: "missing hash", src: RedisSource{Addr: addr, Password: "secret", Hash: "none"}, want: withPort("default", 0)},
+		{name: "wrong password", src: RedisSource{Addr: addr, Password: "wrong", Hash: "app"}, wantErr: true},
+		{name: "no auth", src: RedisSource{Addr: addr, Hash: "app"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(makeLookup(nil), []string{"test"}, &C{}, WithRedis(tt.src))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRedisWatch(t *testing.T) {
+	addr := fakeRedis(t, nil, nil)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	changes := 0
+	err := RedisSource{Addr: addr, Password: "secret", Channel: "config"}.Watch(ctx, func() {
+		changes++
+		if changes == 2 {
+			cancel()
+		}
+	})
+	if err != context.Canceled {
+		t.Errorf("Watch() error = %v, want %v", err, context.Canceled)
+	}
+	if changes != 2 {
+		t.Errorf("changes = %d, want 2", changes)
+	}
+}
@@ -0,0 +1,94 @@
+package config
+
+import (
+	"flag"
+	"fmt"
+	"reflect"
+)
+
+// Registration is a struct whose flags were added to a flag set by [Register], to be
+// populated by Resolve once the flag set is parsed.
+type Registration struct {
+	l       *loader
+	t       *target
+	flagset *flag.FlagSet
+}
+
+/*
+Register adds the flags of the struct c to flagset, which the application owns, so they
+can be parsed alongside flags defined by hand. Once flagset is parsed, Resolve populates
+c from the flags and the other layers of values, as [New] does:
+
+	fs := flag.NewFlagSet("app", flag.ExitOnError)
+	debug := fs.Bool("debug", false, "enable debugging")
+	reg, err := config.Register(fs, nil, &c)
+	if err != nil {
+		log.Fatal(err)
+	}
+	fs.Parse(os.Args[1:])
+	if err := reg.Resolve(); err != nil {
+		log.Fatal(err)
+	}
+
+`lookupenv` and `opts` are as for New. A flag name already defined on flagset is an
+error. The usage and output of flagset are left alone, and since it is parsed by the
+application, options changing how the command line is parsed have no effect.
+
+Config files and other sources are read by Resolve, so the `-config` flag of
+[WithConfigFile] can be used. The kinds of interface fields and the elements of slices
+of structs set by indexed variables are found by Register, so they can only be given
+by environment variables or defaults.
+*/
+func Register[T any](flagset *flag.FlagSet, lookupenv func(string) (string, bool), c *T, opts ...Option) (*Registration, error) {
+	l := newLoader(lookupenv, opts)
+	if c == nil {
+		return nil, fmt.Errorf("config.Register: expected a pointer to a struct, got nil")
+	}
+	v := reflect.ValueOf(c).Elem()
+	if kind := v.Kind(); kind != reflect.Struct {
+		return nil, fmt.Errorf("config.Register: expected struct pointer, got %s pointer", kind)
+	}
+	t, err := l.collectTarget(v, flagset.Name(), true)
+	if err != nil {
+		return nil, err
+	}
+	if err := registerFlags(flagset, t.fields); err != nil {
+		return nil, err
+	}
+	return &Registration{l: l, t: t, flagset: flagset}, nil
+}
+
+// Resolve populates the registered struct from its flags, which must have been parsed,
+// the positional arguments of the flag set, and the other layers of values.
+func (r *Registration) Resolve() error {
+	if !r.flagset.Parsed() {
+		return fmt.Errorf("config.Resolve: flag set %s has not been parsed", r.flagset.Name())
+	}
+	l := r.l
+	l.args = parsedArgs(r.flagset)
+	if err := l.loadDotenv(); err != nil {
+		return err
+	}
+	if err := l.openSources(); err != nil {
+		return err
+	}
+	remaining, passthrough := r.t.positionals.split(nil, r.flagset.Args())
+	return l.resolve(r.t, r.flagset, remaining, passthrough)
+}
+
+// parsedArgs returns the flags set on the parsed flagset as arguments, such as
+// `-config=app.toml`, for options that scan the command line. Each value of a repeated
+// flag of a field is included.
+func parsedArgs(flagset *flag.FlagSet) []string {
+	var args []string
+	flagset.Visit(func(f *flag.Flag) {
+		values := []string{f.Value.String()}
+		if v, ok := f.Value.(*flagValue); ok && len(v.occurrences) > 0 {
+			values = v.occurrences
+		}
+		for _, value := range values {
+			args = append(args, "-"+f.Name+"="+value)
+		}
+	})
+	return args
+}
@@ -0,0 +1,95 @@
+package config
+
+import (
+	"flag"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestRegister(t *testing.T) {
+	type C struct {
+		Host  string   `env:"HOST" default:"localhost" usage:"Host to connect to"`
+		Port  int      `env:"PORT" short:"p"`
+		Cache bool     `env:"CACHE" default:"true"`
+		Tags  []string `flag:"tag"`
+		Name  string   `env:"NAME" required:"true"`
+		Files []string `arg:"rest"`
+	}
+	fs := flag.NewFlagSet("app", flag.ContinueOnError)
+	debug := fs.Bool("debug", false, "enable debugging")
+	var c C
+	reg, err := Register(fs, makeLookup(map[string]string{"PORT": "80", "NAME": "app"}), &c)
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if f := fs.Lookup("host"); f == nil || f.Usage != "Host to connect to" {
+		t.Errorf("Register() defined %+v, want the host flag", f)
+	}
+	if err := reg.Resolve(); err == nil {
+		t.Error("Resolve() expected an error before the flag set is parsed")
+	}
+	if err := fs.Parse([]string{"-debug", "-p=8080", "-no-cache", "-tag=a", "-tag=b", "x", "y"}); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if err := reg.Resolve(); err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	want := C{Host: "localhost", Port: 8080, Tags: []string{"a", "b"}, Name: "app", Files: []string{"x", "y"}}
+	if !reflect.DeepEqual(c, want) || !*debug {
+		t.Errorf("Resolve() = %+v, debug %v, want %+v, debug true", c, *debug, want)
+	}
+}
+
+func TestRegisterErrors(t *testing.T) {
+	type C struct {
+		Debug bool   `env:"DEBUG"`
+		Name  string `env:"NAME" required:"true"`
+	}
+	fs := flag.NewFlagSet("app", flag.ContinueOnError)
+	fs.Bool("debug", false, "")
+	_, err := Register(fs, makeLookup(nil), &C{})
+	if err == nil || err.Error() != "field Debug has the flag name 'debug', which is already defined" {
+		t.Errorf("Register() error = %v, want an error for the defined flag", err)
+	}
+
+	//Aliases give way to flags defined by hand.
+	fs = flag.NewFlagSet("app", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	fs.String("NAME", "", "")
+	type D struct {
+		Name string `env:"NAME" required:"true"`
+	}
+	reg, err := Register(fs, makeLookup(nil), &D{})
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if err := fs.Parse(nil); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if err := reg.Resolve(); err == nil || err.Error() != "missing required setting Name (env NAME, flag -name)" {
+		t.Errorf("Resolve() error = %v, want the missing setting", err)
+	}
+}
+
+func TestRegisterConfigFile(t *testing.T) {
+	type C struct {
+		Host string `env:"HOST"`
+	}
+	path := writeFile(t, "app.json", `{"host": "example.com"}`)
+	fs := flag.NewFlagSet("app", flag.ContinueOnError)
+	var c C
+	reg, err := Register(fs, makeLookup(nil), &c, WithConfigFile(""))
+	if err != nil {
+		t.Fatalf("Register() error = %v", err)
+	}
+	if err := fs.Parse([]string{"-config", path}); err != nil {
+		t.Fatalf("Parse() error = %v", err)
+	}
+	if err := reg.Resolve(); err != nil {
+		t.Fatalf("Resolve() error = %v", err)
+	}
+	if c.Host != "example.com" {
+		t.Errorf("Resolve() Host = %s, want the value from the config file", c.Host)
+	}
+}
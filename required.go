@@ -0,0 +1,44 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// isRequired reports whether the field has a `required:"true"` tag. Fields set by an
+// indexed positional argument are required unless they have a default or a `required`
+// tag of their own.
+func (f field) isRequired() bool {
+	required, ok := f.tag.Lookup("required")
+	if !ok && f.arg != "" && f.arg != argRest && f.arg != argPassthrough {
+		_, hasDefault := f.tag.Lookup("default")
+		return !hasDefault
+	}
+	return required == "true"
+}
+
+// missingError returns the error for the required fields that were given no value,
+// naming the env var and flags each can be set by.
+func (l *loader) missingError(missing []field) error {
+	settings := make([]string, len(missing))
+	for i, f := range missing {
+		var names []string
+		if f.arg != "" {
+			names = append(names, "argument "+f.argName())
+		}
+		if f.env != "" {
+			names = append(names, "env "+f.env)
+		}
+		if f.flag != "" {
+			names = append(names, "flag "+l.longFlag()+f.flag)
+		}
+		settings[i] = f.name
+		if len(names) > 0 {
+			settings[i] += " (" + strings.Join(names, ", ") + ")"
+		}
+	}
+	if len(missing) == 1 {
+		return fmt.Errorf("missing required setting %s", settings[0])
+	}
+	return fmt.Errorf("missing required settings %s", strings.Join(settings, ", "))
+}
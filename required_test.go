@@ -0,0 +1,84 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNewRequired(t *testing.T) {
+	type Upstream struct {
+		Host string `env:"HOST"`
+	}
+	type C struct {
+		Host      string     `env:"HOST" required:"true"`
+		Token     string     `flag:"token" required:"true"`
+		Port      int        `env:"PORT" default:"80" required:"true"`
+		Debug     *bool      `env:"DEBUG" required:"true"`
+		Upstreams []Upstream `env:"UPSTREAMS" required:"true"`
+		Optional  string     `env:"OPTIONAL" required:"false"`
+	}
+	tests := []struct {
+		name    string
+		env     map[string]string
+		args    []string
+		want    *C
+		wantErr string
+	}{
+		{
+			name: "AllSet",
+			env:  map[string]string{"HOST": "example.com", "DEBUG": "false", "UPSTREAMS_0_HOST": "a"},
+			args: []string{"app", "-token=t"},
+			want: &C{Host: "example.com", Token: "t", Port: 80, Debug: ptr(false), Upstreams: []Upstream{{Host: "a"}}},
+		},
+		{
+			name: "ZeroValues",
+			env:  map[string]string{"HOST": "", "DEBUG": "false", "UPSTREAMS": "[]"},
+			args: []string{"app", "-token="},
+			want: &C{Port: 80, Debug: ptr(false), Upstreams: []Upstream{}},
+		},
+		{
+			name:    "Missing",
+			env:     map[string]string{"DEBUG": "true", "UPSTREAMS": "[]"},
+			args:    []string{"app"},
+			wantErr: "missing required settings Host (env HOST, flag -host), Token (flag -token)",
+		},
+		{
+			name:    "MissingOne",
+			env:     map[string]string{"HOST": "a", "DEBUG": "true"},
+			args:    []string{"app", "-token=t"},
+			wantErr: "missing required setting Upstreams (env UPSTREAMS, flag -upstreams)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(makeLookup(tt.env), tt.args, &C{})
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("New() error = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestWriteUsageRequired(t *testing.T) {
+	type C struct {
+		Host string `env:"HOST" required:"true" usage:"Host to connect to"`
+	}
+	var b strings.Builder
+	_, err := New(makeLookup(nil), []string{"app", "-h"}, &C{}, WithOutput(&b))
+	if err != ErrHelp {
+		t.Fatalf("New() error = %v, want ErrHelp", err)
+	}
+	if !strings.Contains(b.String(), "Host to connect to (required, env HOST)") {
+		t.Errorf("New() printed %q, want the field marked as required", b.String())
+	}
+}
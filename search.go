@@ -0,0 +1,59 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+/*
+WithConfigSearch adds the config file of the application app, found in the standard
+search paths, as a source of values below environment variables. The file is named
+`config` with any of the extensions supported by [WithConfigFile], and is searched for
+in this order:
+
+- The working directory
+- `$HOME/.<app>/`
+- `/etc/<app>/`
+
+The first file found is used, and it is not an error if there is none. If used is not
+nil, it is set to the path of the file, or to the empty string if none was found, which
+lets the program report where its settings came from.
+*/
+func WithConfigSearch(app string, used *string) Option {
+	return func(l *loader) {
+		s := &source{name: "config search"}
+		s.key = func(l *loader) string {
+			return strings.Join(l.searchDirs(app), string(filepath.ListSeparator))
+		}
+		s.resolve = func(l *loader) {
+			path, ok := findFile(l.searchDirs(app), "config")
+			if used != nil {
+				*used = path
+			}
+			if ok {
+				s.name = "file " + path
+			}
+		}
+		s.open = func(l *loader) (map[string]string, error) {
+			path, ok := findFile(l.searchDirs(app), "config")
+			if !ok {
+				return nil, nil
+			}
+			return l.openFile(path)
+		}
+		l.sources = append(l.sources, s)
+	}
+}
+
+// searchDirs returns the directories searched for the config file of app, in order.
+func (l *loader) searchDirs(app string) []string {
+	var dirs []string
+	if wd, err := os.Getwd(); err == nil {
+		dirs = append(dirs, wd)
+	}
+	if home, ok := l.lookupenv("HOME"); ok && home != "" {
+		dirs = append(dirs, filepath.Join(home, "."+app))
+	}
+	return append(dirs, filepath.Join("/etc", app))
+}
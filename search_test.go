@@ -0,0 +1,82 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestConfigSearch(t *testing.T) {
+	type C struct {
+		Name string `env:"NAME" default:"default"`
+	}
+	root := t.TempDir()
+	for path, content := range map[string]string{
+		"wd/config.toml":           `name = "wd"`,
+		"home/.app/config.json":    `{"name": "home"}`,
+		"other/.app/notconfig.ini": "name = other",
+	} {
+		path = filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.MkdirAll(filepath.Join(root, "empty"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name     string
+		wd       string
+		home     string
+		want     *C
+		wantUsed string
+	}{
+		{name: "working directory", wd: "wd", home: "home", want: &C{Name: "wd"}, wantUsed: filepath.Join(root, "wd/config.toml")},
+		{name: "home", wd: "empty", home: "home", want: &C{Name: "home"}, wantUsed: filepath.Join(root, "home/.app/config.json")},
+		{name: "none", wd: "empty", home: "other", want: &C{Name: "default"}},
+	}
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := os.Chdir(filepath.Join(root, tt.wd)); err != nil {
+				t.Fatal(err)
+			}
+			var used string
+			got, err := New(makeLookup(map[string]string{"HOME": filepath.Join(root, tt.home)}), []string{"test"}, &C{}, WithConfigSearch("app", &used))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %+v, want %+v", got, tt.want)
+			}
+			if used != tt.wantUsed {
+				t.Errorf("used = %q, want %q", used, tt.wantUsed)
+			}
+		})
+	}
+	t.Run("cached", func(t *testing.T) {
+		if err := os.Chdir(filepath.Join(root, "wd")); err != nil {
+			t.Fatal(err)
+		}
+		var used string
+		cache := WithCache(time.Minute, time.Minute, WithConfigSearch("app", &used))
+		for i := 0; i < 2; i++ {
+			used = ""
+			if _, err := New(makeLookup(nil), []string{"test"}, &C{}, cache); err != nil {
+				t.Fatal(err)
+			}
+			if want := filepath.Join(root, "wd/config.toml"); used != want {
+				t.Errorf("used on call %d = %q, want %q", i, used, want)
+			}
+		}
+	})
+}
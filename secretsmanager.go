@@ -0,0 +1,142 @@
+package config
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SecretsManagerSource describes how to read secrets from AWS Secrets Manager, for
+// [WithSecretsManager].
+type SecretsManagerSource struct {
+	AWS AWSConfig
+	//SecretID is the name or ARN of a JSON secret whose keys are mapped onto fields, if
+	//set.
+	SecretID string
+	//MaxAttempts limits the attempts to read each secret when the request fails with a
+	//network error, throttling, or a server error. Defaults to 3.
+	MaxAttempts int
+}
+
+/*
+WithSecretsManager reads secrets from AWS Secrets Manager. If the source has a SecretID,
+that secret must hold a JSON object, which is added as a source of values below
+environment variables and mapped onto fields like [WithJSONFile]. In addition, fields
+with an `awssecret` tag are resolved from the secret it names, optionally followed by
+`#` and the key to read from a JSON secret:
+
+	type C struct {
+		Token    string `awssecret:"prod/app/token"`
+		Password string `awssecret:"prod/db#password"`
+	}
+
+A missing secret, or a missing key in a tagged secret, is an error. Failed requests
+are retried with exponential backoff.
+*/
+func WithSecretsManager(src SecretsManagerSource) Option {
+	return func(l *loader) {
+		sm := &secretsManager{src: src, secrets: map[string]string{}}
+		if src.SecretID != "" {
+			l.sources = append(l.sources, &source{name: "secretsmanager " + src.SecretID, open: func(l *loader) (map[string]string, error) {
+				secret, err := sm.get(l, src.SecretID)
+				if err != nil {
+					return nil, err
+				}
+				values, err := document(parseJSON)(l, []byte(secret))
+				if err != nil {
+					return nil, fmt.Errorf("secret '%s' is not a JSON object: %w", src.SecretID, err)
+				}
+				return values, nil
+			}})
+		}
+		l.resolvers = append(l.resolvers, &resolver{tag: "awssecret", name: "secretsmanager", resolve: sm.resolve})
+	}
+}
+
+type secretsManager struct {
+	src     SecretsManagerSource
+	mu      sync.Mutex
+	secrets map[string]string
+}
+
+func (sm *secretsManager) resolve(l *loader, ref string) (string, error) {
+	id, key, hasKey := strings.Cut(ref, "#")
+	secret, err := sm.get(l, id)
+	if err != nil || !hasKey {
+		return secret, err
+	}
+	var obj map[string]any
+	d := json.NewDecoder(strings.NewReader(secret))
+	d.UseNumber()
+	if err := d.Decode(&obj); err != nil {
+		return "", fmt.Errorf("secret '%s' is not a JSON object", id)
+	}
+	value, ok := obj[key]
+	if !ok {
+		return "", fmt.Errorf("secret '%s' has no key '%s'", id, key)
+	}
+	if s, ok := scalarString(value); ok {
+		return s, nil
+	}
+	raw, err := json.Marshal(value)
+	return string(raw), err
+}
+
+// get returns the secret string of the secret id, reading it once.
+func (sm *secretsManager) get(l *loader, id string) (string, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if secret, ok := sm.secrets[id]; ok {
+		return secret, nil
+	}
+	aws, err := sm.src.AWS.resolve(l)
+	if err != nil {
+		return "", err
+	}
+	attempts := sm.src.MaxAttempts
+	if attempts <= 0 {
+		attempts = 3
+	}
+	var resp struct {
+		SecretString *string
+	}
+	backoff := 100 * time.Millisecond
+	for attempt := 1; ; attempt++ {
+		err = aws.call("secretsmanager", "secretsmanager.GetSecretValue", map[string]string{"SecretId": id}, &resp)
+		if err == nil || attempt == attempts || !retryable(err) {
+			break
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+	var awsErr *awsError
+	if errors.As(err, &awsErr) && awsErr.name == "ResourceNotFoundException" {
+		return "", fmt.Errorf("secret '%s' not found", id)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read secret '%s': %w", id, err)
+	}
+	if resp.SecretString == nil {
+		return "", fmt.Errorf("secret '%s' has no string value", id)
+	}
+	sm.secrets[id] = *resp.SecretString
+	return *resp.SecretString, nil
+}
+
+// retryable reports whether a failed AWS request may succeed if it is sent again.
+func retryable(err error) bool {
+	var awsErr *awsError
+	if errors.As(err, &awsErr) {
+		return awsErr.code >= 500 || strings.Contains(awsErr.name, "Throttling") || awsErr.name == "TooManyRequestsException"
+	}
+	var statusErr *statusError
+	if errors.As(err, &statusErr) {
+		return statusErr.code >= 500 || statusErr.code == http.StatusTooManyRequests
+	}
+	//Anything else is a network error.
+	return true
+}
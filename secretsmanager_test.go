@@ -0,0 +1,95 @@
+package config
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSecretsManager(t *testing.T) {
+	requests := map[string]int{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct{ SecretId string }
+		json.NewDecoder(r.Body).Decode(&req)
+		requests[req.SecretId]++
+		switch req.SecretId {
+		case "app":
+			w.Write([]byte(`{"SecretString": "{\"name\": \"app\", \"db\": {\"port\": 5432}}"}`))
+		case "token":
+			w.Write([]byte(`{"SecretString": "t0ken"}`))
+		case "flaky":
+			if requests["flaky"] < 3 {
+				w.WriteHeader(http.StatusBadRequest)
+				w.Write([]byte(`{"__type": "ThrottlingException", "message": "Rate exceeded"}`))
+				return
+			}
+			w.Write([]byte(`{"SecretString": "{\"password\": \"pw\"}"}`))
+		default:
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type": "ResourceNotFoundException", "message": "Secrets Manager can't find the specified secret."}`))
+		}
+	}))
+	defer srv.Close()
+	env := map[string]string{"AWS_REGION": "us-east-1", "AWS_ACCESS_KEY_ID": "AKID", "AWS_SECRET_ACCESS_KEY": "secret"}
+	aws := AWSConfig{Endpoint: srv.URL}
+
+	type C struct {
+		Name     string `env:"NAME"`
+		Token    string `awssecret:"token"`
+		Password string `awssecret:"flaky#password"`
+		DB       struct {
+			Port int `env:"PORT"`
+		}
+	}
+	got, err := New(makeLookup(env), []string{"test"}, &C{}, WithSecretsManager(SecretsManagerSource{AWS: aws, SecretID: "app"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &C{Name: "app", Token: "t0ken", Password: "pw"}
+	want.DB.Port = 5432
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("New() = %+v, want %+v", got, want)
+	}
+	if requests["flaky"] != 3 {
+		t.Errorf("flaky secret requested %d times, want 3", requests["flaky"])
+	}
+
+	type MissingKey struct {
+		Password string `awssecret:"app#password"`
+	}
+	type MissingSecret struct {
+		Password string `awssecret:"missing"`
+	}
+	tests := []struct {
+		name string
+		c    any
+		src  SecretsManagerSource
+		want string
+	}{
+		{name: "missing key", c: &MissingKey{}, src: SecretsManagerSource{AWS: aws}, want: "secret 'app' has no key 'password'"},
+		{name: "missing secret", c: &MissingSecret{}, src: SecretsManagerSource{AWS: aws}, want: "secret 'missing' not found"},
+		{name: "missing source secret", c: &MissingKey{}, src: SecretsManagerSource{AWS: aws, SecretID: "missing"}, want: "secret 'missing' not found"},
+		{name: "not an object", c: &MissingKey{}, src: SecretsManagerSource{AWS: aws, SecretID: "token"}, want: "secret 'token' is not a JSON object"},
+		{name: "retries exhausted", c: &C{}, src: SecretsManagerSource{AWS: aws, MaxAttempts: 1}, want: "ThrottlingException"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			requests["flaky"] = 0
+			var err error
+			switch c := tt.c.(type) {
+			case *MissingKey:
+				_, err = New(makeLookup(env), []string{"test"}, c, WithSecretsManager(tt.src))
+			case *MissingSecret:
+				_, err = New(makeLookup(env), []string{"test"}, c, WithSecretsManager(tt.src))
+			case *C:
+				_, err = New(makeLookup(env), []string{"test"}, c, WithSecretsManager(tt.src))
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("New() error = %v, want %s", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// SemVer is a semantic version as described by https://semver.org, e.g. `1.4.0` or
+// `2.0.0-rc.1+build.5`. A leading `v` is accepted when parsing.
+type SemVer struct {
+	Major, Minor, Patch uint64
+	Prerelease          string // Dot separated prerelease identifiers, e.g. rc.1
+	Build               string // Dot separated build metadata, e.g. build.5
+}
+
+// ParseSemVer parses a semantic version.
+func ParseSemVer(s string) (SemVer, error) {
+	var v SemVer
+	rest, build, hasBuild := strings.Cut(strings.TrimPrefix(s, "v"), "+")
+	rest, prerelease, hasPrerelease := strings.Cut(rest, "-")
+	parts := strings.Split(rest, ".")
+	if len(parts) != 3 {
+		return SemVer{}, fmt.Errorf("invalid semantic version '%s': expected MAJOR.MINOR.PATCH", s)
+	}
+	for i, p := range []*uint64{&v.Major, &v.Minor, &v.Patch} {
+		n, err := parseSemVerNumber(parts[i])
+		if err != nil {
+			return SemVer{}, fmt.Errorf("invalid semantic version '%s': %w", s, err)
+		}
+		*p = n
+	}
+	if hasPrerelease {
+		if err := validateSemVerIdentifiers(prerelease, true); err != nil {
+			return SemVer{}, fmt.Errorf("invalid semantic version '%s': prerelease %w", s, err)
+		}
+		v.Prerelease = prerelease
+	}
+	if hasBuild {
+		if err := validateSemVerIdentifiers(build, false); err != nil {
+			return SemVer{}, fmt.Errorf("invalid semantic version '%s': build %w", s, err)
+		}
+		v.Build = build
+	}
+	return v, nil
+}
+
+func parseSemVerNumber(s string) (uint64, error) {
+	if len(s) > 1 && s[0] == '0' {
+		return 0, fmt.Errorf("number '%s' has a leading zero", s)
+	}
+	if s == "" || strings.TrimLeft(s, "0123456789") != "" {
+		return 0, fmt.Errorf("'%s' is not a number", s)
+	}
+	return strconv.ParseUint(s, 10, 64)
+}
+
+func validateSemVerIdentifiers(s string, numeric bool) error {
+	for _, id := range strings.Split(s, ".") {
+		if id == "" {
+			return fmt.Errorf("has an empty identifier")
+		}
+		if strings.TrimLeft(id, "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz-") != "" {
+			return fmt.Errorf("identifier '%s' has invalid characters", id)
+		}
+		if numeric && len(id) > 1 && id[0] == '0' && strings.TrimLeft(id, "0123456789") == "" {
+			return fmt.Errorf("identifier '%s' has a leading zero", id)
+		}
+	}
+	return nil
+}
+
+func (v *SemVer) UnmarshalText(text []byte) error {
+	parsed, err := ParseSemVer(string(text))
+	if err != nil {
+		return err
+	}
+	*v = parsed
+	return nil
+}
+
+func (v SemVer) String() string {
+	s := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.Prerelease != "" {
+		s += "-" + v.Prerelease
+	}
+	if v.Build != "" {
+		s += "+" + v.Build
+	}
+	return s
+}
+
+// Compare returns -1, 0, or +1 depending on whether v has lower, equal, or higher
+// precedence than w. Build metadata is ignored, as required by the specification.
+func (v SemVer) Compare(w SemVer) int {
+	for _, c := range [][2]uint64{{v.Major, w.Major}, {v.Minor, w.Minor}, {v.Patch, w.Patch}} {
+		if c[0] != c[1] {
+			if c[0] < c[1] {
+				return -1
+			}
+			return 1
+		}
+	}
+	switch {
+	case v.Prerelease == w.Prerelease:
+		return 0
+	case v.Prerelease == "":
+		return 1
+	case w.Prerelease == "":
+		return -1
+	}
+	a, b := strings.Split(v.Prerelease, "."), strings.Split(w.Prerelease, ".")
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if c := compareSemVerIdentifier(a[i], b[i]); c != 0 {
+			return c
+		}
+	}
+	switch {
+	case len(a) < len(b):
+		return -1
+	case len(a) > len(b):
+		return 1
+	}
+	return 0
+}
+
+func compareSemVerIdentifier(a, b string) int {
+	an, aErr := strconv.ParseUint(a, 10, 64)
+	bn, bErr := strconv.ParseUint(b, 10, 64)
+	switch {
+	case aErr == nil && bErr == nil:
+		switch {
+		case an < bn:
+			return -1
+		case an > bn:
+			return 1
+		}
+		return 0
+	case aErr == nil:
+		return -1
+	case bErr == nil:
+		return 1
+	}
+	return strings.Compare(a, b)
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+type SemVerStruct struct {
+	MinClientVersion SemVer  `env:"MIN_CLIENT_VERSION" default:"1.4.0"`
+	MaxVersion       *SemVer `env:"MAX_VERSION"`
+}
+
+func TestNewSemVer(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		args    []string
+		want    *SemVerStruct
+		wantErr bool
+	}{
+		{name: "Defaults", env: map[string]string{}, args: []string{"ConfigTestApp"}, want: &SemVerStruct{MinClientVersion: SemVer{Major: 1, Minor: 4}}},
+		{name: "SetArg", env: map[string]string{}, args: []string{"ConfigTestApp", "-MAX_VERSION=v2.0.0-rc.1+build-5"}, want: &SemVerStruct{MinClientVersion: SemVer{Major: 1, Minor: 4}, MaxVersion: &SemVer{Major: 2, Prerelease: "rc.1", Build: "build-5"}}},
+		{name: "MissingPatch", env: map[string]string{"MIN_CLIENT_VERSION": "1.4"}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+		{name: "LeadingZero", env: map[string]string{"MIN_CLIENT_VERSION": "1.04.0"}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+		{name: "InvalidPrerelease", env: map[string]string{"MIN_CLIENT_VERSION": "1.4.0-rc..1"}, args: []string{"ConfigTestApp"}, want: nil, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(makeLookup(tt.env), tt.args, &SemVerStruct{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("New() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSemVerCompare(t *testing.T) {
+	//Ordered by precedence, from the example in the specification.
+	versions := []string{"1.0.0-alpha", "1.0.0-alpha.1", "1.0.0-alpha.beta", "1.0.0-beta", "1.0.0-beta.2", "1.0.0-beta.11", "1.0.0-rc.1", "1.0.0", "1.0.1", "1.1.0", "2.0.0"}
+	for i := range versions {
+		for j := range versions {
+			v, err := ParseSemVer(versions[i])
+			if err != nil {
+				t.Fatal(err)
+			}
+			w, err := ParseSemVer(versions[j])
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := 0
+			if i < j {
+				want = -1
+			} else if i > j {
+				want = 1
+			}
+			if got := v.Compare(w); got != want {
+				t.Errorf("%s.Compare(%s) = %d, want %d", v, w, got, want)
+			}
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"regexp"
+)
+
+/*
+WithSOPSFile adds the config file at path, encrypted with SOPS, as a source of values
+below environment variables. The file may be in any format SOPS supports, including
+YAML, and is mapped onto fields like [WithJSONFile] once decrypted.
+
+This runs `sops --decrypt`, which must be installed, so keys are configured as for SOPS
+itself, e.g. with the `SOPS_AGE_KEY_FILE` environment variable or the credentials of the
+KMS holding the data key. Environment variables given to [New] are not passed to it.
+
+[WithConfigFile] also decrypts `.json` files with a top level `sops` metadata object
+this way, as well as `.yaml` and `.yml` files with a top level `sops` key, which are not
+supported otherwise.
+*/
+func WithSOPSFile(path string) Option {
+	return func(l *loader) {
+		l.sources = append(l.sources, &source{name: "file " + path, open: func(l *loader) (map[string]string, error) {
+			return l.decryptSOPS(path)
+		}})
+	}
+}
+
+func (l *loader) decryptSOPS(path string) (map[string]string, error) {
+	stdout, err := runCommand(exec.Command("sops", "--decrypt", "--output-type", "json", path))
+	if err != nil {
+		return nil, err
+	}
+	return decoders[".json"](l, stdout)
+}
+
+var sopsYAMLKey = regexp.MustCompile(`(?m)^sops:`)
+
+// isSOPSFile reports whether the JSON or YAML file at path holds SOPS metadata.
+func isSOPSFile(path, ext string) (bool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false, err
+	}
+	if ext != ".json" {
+		return sopsYAMLKey.Match(data), nil
+	}
+	var doc struct {
+		SOPS json.RawMessage `json:"sops"`
+	}
+	//Invalid JSON is left to be reported by the JSON decoder.
+	if json.Unmarshal(data, &doc) != nil {
+		return false, nil
+	}
+	return bytes.HasPrefix(doc.SOPS, []byte("{")), nil
+}
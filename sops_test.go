@@ -0,0 +1,50 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSOPSFile(t *testing.T) {
+	fakeCommand(t, "sops", `
+[ "$1 $2 $3" = "--decrypt --output-type json" ] || exit 2
+case "$4" in
+*bad*) echo 'Failed to get the data key required to decrypt the SOPS file.' >&2; exit 128 ;;
+*) echo '{"name": "sops", "db": {"password": "s3cret"}}' ;;
+esac
+`)
+	yaml := writeFile(t, "secrets.yaml", "name: ENC[AES256_GCM,data:abc=,type:str]\nsops:\n    version: 3.8.1\n")
+	json := writeFile(t, "secrets.json", `{"name": "ENC[AES256_GCM,data:abc=,type:str]", "sops": {"mac": "ENC[...]", "version": "3.8.1"}}`)
+	type C struct {
+		Name string `env:"NAME"`
+		DB   struct {
+			Password string `env:"PASSWORD"`
+		}
+	}
+	want := &C{Name: "sops"}
+	want.DB.Password = "s3cret"
+	for _, opt := range []Option{WithSOPSFile(yaml), WithSOPSFile(json), WithConfigFiles(yaml), WithConfigFiles(json)} {
+		got, err := New(makeLookup(nil), []string{"test"}, &C{}, opt)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("New() = %+v, want %+v", got, want)
+		}
+	}
+
+	//Plain files are not passed to sops.
+	plain := writeFile(t, "plain.json", `{"name": "plain", "sops": "not metadata"}`)
+	if got, err := New(makeLookup(nil), []string{"test"}, &C{}, WithConfigFiles(plain)); err != nil || got.Name != "plain" {
+		t.Errorf("New() = %+v, %v, want the plain file", got, err)
+	}
+	_, err := New(makeLookup(nil), []string{"test"}, &C{}, WithConfigFiles(writeFile(t, "plain.yaml", "name: plain\n")))
+	if err == nil || !strings.Contains(err.Error(), "unsupported config file extension '.yaml'") {
+		t.Errorf("New() error = %v, want plain YAML to be unsupported", err)
+	}
+	_, err = New(makeLookup(nil), []string{"test"}, &C{}, WithSOPSFile("bad.yaml"))
+	if err == nil || err.Error() != "failed to load file bad.yaml: sops: Failed to get the data key required to decrypt the SOPS file." {
+		t.Errorf("New() error = %v, want the sops error", err)
+	}
+}
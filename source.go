@@ -0,0 +1,289 @@
+package config
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+/*
+Source provides values below environment variables, like a config file, for
+[WithSource]. Lookup is called with the file key of each field in lower case, e.g.
+`db_host`, and reports whether the source has a value for it.
+*/
+type Source interface {
+	Lookup(key string) (string, bool)
+}
+
+/*
+Watcher is implemented by sources that can report when their values change, such as
+[GRPCSource] and [RedisSource]. Watch calls onChange for each change until ctx is done,
+and returns the error that ended it, which is ctx.Err() if ctx was done. A [Source]
+given to [WithSource] may implement it too, to be watched by [Watch].
+*/
+type Watcher interface {
+	Watch(ctx context.Context, onChange func()) error
+}
+
+/*
+Watch watches the sources added by opts that implement [Watcher], calling onChange when
+any of them changes, so the config can be loaded again by calling [New] with the same
+options:
+
+	opts := []config.Option{config.WithGRPC(src)}
+	go config.Watch(ctx, func() { reload <- struct{}{} }, opts...)
+
+onChange may be called concurrently. Watch returns once ctx is done, with ctx.Err(), or
+the error of the first watcher to fail, which stops the others. It is an error if none
+of the sources can be watched.
+*/
+func Watch(ctx context.Context, onChange func(), opts ...Option) error {
+	l := newLoader(nil, opts)
+	if len(l.watchers) == 0 {
+		return errors.New("config.Watch: none of the sources can be watched")
+	}
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	errs := make(chan error, len(l.watchers))
+	for _, w := range l.watchers {
+		go func() { errs <- w.Watch(ctx, onChange) }()
+	}
+	err := <-errs
+	cancel()
+	for range len(l.watchers) - 1 {
+		<-errs
+	}
+	return err
+}
+
+/*
+WithSource adds src as a source of values below environment variables. Sources are
+consulted in the order their options are given, with later ones taking precedence, so
+it can be placed between config files. Its name in errors is given by its String
+method, if it has one, and it is watched by [Watch] if it implements [Watcher].
+*/
+func WithSource(src Source) Option {
+	return func(l *loader) {
+		name := fmt.Sprintf("source %T", src)
+		if s, ok := src.(fmt.Stringer); ok {
+			name = s.String()
+		}
+		l.sources = append(l.sources, &source{name: name, src: src})
+		if w, ok := src.(Watcher); ok {
+			l.watchers = append(l.watchers, w)
+		}
+	}
+}
+
+/*
+MapSource returns a [Source] of the values, keyed like a flattened config file, so
+`db_host` sets the `DB_HOST` field. Keys are matched case insensitively. It is useful to
+inject values computed at startup, or in tests:
+
+	config.New(os.LookupEnv, os.Args, &C{}, config.WithSource(config.MapSource(map[string]string{
+		"db_host": host,
+	})))
+
+The map is copied, so later changes to it are not seen.
+*/
+func MapSource(values map[string]string) Source {
+	m := make(mapSource, len(values))
+	for k, v := range values {
+		m[strings.ToLower(k)] = v
+	}
+	return m
+}
+
+type mapSource map[string]string
+
+func (m mapSource) Lookup(key string) (string, bool) {
+	v, ok := m[key]
+	return v, ok
+}
+
+func (m mapSource) String() string { return "map" }
+
+// source is a layer of values below environment variables, such as a config file.
+// Its values are looked up by the file keys of the fields.
+type source struct {
+	name   string // Shown in errors, e.g. `file config.json`
+	open   func(l *loader) (map[string]string, error)
+	values map[string]string
+	src    Source                 // Looked up instead of values, if set
+	key    func(l *loader) string // Identifies the values for WithCache, if they depend on args or env
+	//resolve names the source from args or env before it is opened. Unlike open, it is
+	//also called when WithCache has the values.
+	resolve func(l *loader)
+}
+
+// lookup returns the value of the lower cased file key in s.
+func (s *source) lookup(key string) (string, bool) {
+	if s.src != nil {
+		return s.src.Lookup(key)
+	}
+	value, ok := s.values[key]
+	return value, ok
+}
+
+// openSources loads the values of every source added by the options.
+func (l *loader) openSources() error {
+	for _, s := range l.sources {
+		if s.resolve != nil {
+			s.resolve(l)
+		}
+		if s.open == nil {
+			continue
+		}
+		values, err := s.open(l)
+		if err != nil {
+			return fmt.Errorf("failed to load %s: %w", s.name, err)
+		}
+		s.values = make(map[string]string, len(values))
+		for k, v := range values {
+			s.values[strings.ToLower(k)] = v
+		}
+	}
+	return nil
+}
+
+// lookupSources returns the value of the file key from the last source that has it, and
+// the name of that source. Keys are matched case insensitively.
+func (l *loader) lookupSources(key string) (value, name string, ok bool) {
+	if key == "" {
+		return "", "", false
+	}
+	key = strings.ToLower(key)
+	for i := len(l.sources) - 1; i >= 0; i-- {
+		if value, ok := l.sources[i].lookup(key); ok {
+			return value, l.sources[i].name, true
+		}
+	}
+	return "", "", false
+}
+
+// resolver provides the values of fields with a struct tag referencing where each one
+// is stored, such as the path of a secret. Resolved values take precedence over sources
+// and defaults, but not environment variables or command line arguments.
+type resolver struct {
+	tag     string
+	name    string // Shown in errors, e.g. `vault`
+	resolve func(l *loader, ref string) (string, error)
+}
+
+// errNoValue is returned by resolvers that have no value for a reference, which leaves
+// the field to the other layers rather than failing.
+var errNoValue = errors.New("no value")
+
+// resolveTags returns the value of the first resolver whose tag is set in tag, and the
+// name of that resolver.
+func (l *loader) resolveTags(tag reflect.StructTag) (value, name string, ok bool, err error) {
+	for _, r := range l.resolvers {
+		ref, ok := tag.Lookup(r.tag)
+		if !ok {
+			continue
+		}
+		value, err := r.resolve(l, ref)
+		if errors.Is(err, errNoValue) {
+			return "", r.name, false, nil
+		}
+		return value, r.name, err == nil, err
+	}
+	return "", "", false, nil
+}
+
+// decoder turns the contents of a config file into values keyed by file key.
+type decoder func(l *loader, data []byte) (map[string]string, error)
+
+// document returns a decoder for formats decoded into a document of nested values.
+func document(decode func([]byte) (map[string]any, error)) decoder {
+	return func(l *loader, data []byte) (map[string]string, error) {
+		doc, err := decode(data)
+		if err != nil {
+			return nil, err
+		}
+		values := map[string]string{}
+		if err := l.flatten(values, "", doc); err != nil {
+			return nil, err
+		}
+		return values, nil
+	}
+}
+
+// withFile adds the file at path as a source, decoded by decode.
+func withFile(path string, decode decoder) Option {
+	return func(l *loader) {
+		l.sources = append(l.sources, &source{name: "file " + path, open: func(l *loader) (map[string]string, error) {
+			return l.readFile(path, decode)
+		}})
+	}
+}
+
+func (l *loader) readFile(path string, decode decoder) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return decode(l, data)
+}
+
+/*
+Flatten the decoded document v into values keyed by the path to each of them. The keys
+of nested objects are joined with the file key delimiter, so `{"db": {"host": "x"}}`
+provides `db_host`. Objects are also kept whole as JSON under their own key, for map,
+struct slice and `format:"json"` fields. Arrays are kept as JSON too, which slice fields
+split into their elements, so elements may contain the delimiter. Nulls are skipped.
+*/
+func (l *loader) flatten(values map[string]string, key string, v any) error {
+	switch v := v.(type) {
+	case nil:
+	case map[string]any:
+		if key != "" {
+			raw, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			values[key] = string(raw)
+			key += l.keyDelimiter
+		}
+		for k, e := range v {
+			if err := l.flatten(values, key+k, e); err != nil {
+				return err
+			}
+		}
+	case []any:
+		raw, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		values[key] = string(raw)
+	default:
+		s, ok := scalarString(v)
+		if !ok {
+			return fmt.Errorf("unsupported value %v for key '%s'", v, key)
+		}
+		values[key] = s
+	}
+	return nil
+}
+
+// scalarString formats a decoded string, number, or bool as a value to parse.
+func scalarString(v any) (string, bool) {
+	switch v := v.(type) {
+	case string:
+		return v, true
+	case json.Number:
+		return v.String(), true
+	case bool:
+		return strconv.FormatBool(v), true
+	case int64:
+		return strconv.FormatInt(v, 10), true
+	case float64:
+		return strconv.FormatFloat(v, 'g', -1, 64), true
+	}
+	return "", false
+}
@@ -0,0 +1,139 @@
+package config
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"sync/atomic"
+	"testing"
+)
+
+type upperSource map[string]string
+
+func (s upperSource) Lookup(key string) (string, bool) {
+	v, ok := s[strings.ToUpper(key)]
+	return v, ok
+}
+
+func (s upperSource) String() string { return "upper" }
+
+var (
+	_ Watcher = GRPCSource{}
+	_ Watcher = RedisSource{}
+)
+
+// watchedSource is a Source whose Watch reports changes until ctx is done, or returns
+// err after reporting them.
+type watchedSource struct {
+	changes int
+	err     error
+}
+
+func (s watchedSource) Lookup(key string) (string, bool) { return "", false }
+
+func (s watchedSource) Watch(ctx context.Context, onChange func()) error {
+	for range s.changes {
+		onChange()
+	}
+	if s.err != nil {
+		return s.err
+	}
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func TestWithSource(t *testing.T) {
+	base := writeFile(t, "base.json", `{"name": "base", "port": 80, "host": "base"}`)
+	override := writeFile(t, "override.json", `{"host": "override"}`)
+	type C struct {
+		Name string `env:"NAME"`
+		Port int    `env:"PORT"`
+		Host string `env:"HOST"`
+		DB   struct {
+			User string `env:"USER"`
+		}
+	}
+	src := upperSource{"PORT": "8080", "HOST": "source", "DB_USER": "admin"}
+	got, err := New(makeLookup(map[string]string{"NAME": "env"}), []string{"test"}, &C{}, WithJSONFile(base), WithSource(src), WithJSONFile(override))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &C{Name: "env", Port: 8080, Host: "override"}
+	want.DB.User = "admin"
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("New() = %+v, want %+v", got, want)
+	}
+
+	_, err = New(makeLookup(nil), []string{"test"}, &C{}, WithSource(upperSource{"PORT": "x"}))
+	if err == nil || !strings.Contains(err.Error(), "from upper:") {
+		t.Errorf("New() error = %v, want it to name the source", err)
+	}
+}
+
+func TestMapSource(t *testing.T) {
+	type C struct {
+		Name string `env:"NAME" default:"default"`
+		DB   struct {
+			Host string `env:"HOST"`
+		}
+		Port int `env:"PORT"`
+	}
+	values := map[string]string{"DB_HOST": "db", "port": "5432", "name": "map"}
+	opt := WithSource(MapSource(values))
+	values["port"] = "1"
+	got, err := New(makeLookup(map[string]string{"NAME": "env"}), []string{"test"}, &C{}, opt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &C{Name: "env", Port: 5432}
+	want.DB.Host = "db"
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("New() = %+v, want %+v", got, want)
+	}
+	_, err = New(makeLookup(nil), []string{"test"}, &C{}, WithSource(MapSource(map[string]string{"port": "x"})))
+	if err == nil || !strings.Contains(err.Error(), "from map:") {
+		t.Errorf("New() error = %v, want it to name the map source", err)
+	}
+}
+
+func TestWatch(t *testing.T) {
+	t.Run("Changes", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		var changes atomic.Int32
+		err := Watch(ctx, func() {
+			if changes.Add(1) == 3 {
+				cancel()
+			}
+		}, WithSource(watchedSource{changes: 1}), WithSource(MapSource(nil)), WithSource(watchedSource{changes: 2}))
+		if err != context.Canceled {
+			t.Errorf("Watch() error = %v, want %v", err, context.Canceled)
+		}
+		if n := changes.Load(); n != 3 {
+			t.Errorf("changes = %d, want 3", n)
+		}
+	})
+
+	t.Run("Error", func(t *testing.T) {
+		failed := errors.New("failed")
+		err := Watch(context.Background(), func() {}, WithSource(watchedSource{}), WithSource(watchedSource{err: failed}))
+		if err != failed {
+			t.Errorf("Watch() error = %v, want %v", err, failed)
+		}
+	})
+
+	t.Run("Redis", func(t *testing.T) {
+		if n := len(newLoader(nil, []Option{WithRedis(RedisSource{Hash: "app"})}).watchers); n != 0 {
+			t.Errorf("watchers without a channel = %d, want 0", n)
+		}
+		if n := len(newLoader(nil, []Option{WithRedis(RedisSource{Hash: "app", Channel: "config"})}).watchers); n != 1 {
+			t.Errorf("watchers with a channel = %d, want 1", n)
+		}
+	})
+
+	t.Run("None", func(t *testing.T) {
+		if err := Watch(context.Background(), func() {}, WithSource(MapSource(nil))); err == nil {
+			t.Error("Watch() error = nil, want an error without watchers")
+		}
+	})
+}
@@ -0,0 +1,65 @@
+package config
+
+import (
+	"context"
+	"database/sql"
+	"time"
+)
+
+// SQLSource describes a table of settings in a database, for [WithSQL].
+type SQLSource struct {
+	DB *sql.DB
+	//Query returns the settings as rows of a key and a value, both text. Defaults to
+	//`SELECT name, value FROM settings`.
+	Query string
+	//Args are the arguments of the query, e.g. a tenant ID to select the settings of.
+	Args []any
+	//Timeout limits the query. Defaults to 10 seconds.
+	Timeout time.Duration
+}
+
+/*
+WithSQL adds the rows returned by a query as a source of values below environment
+variables, using the first column as the key and the second as the value. Keys are
+matched like those of a flattened config file, so the key `db_host` sets the `DB_HOST`
+field. NULL values are skipped.
+
+	config.WithSQL(config.SQLSource{
+		DB:    db,
+		Query: "SELECT name, value FROM tenant_settings WHERE tenant_id = $1",
+		Args:  []any{tenantID},
+	})
+*/
+func WithSQL(src SQLSource) Option {
+	return func(l *loader) {
+		l.sources = append(l.sources, &source{name: "sql", open: func(l *loader) (map[string]string, error) {
+			query := src.Query
+			if query == "" {
+				query = "SELECT name, value FROM settings"
+			}
+			timeout := src.Timeout
+			if timeout == 0 {
+				timeout = 10 * time.Second
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			rows, err := src.DB.QueryContext(ctx, query, src.Args...)
+			if err != nil {
+				return nil, err
+			}
+			defer rows.Close()
+			values := map[string]string{}
+			for rows.Next() {
+				var key string
+				var value sql.NullString
+				if err := rows.Scan(&key, &value); err != nil {
+					return nil, err
+				}
+				if value.Valid {
+					values[key] = value.String
+				}
+			}
+			return values, rows.Err()
+		}})
+	}
+}
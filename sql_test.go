@@ -0,0 +1,103 @@
+package config
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+// settingsDriver is a database/sql driver serving a fixed settings table, keyed by the
+// first query argument or "" if there is none.
+type settingsDriver map[string][][2]any
+
+func (d settingsDriver) Open(string) (driver.Conn, error) { return settingsConn{d}, nil }
+
+type settingsConn struct{ d settingsDriver }
+
+func (c settingsConn) Prepare(query string) (driver.Stmt, error) {
+	if query != "SELECT name, value FROM settings" && query != "SELECT k, v FROM tenant_settings WHERE tenant = ?" {
+		return nil, errors.New("no such table")
+	}
+	return settingsStmt{c.d}, nil
+}
+func (c settingsConn) Close() error              { return nil }
+func (c settingsConn) Begin() (driver.Tx, error) { return nil, errors.New("not supported") }
+
+type settingsStmt struct{ d settingsDriver }
+
+func (s settingsStmt) Close() error  { return nil }
+func (s settingsStmt) NumInput() int { return -1 }
+func (s settingsStmt) Exec([]driver.Value) (driver.Result, error) {
+	return nil, errors.New("not supported")
+}
+func (s settingsStmt) Query(args []driver.Value) (driver.Rows, error) {
+	tenant := ""
+	if len(args) > 0 {
+		tenant = args[0].(string)
+	}
+	return &settingsRows{rows: s.d[tenant]}, nil
+}
+
+type settingsRows struct{ rows [][2]any }
+
+func (r *settingsRows) Columns() []string { return []string{"key", "value"} }
+func (r *settingsRows) Close() error      { return nil }
+func (r *settingsRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	dest[0], dest[1] = r.rows[0][0], r.rows[0][1]
+	r.rows = r.rows[1:]
+	return nil
+}
+
+func TestSQL(t *testing.T) {
+	sql.Register("settings", settingsDriver{
+		"":     {{"name", "app"}, {"db_port", "5432"}, {"debug", nil}},
+		"acme": {{"name", "acme"}},
+	})
+	db, err := sql.Open("settings", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	type C struct {
+		Name  string `env:"NAME"`
+		Debug bool   `env:"DEBUG" default:"true"`
+		DB    struct {
+			Port int `env:"PORT"`
+		}
+	}
+	tests := []struct {
+		name    string
+		src     SQLSource
+		want    *C
+		wantErr bool
+	}{
+		{
+			name: "default query",
+			src:  SQLSource{DB: db},
+			want: func() *C {
+				c := &C{Name: "app", Debug: true}
+				c.DB.Port = 5432
+				return c
+			}(),
+		},
+		{name: "custom query", src: SQLSource{DB: db, Query: "SELECT k, v FROM tenant_settings WHERE tenant = ?", Args: []any{"acme"}}, want: &C{Name: "acme", Debug: true}},
+		{name: "query error", src: SQLSource{DB: db, Query: "SELECT * FROM missing"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(makeLookup(nil), []string{"test"}, &C{}, WithSQL(tt.src))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
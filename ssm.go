@@ -0,0 +1,54 @@
+package config
+
+import (
+	"strings"
+)
+
+// SSMSource describes the parameters under a path in AWS Systems Manager Parameter
+// Store, for [WithSSM].
+type SSMSource struct {
+	AWS AWSConfig
+	//Path selects the parameters to read, recursively, e.g. `/app/prod/`.
+	Path string
+}
+
+/*
+WithSSM adds the parameters under a path in AWS Systems Manager Parameter Store as a
+source of values below environment variables. SecureString parameters are decrypted.
+The path is removed from each parameter name and the remaining `/` separated segments
+are joined with the file key delimiter, so with the path `/app/prod/` the parameter
+`/app/prod/db/password` sets the `DB_PASSWORD` field. StringList parameters are comma
+separated, so they populate slice fields.
+*/
+func WithSSM(src SSMSource) Option {
+	return func(l *loader) {
+		l.sources = append(l.sources, &source{name: "ssm " + src.Path, open: func(l *loader) (map[string]string, error) {
+			aws, err := src.AWS.resolve(l)
+			if err != nil {
+				return nil, err
+			}
+			values := map[string]string{}
+			path := "/" + strings.Trim(src.Path, "/")
+			req := map[string]any{"Path": path, "Recursive": true, "WithDecryption": true}
+			for {
+				var resp struct {
+					Parameters []struct {
+						Name  string
+						Value string
+					}
+					NextToken string
+				}
+				if err := aws.call("ssm", "AmazonSSM.GetParametersByPath", req, &resp); err != nil {
+					return nil, err
+				}
+				for _, p := range resp.Parameters {
+					values[l.pathKey(strings.TrimPrefix(p.Name, path), "/")] = p.Value
+				}
+				if resp.NextToken == "" {
+					return values, nil
+				}
+				req["NextToken"] = resp.NextToken
+			}
+		}})
+	}
+}
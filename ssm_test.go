@@ -0,0 +1,65 @@
+package config
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSSM(t *testing.T) {
+	type C struct {
+		Hosts []string `env:"HOSTS"`
+		DB    struct {
+			Password string `env:"PASSWORD"`
+		}
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Amz-Target") != "AmazonSSM.GetParametersByPath" || !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		var req struct {
+			Path           string
+			WithDecryption bool
+			NextToken      string
+		}
+		json.NewDecoder(r.Body).Decode(&req)
+		if req.Path != "/app/prod" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"__type": "com.amazon.coral#ValidationException", "message": "bad path"}`))
+			return
+		}
+		if req.NextToken == "" {
+			w.Write([]byte(`{"Parameters": [{"Name": "/app/prod/hosts", "Type": "StringList", "Value": "a,b"}], "NextToken": "next"}`))
+			return
+		}
+		password := "encrypted"
+		if req.WithDecryption {
+			password = "s3cret"
+		}
+		w.Write([]byte(`{"Parameters": [{"Name": "/app/prod/db/password", "Type": "SecureString", "Value": "` + password + `"}]}`))
+	}))
+	defer srv.Close()
+	env := map[string]string{"AWS_REGION": "us-east-1", "AWS_ACCESS_KEY_ID": "AKID", "AWS_SECRET_ACCESS_KEY": "secret"}
+
+	got, err := New(makeLookup(env), []string{"test"}, &C{}, WithSSM(SSMSource{AWS: AWSConfig{Endpoint: srv.URL}, Path: "/app/prod/"}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &C{Hosts: []string{"a", "b"}}
+	want.DB.Password = "s3cret"
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("New() = %+v, want %+v", got, want)
+	}
+
+	_, err = New(makeLookup(env), []string{"test"}, &C{}, WithSSM(SSMSource{AWS: AWSConfig{Endpoint: srv.URL}, Path: "/other"}))
+	if err == nil || !strings.Contains(err.Error(), "ValidationException: bad path") {
+		t.Errorf("New() error = %v, want ValidationException", err)
+	}
+	if _, err := New(makeLookup(nil), []string{"test"}, &C{}, WithSSM(SSMSource{AWS: AWSConfig{Endpoint: srv.URL}, Path: "/app/prod"})); err == nil {
+		t.Error("New() expected error without credentials")
+	}
+}
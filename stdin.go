@@ -0,0 +1,39 @@
+package config
+
+import (
+	"bytes"
+	"io"
+	"os"
+)
+
+/*
+WithStdin sets the reader used for the config file path `-` of [WithConfigFile] and
+[WithConfigFiles]. Defaults to os.Stdin.
+
+As piped input has no extension, its format is detected from its first character: `{`
+for JSON and `<` for XML, and TOML otherwise, which also reads most simple INI files.
+*/
+func WithStdin(r io.Reader) Option {
+	return func(l *loader) {
+		l.stdin = r
+	}
+}
+
+func (l *loader) readStdin() (map[string]string, error) {
+	r := l.stdin
+	if r == nil {
+		r = os.Stdin
+	}
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	decode := decoders[".toml"]
+	switch trimmed := bytes.TrimSpace(data); {
+	case bytes.HasPrefix(trimmed, []byte("{")):
+		decode = decoders[".json"]
+	case bytes.HasPrefix(trimmed, []byte("<")):
+		decode = decoders[".xml"]
+	}
+	return decode(l, data)
+}
@@ -0,0 +1,38 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestStdin(t *testing.T) {
+	type C struct {
+		Name string `env:"NAME" default:"default"`
+		Port int    `env:"PORT"`
+	}
+	tests := []struct {
+		name    string
+		stdin   string
+		args    []string
+		want    *C
+		wantErr bool
+	}{
+		{name: "json", stdin: ` {"name": "json", "port": 1}`, args: []string{"test", "-config", "-"}, want: &C{Name: "json", Port: 1}},
+		{name: "xml", stdin: `<config><name>xml</name></config>`, args: []string{"test", "--config=-"}, want: &C{Name: "xml"}},
+		{name: "toml", stdin: "# comment\nname = \"toml\"\nport = 2\n", args: []string{"test", "-config", "-"}, want: &C{Name: "toml", Port: 2}},
+		{name: "not read without flag", stdin: `{"name": "json"}`, args: []string{"test"}, want: &C{Name: "default"}},
+		{name: "invalid", stdin: "{", args: []string{"test", "-config", "-"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(makeLookup(nil), tt.args, &C{}, WithConfigFile(""), WithStdin(strings.NewReader(tt.stdin)))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,53 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// command is a subcommand declared by a field with a `cmd` tag.
+type command struct {
+	name  string
+	usage string
+	value reflect.Value // The field, a pointer to the struct of the subcommand
+}
+
+// collectCommands returns the subcommands declared by the fields of the struct v, which
+// must be pointers to structs.
+func collectCommands(v reflect.Value) ([]command, error) {
+	var commands []command
+	for i := 0; i < v.NumField(); i++ {
+		sf := v.Type().Field(i)
+		name, ok := sf.Tag.Lookup("cmd")
+		if !ok {
+			continue
+		}
+		if sf.Type.Kind() != reflect.Pointer || sf.Type.Elem().Kind() != reflect.Struct || !sf.IsExported() {
+			return nil, fmt.Errorf("field %s has a cmd tag but is not an exported pointer to a struct", sf.Name)
+		}
+		if name == "" {
+			name = kebabCase(sf.Name)
+		}
+		for _, c := range commands {
+			if c.name == name {
+				return nil, fmt.Errorf("subcommand '%s' is declared twice", name)
+			}
+		}
+		commands = append(commands, command{name: name, usage: sf.Tag.Get("usage"), value: v.Field(i)})
+	}
+	return commands, nil
+}
+
+// loadCommand populates the subcommand named by the first of args with the rest of
+// them.
+func (l *loader) loadCommand(commands []command, name string, args []string) error {
+	for _, c := range commands {
+		if c.name != args[0] {
+			continue
+		}
+		ptr := reflect.New(c.value.Type().Elem())
+		c.value.Set(ptr)
+		return l.load(ptr.Elem(), name+" "+c.name, args[1:], false)
+	}
+	return fmt.Errorf("unknown command '%s'", args[0])
+}
@@ -0,0 +1,129 @@
+package config
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type ServeConfig struct {
+	Port int    `env:"PORT" default:"8080" usage:"Port to listen on"`
+	Addr string `default:"localhost:{{.Port}}" flag:"addr"`
+}
+
+type MigrateConfig struct {
+	Steps int         `flag:"steps" short:"n"`
+	Down  *DownConfig `cmd:"" usage:"Revert migrations"`
+}
+
+type DownConfig struct {
+	Force   bool   `flag:"force"`
+	Version string `arg:"0"`
+}
+
+type CommandsStruct struct {
+	Verbose bool           `env:"VERBOSE" short:"v"`
+	Serve   *ServeConfig   `cmd:"serve" usage:"Run the server"`
+	Migrate *MigrateConfig `cmd:"" usage:"Apply migrations"`
+}
+
+func TestNewSubcommands(t *testing.T) {
+	tests := []struct {
+		name    string
+		env     map[string]string
+		args    []string
+		want    *CommandsStruct
+		wantErr string
+	}{
+		{name: "None", args: []string{"app", "-v"}, want: &CommandsStruct{Verbose: true}},
+		{name: "Serve", args: []string{"app", "-v", "serve", "-port=9000"}, want: &CommandsStruct{Verbose: true, Serve: &ServeConfig{Port: 9000, Addr: "localhost:9000"}}},
+		{name: "ServeEnv", env: map[string]string{"PORT": "90", "VERBOSE": "true"}, args: []string{"app", "serve"}, want: &CommandsStruct{Verbose: true, Serve: &ServeConfig{Port: 90, Addr: "localhost:90"}}},
+		{name: "Migrate", args: []string{"app", "migrate", "-n", "3"}, want: &CommandsStruct{Migrate: &MigrateConfig{Steps: 3}}},
+		{name: "Nested", args: []string{"app", "migrate", "-n=1", "down", "-force", "v2"}, want: &CommandsStruct{Migrate: &MigrateConfig{Steps: 1, Down: &DownConfig{Force: true, Version: "v2"}}}},
+		{name: "Unknown", args: []string{"app", "deploy"}, wantErr: "unknown command 'deploy'"},
+		{name: "GlobalAfterCommand", args: []string{"app", "serve", "-v"}, wantErr: "failed to parse command line arguments: flag provided but not defined: -v"},
+		{name: "CommandFlagBefore", args: []string{"app", "-port=1", "serve"}, wantErr: "failed to parse command line arguments: flag provided but not defined: -port"},
+		{name: "MissingArg", args: []string{"app", "migrate", "down"}, wantErr: "missing required setting Version (argument <version>)"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			got, err := New(makeLookup(tt.env), tt.args, &CommandsStruct{}, WithOutput(&b))
+			if tt.wantErr != "" {
+				if err == nil || err.Error() != tt.wantErr {
+					t.Errorf("New() error = %v, want %s", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("New() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewSubcommandsHelp(t *testing.T) {
+	var b strings.Builder
+	if _, err := New(makeLookup(nil), []string{"app", "-h"}, &CommandsStruct{}, WithOutput(&b)); err != ErrHelp {
+		t.Fatalf("New() error = %v, want ErrHelp", err)
+	}
+	want := `Usage: app [options] <command> [arguments]
+
+Commands:
+  serve          Run the server
+  migrate        Apply migrations
+
+Options:
+  -v, -verbose   (env VERBOSE)
+
+General options:
+  -h, -help      Show this help
+`
+	if b.String() != want {
+		t.Errorf("New() printed\n%s\nwant\n%s", b.String(), want)
+	}
+
+	b.Reset()
+	if _, err := New(makeLookup(nil), []string{"app", "migrate", "-h"}, &CommandsStruct{}, WithOutput(&b)); err != ErrHelp {
+		t.Fatalf("New() error = %v, want ErrHelp", err)
+	}
+	if !strings.HasPrefix(b.String(), "Usage: app migrate [options] <command> [arguments]") {
+		t.Errorf("New() printed\n%s\nwant the help of the subcommand", b.String())
+	}
+}
+
+func TestCollectCommands(t *testing.T) {
+	type NotPointer struct {
+		Serve ServeConfig `cmd:"serve"`
+	}
+	type Duplicate struct {
+		Serve *ServeConfig `cmd:"run"`
+		Run   *ServeConfig `cmd:""`
+	}
+	type WithArgs struct {
+		File  string       `arg:"0"`
+		Serve *ServeConfig `cmd:"serve"`
+	}
+	tests := []struct {
+		name    string
+		load    func() error
+		wantErr string
+	}{
+		{name: "NotPointer", load: func() error { _, err := New(makeLookup(nil), []string{"app"}, &NotPointer{}); return err },
+			wantErr: "field Serve has a cmd tag but is not an exported pointer to a struct"},
+		{name: "Duplicate", load: func() error { _, err := New(makeLookup(nil), []string{"app"}, &Duplicate{}); return err },
+			wantErr: "subcommand 'run' is declared twice"},
+		{name: "WithArgs", load: func() error { _, err := New(makeLookup(nil), []string{"app"}, &WithArgs{}); return err },
+			wantErr: "field File takes a positional argument but the struct has subcommands"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.load(); err == nil || err.Error() != tt.wantErr {
+				t.Errorf("New() error = %v, want %s", err, tt.wantErr)
+			}
+		})
+	}
+}
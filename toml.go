@@ -0,0 +1,542 @@
+package config
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+/*
+WithTOMLFile adds the TOML file at path as a source of values below environment
+variables. Tables map to nested structs like JSON objects, so both of these set the
+`DB_HOST` field:
+
+	[db]
+	host = "localhost"
+
+	db.host = "localhost"
+
+Arrays of tables populate slices of structs. Dates and times are kept as written, with
+a `T` between the date and time, so they can be parsed by `time.Time` fields. A missing
+file is an error.
+*/
+func WithTOMLFile(path string) Option {
+	return withFile(path, document(parseTOML))
+}
+
+// tomlParser decodes a TOML document into maps, slices, and scalar values.
+type tomlParser struct {
+	data string
+	pos  int
+	line int
+}
+
+func parseTOML(data []byte) (map[string]any, error) {
+	p := &tomlParser{data: string(data), line: 1}
+	doc, err := p.parse()
+	if err != nil {
+		return nil, fmt.Errorf("line %d: %w", p.line, err)
+	}
+	return doc, nil
+}
+
+func (p *tomlParser) parse() (map[string]any, error) {
+	root := map[string]any{}
+	table := root
+	for {
+		p.skipSpace()
+		if p.eof() {
+			return root, nil
+		}
+		switch p.peek() {
+		case '#', '\r', '\n':
+			if err := p.endLine(); err != nil {
+				return nil, err
+			}
+			continue
+		case '[':
+			var err error
+			if strings.HasPrefix(p.data[p.pos:], "[[") {
+				p.pos += 2
+				table, err = p.parseArrayTable(root)
+			} else {
+				p.pos++
+				table, err = p.parseTable(root)
+			}
+			if err != nil {
+				return nil, err
+			}
+		default:
+			if err := p.parseKeyValue(table); err != nil {
+				return nil, err
+			}
+		}
+		if err := p.endLine(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parseTable parses a `[table]` header and returns the table it names.
+func (p *tomlParser) parseTable(root map[string]any) (map[string]any, error) {
+	keys, err := p.parseKey()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect("]"); err != nil {
+		return nil, err
+	}
+	return descend(root, keys)
+}
+
+// parseArrayTable parses a `[[table]]` header and returns the new element it appends.
+func (p *tomlParser) parseArrayTable(root map[string]any) (map[string]any, error) {
+	keys, err := p.parseKey()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect("]]"); err != nil {
+		return nil, err
+	}
+	parent, err := descend(root, keys[:len(keys)-1])
+	if err != nil {
+		return nil, err
+	}
+	last := keys[len(keys)-1]
+	var array []any
+	switch v := parent[last].(type) {
+	case nil:
+	case []any:
+		array = v
+	default:
+		return nil, fmt.Errorf("key '%s' is not an array of tables", last)
+	}
+	table := map[string]any{}
+	parent[last] = append(array, table)
+	return table, nil
+}
+
+// descend returns the table at keys below t, creating missing tables. The last element
+// of an array of tables stands for the array.
+func descend(t map[string]any, keys []string) (map[string]any, error) {
+	for _, key := range keys {
+		switch v := t[key].(type) {
+		case nil:
+			next := map[string]any{}
+			t[key] = next
+			t = next
+		case map[string]any:
+			t = v
+		case []any:
+			if len(v) == 0 {
+				return nil, fmt.Errorf("key '%s' is not a table", key)
+			}
+			last, ok := v[len(v)-1].(map[string]any)
+			if !ok {
+				return nil, fmt.Errorf("key '%s' is not a table", key)
+			}
+			t = last
+		default:
+			return nil, fmt.Errorf("key '%s' is not a table", key)
+		}
+	}
+	return t, nil
+}
+
+func (p *tomlParser) parseKeyValue(t map[string]any) error {
+	keys, err := p.parseKey()
+	if err != nil {
+		return err
+	}
+	if err := p.expect("="); err != nil {
+		return err
+	}
+	p.skipSpace()
+	value, err := p.parseValue()
+	if err != nil {
+		return err
+	}
+	t, err = descend(t, keys[:len(keys)-1])
+	if err != nil {
+		return err
+	}
+	last := keys[len(keys)-1]
+	if _, ok := t[last]; ok {
+		return fmt.Errorf("duplicate key '%s'", last)
+	}
+	t[last] = value
+	return nil
+}
+
+// parseKey parses a dotted key made of bare and quoted keys.
+func (p *tomlParser) parseKey() ([]string, error) {
+	var keys []string
+	for {
+		p.skipSpace()
+		var key string
+		var err error
+		switch p.peek() {
+		case '"':
+			p.pos++
+			key, err = p.parseBasicString()
+		case '\'':
+			p.pos++
+			key, err = p.parseLiteralString()
+		default:
+			start := p.pos
+			for !p.eof() && isBareKeyChar(p.peek()) {
+				p.pos++
+			}
+			if p.pos == start {
+				return nil, fmt.Errorf("expected a key")
+			}
+			key = p.data[start:p.pos]
+		}
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, key)
+		p.skipSpace()
+		if p.peek() != '.' {
+			return keys, nil
+		}
+		p.pos++
+	}
+}
+
+func isBareKeyChar(c byte) bool {
+	return c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9' || c == '_' || c == '-'
+}
+
+func (p *tomlParser) parseValue() (any, error) {
+	switch p.peek() {
+	case '"':
+		if strings.HasPrefix(p.data[p.pos:], `"""`) {
+			p.pos += 3
+			return p.parseMultilineString(`"`)
+		}
+		p.pos++
+		return p.parseBasicString()
+	case '\'':
+		if strings.HasPrefix(p.data[p.pos:], "'''") {
+			p.pos += 3
+			return p.parseMultilineString("'")
+		}
+		p.pos++
+		return p.parseLiteralString()
+	case '[':
+		p.pos++
+		return p.parseArray()
+	case '{':
+		p.pos++
+		return p.parseInlineTable()
+	}
+	return p.parseScalar()
+}
+
+func (p *tomlParser) parseArray() ([]any, error) {
+	array := []any{}
+	for {
+		p.skipSpaceAndComments()
+		if p.peek() == ']' {
+			p.pos++
+			return array, nil
+		}
+		value, err := p.parseValue()
+		if err != nil {
+			return nil, err
+		}
+		array = append(array, value)
+		p.skipSpaceAndComments()
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case ']':
+		default:
+			return nil, fmt.Errorf("expected ',' or ']' in array")
+		}
+	}
+}
+
+func (p *tomlParser) parseInlineTable() (map[string]any, error) {
+	t := map[string]any{}
+	p.skipSpace()
+	if p.peek() == '}' {
+		p.pos++
+		return t, nil
+	}
+	for {
+		if err := p.parseKeyValue(t); err != nil {
+			return nil, err
+		}
+		p.skipSpace()
+		switch p.peek() {
+		case ',':
+			p.pos++
+		case '}':
+			p.pos++
+			return t, nil
+		default:
+			return nil, fmt.Errorf("expected ',' or '}' in inline table")
+		}
+	}
+}
+
+// parseBasicString parses a double quoted string, after the opening quote.
+func (p *tomlParser) parseBasicString() (string, error) {
+	var b strings.Builder
+	for {
+		if p.eof() || p.peek() == '\n' {
+			return "", fmt.Errorf("unterminated string")
+		}
+		c := p.data[p.pos]
+		p.pos++
+		switch c {
+		case '"':
+			return b.String(), nil
+		case '\\':
+			if err := p.parseEscape(&b); err != nil {
+				return "", err
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+}
+
+// parseLiteralString parses a single quoted string, after the opening quote.
+func (p *tomlParser) parseLiteralString() (string, error) {
+	end := strings.IndexAny(p.data[p.pos:], "'\n")
+	if end < 0 || p.data[p.pos+end] == '\n' {
+		return "", fmt.Errorf("unterminated string")
+	}
+	s := p.data[p.pos : p.pos+end]
+	p.pos += end + 1
+	return s, nil
+}
+
+// parseMultilineString parses a string delimited by three of quote, after the opening
+// delimiter. A newline right after the opening delimiter is trimmed, and in basic
+// strings escapes are processed and a backslash at the end of a line trims the
+// whitespace that follows.
+func (p *tomlParser) parseMultilineString(quote string) (string, error) {
+	if strings.HasPrefix(p.data[p.pos:], "\r\n") {
+		p.pos += 2
+	} else if strings.HasPrefix(p.data[p.pos:], "\n") {
+		p.pos++
+	}
+	delim := strings.Repeat(quote, 3)
+	var b strings.Builder
+	for {
+		if p.eof() {
+			return "", fmt.Errorf("unterminated string")
+		}
+		if strings.HasPrefix(p.data[p.pos:], delim) {
+			p.pos += 3
+			//Up to two quotes right before the closing delimiter belong to the string.
+			for i := 0; i < 2 && strings.HasPrefix(p.data[p.pos:], quote); i++ {
+				b.WriteString(quote)
+				p.pos++
+			}
+			return b.String(), nil
+		}
+		c := p.data[p.pos]
+		p.pos++
+		switch {
+		case c == '\\' && quote == `"`:
+			rest := strings.TrimLeft(p.data[p.pos:], " \t")
+			if strings.HasPrefix(rest, "\n") || strings.HasPrefix(rest, "\r\n") {
+				trimmed := strings.TrimLeft(rest, " \t\r\n")
+				p.line += strings.Count(rest[:len(rest)-len(trimmed)], "\n")
+				p.pos = len(p.data) - len(trimmed)
+				continue
+			}
+			if err := p.parseEscape(&b); err != nil {
+				return "", err
+			}
+		default:
+			if c == '\n' {
+				p.line++
+			}
+			b.WriteByte(c)
+		}
+	}
+}
+
+// parseEscape writes the character escaped by the sequence after a backslash.
+func (p *tomlParser) parseEscape(b *strings.Builder) error {
+	if p.eof() {
+		return fmt.Errorf("unterminated string")
+	}
+	c := p.data[p.pos]
+	p.pos++
+	switch c {
+	case 'b':
+		b.WriteByte('\b')
+	case 't':
+		b.WriteByte('\t')
+	case 'n':
+		b.WriteByte('\n')
+	case 'f':
+		b.WriteByte('\f')
+	case 'r':
+		b.WriteByte('\r')
+	case 'e':
+		b.WriteByte('\x1b')
+	case '"', '\\':
+		b.WriteByte(c)
+	case 'u', 'U':
+		size := 4
+		if c == 'U' {
+			size = 8
+		}
+		if p.pos+size > len(p.data) {
+			return fmt.Errorf("invalid unicode escape")
+		}
+		n, err := strconv.ParseUint(p.data[p.pos:p.pos+size], 16, 32)
+		if err != nil || !utf8.ValidRune(rune(n)) {
+			return fmt.Errorf("invalid unicode escape '\\%c%s'", c, p.data[p.pos:p.pos+size])
+		}
+		b.WriteRune(rune(n))
+		p.pos += size
+	default:
+		return fmt.Errorf("invalid escape '\\%c'", c)
+	}
+	return nil
+}
+
+// parseScalar parses a bool, number, date, or time. Dates and times are returned as
+// strings.
+func (p *tomlParser) parseScalar() (any, error) {
+	start := p.pos
+	for !p.eof() && isScalarChar(p.peek()) {
+		p.pos++
+	}
+	//A space may separate the date and time of a datetime.
+	if p.pos-start == 10 && p.data[start+4] == '-' && p.pos+1 < len(p.data) && p.peek() == ' ' && isDigit(p.data[p.pos+1]) {
+		p.pos++
+		for !p.eof() && isScalarChar(p.peek()) {
+			p.pos++
+		}
+	}
+	token := p.data[start:p.pos]
+	switch token {
+	case "":
+		return nil, fmt.Errorf("expected a value")
+	case "true":
+		return true, nil
+	case "false":
+		return false, nil
+	case "inf", "+inf":
+		return math.Inf(1), nil
+	case "-inf":
+		return math.Inf(-1), nil
+	case "nan", "+nan", "-nan":
+		return math.NaN(), nil
+	}
+	if len(token) >= 8 && (token[4] == '-' && isDigits(token[:4]) || token[2] == ':' && isDigits(token[:2])) {
+		return strings.Replace(token, " ", "T", 1), nil
+	}
+	number := strings.ReplaceAll(token, "_", "")
+	if len(number) > 2 && number[0] == '0' && strings.ContainsRune("xob", rune(number[1])) {
+		n, err := strconv.ParseInt(number, 0, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid integer '%s'", token)
+		}
+		return n, nil
+	}
+	if n, err := strconv.ParseInt(number, 10, 64); err == nil {
+		return n, nil
+	}
+	f, err := strconv.ParseFloat(number, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value '%s'", token)
+	}
+	return f, nil
+}
+
+func isScalarChar(c byte) bool {
+	return isBareKeyChar(c) || c == '+' || c == '.' || c == ':'
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func isDigits(s string) bool {
+	for i := 0; i < len(s); i++ {
+		if !isDigit(s[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+func (p *tomlParser) eof() bool {
+	return p.pos >= len(p.data)
+}
+
+func (p *tomlParser) peek() byte {
+	if p.eof() {
+		return 0
+	}
+	return p.data[p.pos]
+}
+
+func (p *tomlParser) skipSpace() {
+	for !p.eof() && (p.peek() == ' ' || p.peek() == '\t') {
+		p.pos++
+	}
+}
+
+// skipSpaceAndComments skips whitespace, newlines, and comments, as allowed in arrays.
+func (p *tomlParser) skipSpaceAndComments() {
+	for !p.eof() {
+		switch p.peek() {
+		case ' ', '\t', '\r':
+			p.pos++
+		case '\n':
+			p.line++
+			p.pos++
+		case '#':
+			for !p.eof() && p.peek() != '\n' {
+				p.pos++
+			}
+		default:
+			return
+		}
+	}
+}
+
+// endLine consumes the rest of the line, which may only hold whitespace and a comment.
+func (p *tomlParser) endLine() error {
+	p.skipSpace()
+	if p.peek() == '#' {
+		for !p.eof() && p.peek() != '\n' {
+			p.pos++
+		}
+	}
+	if strings.HasPrefix(p.data[p.pos:], "\r\n") {
+		p.pos++
+	}
+	if p.eof() {
+		return nil
+	}
+	if p.peek() != '\n' {
+		return fmt.Errorf("unexpected '%c' at end of line", p.peek())
+	}
+	p.line++
+	p.pos++
+	return nil
+}
+
+func (p *tomlParser) expect(s string) error {
+	p.skipSpace()
+	if !strings.HasPrefix(p.data[p.pos:], s) {
+		return fmt.Errorf("expected '%s'", s)
+	}
+	p.pos += len(s)
+	return nil
+}
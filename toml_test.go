@@ -0,0 +1,137 @@
+package config
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseTOML(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    map[string]any
+		wantErr bool
+	}{
+		{
+			name: "key values",
+			input: `# comment
+title = "app" # trailing comment
+port = 8_080
+ratio = 0.5
+debug = true
+mask = 0o755
+'quoted key' = 'C:\path'
+"escaped" = "a\tb\u00e9"
+`,
+			want: map[string]any{"title": "app", "port": int64(8080), "ratio": 0.5, "debug": true, "mask": int64(0o755), "quoted key": `C:\path`, "escaped": "a\tbé"},
+		},
+		{
+			name: "tables and dotted keys",
+			input: `db.user = "admin"
+[server]
+host = "localhost"
+[server.tls]
+enabled = true
+`,
+			want: map[string]any{
+				"db":     map[string]any{"user": "admin"},
+				"server": map[string]any{"host": "localhost", "tls": map[string]any{"enabled": true}},
+			},
+		},
+		{
+			name: "arrays and inline tables",
+			input: `ports = [
+  80, # http
+  443,
+]
+point = { x = 1, y = 2 }
+`,
+			want: map[string]any{"ports": []any{int64(80), int64(443)}, "point": map[string]any{"x": int64(1), "y": int64(2)}},
+		},
+		{
+			name: "array of tables",
+			input: `[[servers]]
+host = "a"
+[[servers]]
+host = "b"
+`,
+			want: map[string]any{"servers": []any{map[string]any{"host": "a"}, map[string]any{"host": "b"}}},
+		},
+		{
+			name: "multiline strings",
+			input: `basic = """
+one \
+  two"""
+literal = '''
+a\n'''
+`,
+			want: map[string]any{"basic": "one two", "literal": `a\n`},
+		},
+		{
+			name:  "dates and times",
+			input: "at = 1979-05-27 07:32:00Z\nday = 1979-05-27\nclock = 07:32:00\n",
+			want:  map[string]any{"at": "1979-05-27T07:32:00Z", "day": "1979-05-27", "clock": "07:32:00"},
+		},
+		{
+			name:  "special floats",
+			input: "big = 1e6\nmax = inf\n",
+			want:  map[string]any{"big": 1e6, "max": math.Inf(1)},
+		},
+		{name: "duplicate key", input: "a = 1\na = 2\n", wantErr: true},
+		{name: "unterminated string", input: `a = "b`, wantErr: true},
+		{name: "missing value", input: "a =\n", wantErr: true},
+		{name: "trailing garbage", input: "a = 1 2\n", wantErr: true},
+		{name: "table over value", input: "a = 1\n[a]\n", wantErr: true},
+		{name: "table over empty array", input: "a = []\n[a.b]\n", wantErr: true},
+		{name: "dotted key over empty array", input: "a = []\na.b = 1\n", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseTOML([]byte(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseTOML() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseTOML() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestTOMLFile(t *testing.T) {
+	type Server struct {
+		Host string `env:"HOST"`
+	}
+	type C struct {
+		Name    string        `env:"NAME"`
+		Timeout time.Duration `env:"TIMEOUT"`
+		Start   time.Time     `env:"START"`
+		Tags    []string      `env:"TAGS"`
+		Servers []Server      `env:"SERVERS"`
+		DB      struct {
+			Port int `env:"PORT" default:"5432"`
+		}
+	}
+	path := writeFile(t, "config.toml", `name = "api"
+timeout = "30s"
+start = 2024-01-02 03:04:05Z
+tags = ["a", "b"]
+
+[db]
+port = 6543
+
+[[servers]]
+host = "x"
+`)
+	got, err := New(makeLookup(map[string]string{"NAME": "env"}), []string{"test"}, &C{}, WithTOMLFile(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &C{Name: "env", Timeout: 30 * time.Second, Start: time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), Tags: []string{"a", "b"}, Servers: []Server{{Host: "x"}}}
+	want.DB.Port = 6543
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("New() = %+v, want %+v", got, want)
+	}
+}
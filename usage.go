@@ -0,0 +1,249 @@
+package config
+
+import (
+	"cmp"
+	"flag"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrHelp is returned by New when `-h` or `-help` is passed and the help listing has
+// been printed, so callers can exit cleanly. It is the same error as flag.ErrHelp.
+var ErrHelp = flag.ErrHelp
+
+// generalGroup is the group of the flags added by options rather than struct fields.
+const generalGroup = "General"
+
+// WithOutput sets the writer the help listing and command line errors are printed to.
+// Defaults to os.Stderr, like the `flag` package.
+func WithOutput(w io.Writer) Option {
+	return func(l *loader) {
+		l.output = w
+	}
+}
+
+// usage returns the description of the field given by its `usage` or `desc` tag.
+func (f field) usage() string {
+	return cmp.Or(f.tag.Get("usage"), f.tag.Get("desc"))
+}
+
+// usageEntry is a flag or positional argument in the help listing.
+type usageEntry struct {
+	name, desc string
+}
+
+// usageSection is a titled list of entries in the help listing.
+type usageSection struct {
+	title   string
+	entries []usageEntry
+}
+
+/*
+Write the help listing for the subcommands, positional arguments, and flags of fields to
+w. Flags are listed in field order, in their groups, with the `placeholder` tag or type
+of the value of each flag and the environment variable and default that also set it.
+Deprecated flags are left out. Descriptions are aligned in a column and wrapped to the
+terminal width given by the `COLUMNS` environment variable, or 80 columns:
+
+	Usage: app [options] <input>
+
+	Arguments:
+	  <input>              File to read
+
+	Options:
+	  -v, -verbose         Log every request (env VERBOSE)
+	      -host string     Address to listen on (env HTTP_HOST, default
+	                       "localhost")
+
+	General options:
+	      -config FILE     Path of the config file (env CONFIG_FILE)
+	  -h, -help            Show this help
+*/
+func (l *loader) writeUsage(w io.Writer, name string, fields []field, commands []command) {
+	var sections []*usageSection
+	add := func(title string, e usageEntry) {
+		for _, s := range sections {
+			if s.title == title {
+				s.entries = append(s.entries, e)
+				return
+			}
+		}
+		sections = append(sections, &usageSection{title: title, entries: []usageEntry{e}})
+	}
+	hasShort, taken := false, map[string]bool{}
+	for _, f := range fields {
+		if f.flag != "" {
+			hasShort = hasShort || f.short != ""
+			taken[f.flag], taken[f.alias], taken[f.short] = true, true, true
+		}
+	}
+
+	synopsis := name + " [options]"
+	positionals, _ := collectPositional(fields)
+	for _, f := range []*field{positionals.rest, positionals.passthrough} {
+		if f != nil {
+			positionals.fields = append(positionals.fields, *f)
+		}
+	}
+	for _, f := range positionals.fields {
+		synopsis += " " + f.argName()
+		add("Arguments", usageEntry{name: strings.Trim(f.argName(), "[]"), desc: f.usageDesc()})
+	}
+	if len(commands) > 0 {
+		synopsis += " <command> [arguments]"
+	}
+	for _, c := range commands {
+		add("Commands", usageEntry{name: c.name, desc: c.usage})
+	}
+	for _, f := range fields {
+		if _, ok := f.tag.Lookup("deprecated"); f.flag == "" || ok {
+			continue
+		}
+		e := usageEntry{name: l.longFlag() + f.flag, desc: f.usageDesc()}
+		if f.negation != "" {
+			e.name += ", " + l.longFlag() + f.negation
+		}
+		if f.short != "" {
+			e.name = "-" + f.short + ", " + e.name
+		} else if hasShort {
+			e.name = "    " + e.name
+		}
+		if t := usageType(f.value.Type()); t != "" && !f.isCount() {
+			e.name += " " + cmp.Or(f.tag.Get("placeholder"), t)
+		}
+		add(strings.TrimSpace(f.group+" options"), e)
+	}
+	if l.versionFlag && !taken["version"] {
+		e := usageEntry{name: l.longFlag() + "version", desc: "Show the version"}
+		if hasShort {
+			e.name = "    " + e.name
+		}
+		add(generalGroup+" options", e)
+	}
+	//The `flag` package handles `-h` and `-help` unless a field has taken them.
+	if !taken["help"] {
+		e := usageEntry{name: l.longFlag() + "help", desc: "Show this help"}
+		if !taken["h"] {
+			e.name = "-h, " + e.name
+		} else if hasShort {
+			e.name = "    " + e.name
+		}
+		add(generalGroup+" options", e)
+	}
+
+	width := 80
+	if columns, ok := l.lookupenv("COLUMNS"); ok {
+		if n, err := strconv.Atoi(columns); err == nil && n > 0 {
+			width = n
+		}
+	}
+	column := 0
+	for _, s := range sections {
+		for _, e := range s.entries {
+			if n := len(e.name); n <= maxUsageColumn {
+				column = max(column, n)
+			}
+		}
+	}
+	indent := 2 + column + 3
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "Usage: %s\n", synopsis)
+	for _, s := range sections {
+		fmt.Fprintf(&b, "\n%s%s:\n", strings.ToUpper(s.title[:1]), s.title[1:])
+		for _, e := range s.entries {
+			b.WriteString("  " + e.name)
+			if e.desc == "" {
+				b.WriteString("\n")
+				continue
+			}
+			if len(e.name) > column {
+				b.WriteString("\n" + strings.Repeat(" ", indent))
+			} else {
+				b.WriteString(strings.Repeat(" ", indent-2-len(e.name)))
+			}
+			lines := wrapText(e.desc, max(width-indent, minUsageWidth))
+			b.WriteString(strings.Join(lines, "\n"+strings.Repeat(" ", indent)) + "\n")
+		}
+	}
+	io.WriteString(w, b.String())
+}
+
+// usageDesc returns the description of f in the help listing, followed by whether it is
+// required and the env var and default that also set it.
+func (f field) usageDesc() string {
+	var notes []string
+	if f.isRequired() && f.arg == "" {
+		notes = append(notes, "required")
+	}
+	if f.env != "" {
+		notes = append(notes, "env "+f.env)
+	}
+	if def, ok := f.tag.Lookup("default"); ok && def != "" {
+		if f.value.Kind() == reflect.String {
+			def = strconv.Quote(def)
+		}
+		notes = append(notes, "default "+def)
+	}
+	if len(notes) == 0 {
+		return f.usage()
+	}
+	return strings.TrimSpace(f.usage() + " (" + strings.Join(notes, ", ") + ")")
+}
+
+const (
+	// maxUsageColumn is the widest flag name descriptions are aligned after. Longer
+	// names have their description start on the next line.
+	maxUsageColumn = 32
+	// minUsageWidth is the narrowest descriptions are wrapped to on small terminals.
+	minUsageWidth = 20
+)
+
+// wrapText splits text into lines of at most width characters at spaces, keeping its
+// line breaks. Words longer than width are left whole.
+func wrapText(text string, width int) []string {
+	var lines []string
+	for _, paragraph := range strings.Split(text, "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			switch {
+			case line == "":
+				line = word
+			case len(line)+1+len(word) > width:
+				lines = append(lines, line)
+				line = word
+			default:
+				line += " " + word
+			}
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+// usageType returns the name of the type of values a flag of type t takes, e.g. `int`,
+// `duration`, or `[]string`, or an empty string for bools, which take none.
+func usageType(t reflect.Type) string {
+	if isBoolType(t) {
+		return ""
+	}
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if isNullType(t) {
+		t = t.Field(0).Type
+	}
+	switch {
+	case t == reflect.TypeFor[time.Duration]():
+		return "duration"
+	case t.Name() != "" && t.PkgPath() == "":
+		return t.Name()
+	case t.Name() != "":
+		return strings.ToLower(t.Name())
+	}
+	return t.String()
+}
@@ -0,0 +1,190 @@
+package config
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteUsage(t *testing.T) {
+	type C struct {
+		Verbose bool          `env:"VERBOSE" short:"v" usage:"Log every request"`
+		Host    string        `env:"HTTP_HOST" default:"localhost" desc:"Address to listen on for incoming connections"`
+		Timeout time.Duration `env:"TIMEOUT" default:"5s"`
+		Tags    []string      `flag:"tag" usage:"Tags to add,\nin order"`
+		Port    *int          `env:"PORT"`
+		Quiet   bool          `flag:"quiet"`
+		Secret  string        `env:"SECRET" flag:"-" usage:"Not listed"`
+	}
+	l := &loader{lookupenv: makeLookup(map[string]string{"COLUMNS": "60"}), envDelimiter: "_", flagDelimiter: "_", keyDelimiter: "_", configFile: true}
+	fields, err := l.collectFields(reflect.ValueOf(&C{}).Elem(), "", prefix{})
+	if err != nil {
+		t.Fatalf("collectFields() error = %v", err)
+	}
+	var b strings.Builder
+	l.writeUsage(&b, "app", l.addFileFields(fields), nil)
+	want := `Usage: app [options]
+
+Options:
+  -v, -verbose            Log every request (env VERBOSE)
+      -host string        Address to listen on for incoming
+                          connections (env HTTP_HOST,
+                          default "localhost")
+      -timeout duration   (env TIMEOUT, default 5s)
+      -tag []string       Tags to add,
+                          in order
+      -port int           (env PORT)
+      -quiet
+
+General options:
+      -config FILE        Path of the config file (env
+                          CONFIG_FILE)
+  -h, -help               Show this help
+`
+	if got := b.String(); got != want {
+		t.Errorf("writeUsage() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestWriteUsagePlaceholder(t *testing.T) {
+	type C struct {
+		Output  string   `env:"OUTPUT" placeholder:"FILE" usage:"File to write"`
+		Tags    []string `flag:"tag" placeholder:"TAG"`
+		Verbose bool     `flag:"verbose" placeholder:"BOOL"`
+		Level   int      `flag:"level" count:"true" placeholder:"N"`
+	}
+	l := &loader{lookupenv: makeLookup(nil), envDelimiter: "_", flagDelimiter: "_", keyDelimiter: "_"}
+	fields, err := l.collectFields(reflect.ValueOf(&C{}).Elem(), "", prefix{})
+	if err != nil {
+		t.Fatalf("collectFields() error = %v", err)
+	}
+	var b strings.Builder
+	l.writeUsage(&b, "app", fields, nil)
+	want := `Usage: app [options]
+
+Options:
+  -output FILE   File to write (env OUTPUT)
+  -tag TAG
+  -verbose
+  -level
+
+General options:
+  -h, -help      Show this help
+`
+	if got := b.String(); got != want {
+		t.Errorf("writeUsage() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestNewHelp(t *testing.T) {
+	type C struct {
+		Host string `env:"HOST" usage:"Host to connect to"`
+	}
+	for _, arg := range []string{"-h", "-help", "--help"} {
+		var b strings.Builder
+		_, err := New(makeLookup(nil), []string{"app", arg}, &C{}, WithOutput(&b))
+		if err != ErrHelp {
+			t.Errorf("New(%s) error = %v, want ErrHelp", arg, err)
+		}
+		if !strings.Contains(b.String(), "-host string   Host to connect to (env HOST)") {
+			t.Errorf("New(%s) printed %q, want the help listing", arg, b.String())
+		}
+	}
+	var b strings.Builder
+	_, err := New(makeLookup(nil), []string{"app", "-port=1"}, &C{}, WithOutput(&b))
+	if err == nil || errors.Is(err, ErrHelp) {
+		t.Errorf("New() error = %v, want an error for an unknown flag", err)
+	}
+	if !strings.HasPrefix(b.String(), "flag provided but not defined: -port\nUsage: app") {
+		t.Errorf("New() printed %q, want the error and help listing", b.String())
+	}
+}
+
+func TestWrapText(t *testing.T) {
+	tests := []struct {
+		text  string
+		width int
+		want  []string
+	}{
+		{text: "", width: 10, want: []string{""}},
+		{text: "one two three", width: 7, want: []string{"one two", "three"}},
+		{text: "one  two\nthree", width: 20, want: []string{"one two", "three"}},
+		{text: "extraordinarily long", width: 5, want: []string{"extraordinarily", "long"}},
+	}
+	for _, tt := range tests {
+		if got := wrapText(tt.text, tt.width); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("wrapText(%q, %d) = %q, want %q", tt.text, tt.width, got, tt.want)
+		}
+	}
+}
+
+func TestUsageType(t *testing.T) {
+	type Format string
+	tests := []struct {
+		value any
+		want  string
+	}{
+		{value: false, want: ""},
+		{value: 0, want: "int"},
+		{value: "", want: "string"},
+		{value: time.Second, want: "duration"},
+		{value: Format(""), want: "format"},
+		{value: []int{}, want: "[]int"},
+		{value: map[string]string{}, want: "map[string]string"},
+		{value: ptr(1.5), want: "float64"},
+	}
+	for _, tt := range tests {
+		if got := usageType(reflect.TypeOf(tt.value)); got != tt.want {
+			t.Errorf("usageType(%T) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestWriteUsageGroups(t *testing.T) {
+	type C struct {
+		Host string `env:"HOST"`
+		TLS  struct {
+			Cert string `env:"CERT"`
+			Key  string `env:"KEY"`
+			ACME struct {
+				Email string `env:"EMAIL"`
+			}
+		}
+		Log struct {
+			Level string `env:"LEVEL"`
+			Trace bool   `env:"TRACE" group:"Debugging"`
+		} `group:"Logging"`
+		Debug bool `env:"DEBUG" group:"Debugging"`
+		Port  int  `env:"PORT"`
+	}
+	var b strings.Builder
+	if _, err := New(makeLookup(nil), []string{"app", "-h"}, &C{}, WithOutput(&b)); err != ErrHelp {
+		t.Fatalf("New() error = %v, want ErrHelp", err)
+	}
+	want := `Usage: app [options]
+
+Options:
+  -host string             (env HOST)
+  -port int                (env PORT)
+
+TLS options:
+  -tls-cert string         (env TLS_CERT)
+  -tls-key string          (env TLS_KEY)
+  -tls-acme-email string   (env TLS_ACME_EMAIL)
+
+Logging options:
+  -log-level string        (env LOG_LEVEL)
+
+Debugging options:
+  -log-trace               (env LOG_TRACE)
+  -debug                   (env DEBUG)
+
+General options:
+  -h, -help                Show this help
+`
+	if b.String() != want {
+		t.Errorf("New() printed\n%s\nwant\n%s", b.String(), want)
+	}
+}
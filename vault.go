@@ -0,0 +1,147 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// VaultSource describes how to connect to HashiCorp Vault, for [WithVault].
+type VaultSource struct {
+	//Address is the base URL of Vault. Defaults to the VAULT_ADDR environment variable.
+	Address string
+	//Token authenticates with Vault. Defaults to the VAULT_TOKEN environment variable,
+	//unless RoleID is set.
+	Token string
+	//RoleID and SecretID log in with the AppRole auth method, if RoleID is set.
+	RoleID   string
+	SecretID string
+	//Namespace is sent with each request, for Vault Enterprise.
+	Namespace string
+	//Timeout limits each request. Defaults to 10 seconds. It is ignored if Client is set.
+	Timeout time.Duration
+	//Client is used to send requests. Defaults to a client with the timeout.
+	Client *http.Client
+}
+
+/*
+WithVault resolves fields with a `vault` tag from the KV version 2 secrets engine of
+HashiCorp Vault. The tag holds the API path of a secret and the key to read from it,
+separated by `#`:
+
+	type C struct {
+		APIKey string `vault:"secret/data/app#api_key"`
+	}
+
+Each secret is read once, however many fields reference it. A missing secret or key is
+an error. Environment variables and command line arguments for the field still take
+precedence, and Vault is not contacted for fields they set.
+*/
+func WithVault(src VaultSource) Option {
+	return func(l *loader) {
+		v := &vaultClient{src: src, secrets: map[string]map[string]any{}}
+		l.resolvers = append(l.resolvers, &resolver{tag: "vault", name: "vault", resolve: v.resolve})
+	}
+}
+
+type vaultClient struct {
+	src     VaultSource
+	mu      sync.Mutex
+	header  http.Header
+	secrets map[string]map[string]any
+}
+
+func (v *vaultClient) resolve(l *loader, ref string) (string, error) {
+	path, key, ok := strings.Cut(ref, "#")
+	if !ok || path == "" || key == "" {
+		return "", fmt.Errorf("invalid vault reference '%s', expected path#key", ref)
+	}
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	secret, ok := v.secrets[path]
+	if !ok {
+		var err error
+		if secret, err = v.read(l, path); err != nil {
+			return "", fmt.Errorf("failed to read secret '%s': %w", path, err)
+		}
+		v.secrets[path] = secret
+	}
+	value, ok := secret[key]
+	if !ok {
+		return "", fmt.Errorf("secret '%s' has no key '%s'", path, key)
+	}
+	if s, ok := scalarString(value); ok {
+		return s, nil
+	}
+	raw, err := json.Marshal(value)
+	return string(raw), err
+}
+
+func (v *vaultClient) read(l *loader, path string) (map[string]any, error) {
+	address := v.src.Address
+	if address == "" {
+		address, _ = l.lookupenv("VAULT_ADDR")
+	}
+	if address == "" {
+		return nil, fmt.Errorf("no vault address, set VAULT_ADDR")
+	}
+	address = strings.TrimSuffix(address, "/")
+	client := v.src.Client
+	if client == nil {
+		client = &http.Client{Timeout: v.src.Timeout}
+		if client.Timeout == 0 {
+			client.Timeout = 10 * time.Second
+		}
+	}
+	if v.header == nil {
+		header, err := v.login(l, client, address)
+		if err != nil {
+			return nil, err
+		}
+		v.header = header
+	}
+	var resp struct {
+		Data struct {
+			Data map[string]any `json:"data"`
+		} `json:"data"`
+	}
+	if err := requestJSON(client, http.MethodGet, address+"/v1/"+strings.TrimPrefix(path, "/"), v.header, nil, &resp); err != nil {
+		return nil, err
+	}
+	if resp.Data.Data == nil {
+		return nil, fmt.Errorf("not a KV version 2 secret")
+	}
+	return resp.Data.Data, nil
+}
+
+// login returns the headers that authenticate requests, logging in with AppRole if a
+// role ID is set.
+func (v *vaultClient) login(l *loader, client *http.Client, address string) (http.Header, error) {
+	header := http.Header{}
+	if v.src.Namespace != "" {
+		header.Set("X-Vault-Namespace", v.src.Namespace)
+	}
+	token := v.src.Token
+	if v.src.RoleID != "" {
+		var resp struct {
+			Auth struct {
+				ClientToken string `json:"client_token"`
+			} `json:"auth"`
+		}
+		req := map[string]string{"role_id": v.src.RoleID, "secret_id": v.src.SecretID}
+		if err := requestJSON(client, http.MethodPost, address+"/v1/auth/approle/login", header, req, &resp); err != nil {
+			return nil, fmt.Errorf("approle login failed: %w", err)
+		}
+		token = resp.Auth.ClientToken
+	} else if token == "" {
+		token, _ = l.lookupenv("VAULT_TOKEN")
+	}
+	if token == "" {
+		return nil, fmt.Errorf("no vault token, set VAULT_TOKEN")
+	}
+	header.Set("X-Vault-Token", token)
+	return header, nil
+}
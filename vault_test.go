@@ -0,0 +1,83 @@
+package config
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestVault(t *testing.T) {
+	type C struct {
+		APIKey   string `vault:"secret/data/app#api_key"`
+		Password string `env:"DB_PASSWORD" vault:"secret/data/app#db_password"`
+		Port     int    `vault:"secret/data/db#port" default:"5432"`
+	}
+	reads := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/v1/auth/approle/login":
+			var req map[string]string
+			json.NewDecoder(r.Body).Decode(&req)
+			if req["role_id"] != "role" || req["secret_id"] != "secret" {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			w.Write([]byte(`{"auth": {"client_token": "approle-token"}}`))
+			return
+		}
+		if tok := r.Header.Get("X-Vault-Token"); tok != "token" && tok != "approle-token" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		switch r.URL.Path {
+		case "/v1/secret/data/app":
+			reads++
+			w.Write([]byte(`{"data": {"data": {"api_key": "k3y", "db_password": "pw"}}}`))
+		case "/v1/secret/data/db":
+			w.Write([]byte(`{"data": {"data": {"port": 6543}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	tests := []struct {
+		name    string
+		env     map[string]string
+		src     VaultSource
+		want    *C
+		wantErr bool
+	}{
+		{name: "token from env", env: map[string]string{"VAULT_ADDR": srv.URL, "VAULT_TOKEN": "token"}, want: &C{APIKey: "k3y", Password: "pw", Port: 6543}},
+		{name: "approle", src: VaultSource{Address: srv.URL, RoleID: "role", SecretID: "secret"}, want: &C{APIKey: "k3y", Password: "pw", Port: 6543}},
+		{name: "env takes precedence", env: map[string]string{"DB_PASSWORD": "local"}, src: VaultSource{Address: srv.URL, Token: "token"}, want: &C{APIKey: "k3y", Password: "local", Port: 6543}},
+		{name: "bad token", src: VaultSource{Address: srv.URL, Token: "wrong"}, wantErr: true},
+		{name: "no address", src: VaultSource{Token: "token"}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reads = 0
+			got, err := New(makeLookup(tt.env), []string{"test"}, &C{}, WithVault(tt.src))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %+v, want %+v", got, tt.want)
+			}
+			if reads != 1 {
+				t.Errorf("secret read %d times, want 1", reads)
+			}
+		})
+	}
+
+	type Missing struct {
+		Value string `vault:"secret/data/app#missing"`
+	}
+	if _, err := New(makeLookup(nil), []string{"test"}, &Missing{}, WithVault(VaultSource{Address: srv.URL, Token: "token"})); err == nil {
+		t.Error("New() expected error for missing key")
+	}
+}
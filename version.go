@@ -0,0 +1,103 @@
+package config
+
+import (
+	"cmp"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime/debug"
+	"strconv"
+)
+
+// ErrVersion is returned by New when `-version` is passed and the version given to
+// [WithVersion] has been printed, so callers can exit cleanly.
+var ErrVersion = errors.New("version requested")
+
+// readBuildInfo returns the build info of the binary, and is replaced in tests.
+var readBuildInfo = debug.ReadBuildInfo
+
+/*
+WithVersion adds a `-version` flag that prints the program name and version, and makes
+New return [ErrVersion]:
+
+	c, err := config.New(nil, nil, &C{}, config.WithVersion(version))
+	if errors.Is(err, config.ErrVersion) {
+		os.Exit(0)
+	}
+
+If version is empty, it is taken from the build info of the binary when the flag is
+given: the version of the main module when built with `go install`, or otherwise the
+VCS revision it was built from, marked `-dirty` if there were local changes. The
+version is printed to [WithOutput], or os.Stdout. The flag is left out if a field has
+taken its name.
+*/
+func WithVersion(version string) Option {
+	return func(l *loader) {
+		l.versionFlag, l.version = true, version
+	}
+}
+
+// buildVersion returns the version of the binary from its build info, or `(devel)` if
+// there is none.
+func buildVersion() string {
+	info, ok := readBuildInfo()
+	if !ok {
+		return "(devel)"
+	}
+	if info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	settings := map[string]string{}
+	for _, s := range info.Settings {
+		settings[s.Key] = s.Value
+	}
+	revision := settings["vcs.revision"]
+	if revision == "" {
+		return "(devel)"
+	}
+	revision = revision[:min(len(revision), 12)]
+	if settings["vcs.modified"] == "true" {
+		revision += "-dirty"
+	}
+	return revision
+}
+
+// versionFlag is the value of the `-version` flag added by WithVersion.
+type versionFlag bool
+
+func (v *versionFlag) String() string   { return "false" }
+func (v *versionFlag) IsBoolFlag() bool { return true }
+
+func (v *versionFlag) Set(s string) error {
+	b, err := strconv.ParseBool(s)
+	*v = versionFlag(b)
+	return err
+}
+
+// addVersionFlag defines the `-version` flag on flagset if WithVersion was used and no
+// field has taken its name.
+func (l *loader) addVersionFlag(flagset *flag.FlagSet) {
+	if l.versionFlag && flagset.Lookup("version") == nil {
+		flagset.Var(new(versionFlag), "version", "Show the version")
+	}
+}
+
+// printVersion prints the version and returns ErrVersion if the `-version` flag added
+// by addVersionFlag was given, and returns nil otherwise.
+func (l *loader) printVersion(flagset *flag.FlagSet, name string) error {
+	f := flagset.Lookup("version")
+	if f == nil {
+		return nil
+	}
+	if v, ok := f.Value.(*versionFlag); !ok || !bool(*v) {
+		return nil
+	}
+	var w io.Writer = os.Stdout
+	if l.output != nil {
+		w = l.output
+	}
+	fmt.Fprintf(w, "%s %s\n", name, cmp.Or(l.version, buildVersion()))
+	return ErrVersion
+}
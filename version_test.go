@@ -0,0 +1,119 @@
+package config
+
+import (
+	"errors"
+	"runtime/debug"
+	"strings"
+	"testing"
+)
+
+func TestWithVersion(t *testing.T) {
+	type C struct {
+		Port int `env:"PORT" required:"true"`
+	}
+	tests := []struct {
+		name    string
+		args    []string
+		opts    []Option
+		want    string
+		wantErr error
+	}{
+		{name: "Version", args: []string{"app", "-version"}, want: "app v1.2.3\n", wantErr: ErrVersion},
+		{name: "GNU", args: []string{"app", "--version"}, opts: []Option{WithGNUFlags()}, want: "app v1.2.3\n", wantErr: ErrVersion},
+		{name: "False", args: []string{"app", "-version=false", "-port=1"}},
+		{name: "NotGiven", args: []string{"app", "-port=1"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			_, err := New(makeLookup(nil), tt.args, &C{}, append(tt.opts, WithVersion("v1.2.3"), WithOutput(&b))...)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("New() error = %v, want %v", err, tt.wantErr)
+			}
+			if got := b.String(); got != tt.want {
+				t.Errorf("New() printed %q, want %q", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("FieldTakesName", func(t *testing.T) {
+		var b strings.Builder
+		got, err := New(makeLookup(nil), []string{"app", "-version", "2"}, &struct {
+			Version int `flag:"version"`
+		}{}, WithVersion("v1.2.3"), WithOutput(&b))
+		if err != nil {
+			t.Fatalf("New() error = %v", err)
+		}
+		if got.Version != 2 || b.Len() != 0 {
+			t.Errorf("New() = %+v, printed %q, want Version 2 and nothing printed", *got, b.String())
+		}
+	})
+
+	t.Run("Usage", func(t *testing.T) {
+		var b strings.Builder
+		_, err := New(makeLookup(nil), []string{"app", "-h"}, &struct{}{}, WithVersion("v1.2.3"), WithOutput(&b))
+		if !errors.Is(err, ErrHelp) {
+			t.Fatalf("New() error = %v, want %v", err, ErrHelp)
+		}
+		want := `Usage: app [options]
+
+General options:
+  -version    Show the version
+  -h, -help   Show this help
+`
+		if got := b.String(); got != want {
+			t.Errorf("New() printed\n%s\nwant\n%s", got, want)
+		}
+	})
+}
+
+func TestWithVersionBuildInfo(t *testing.T) {
+	reads := 0
+	defer func(f func() (*debug.BuildInfo, bool)) { readBuildInfo = f }(readBuildInfo)
+	readBuildInfo = func() (*debug.BuildInfo, bool) {
+		reads++
+		return &debug.BuildInfo{Main: debug.Module{Version: "v2.0.0"}}, true
+	}
+	var b strings.Builder
+	if _, err := New(makeLookup(nil), []string{"app"}, &struct{}{}, WithVersion(""), WithOutput(&b)); err != nil {
+		t.Fatalf("New() error = %v", err)
+	}
+	if reads != 0 {
+		t.Errorf("build info read %d times without -version, want 0", reads)
+	}
+	_, err := New(makeLookup(nil), []string{"app", "-version"}, &struct{}{}, WithVersion(""), WithOutput(&b))
+	if !errors.Is(err, ErrVersion) {
+		t.Fatalf("New() error = %v, want %v", err, ErrVersion)
+	}
+	if got, want := b.String(), "app v2.0.0\n"; got != want {
+		t.Errorf("New() printed %q, want %q", got, want)
+	}
+}
+
+func TestBuildVersion(t *testing.T) {
+	tests := []struct {
+		name string
+		info *debug.BuildInfo
+		want string
+	}{
+		{name: "None", want: "(devel)"},
+		{name: "Module", info: &debug.BuildInfo{Main: debug.Module{Version: "v1.0.0"}}, want: "v1.0.0"},
+		{name: "Revision", info: &debug.BuildInfo{Main: debug.Module{Version: "(devel)"}, Settings: []debug.BuildSetting{
+			{Key: "vcs.revision", Value: "0123456789abcdef"},
+		}}, want: "0123456789ab"},
+		{name: "Dirty", info: &debug.BuildInfo{Settings: []debug.BuildSetting{
+			{Key: "vcs.revision", Value: "0123456789abcdef"},
+			{Key: "vcs.modified", Value: "true"},
+		}}, want: "0123456789ab-dirty"},
+		{name: "NoRevision", info: &debug.BuildInfo{Main: debug.Module{Version: "(devel)"}}, want: "(devel)"},
+	}
+	defer func(f func() (*debug.BuildInfo, bool)) { readBuildInfo = f }(readBuildInfo)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			readBuildInfo = func() (*debug.BuildInfo, bool) { return tt.info, tt.info != nil }
+			if got := buildVersion(); got != tt.want {
+				t.Errorf("buildVersion() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,64 @@
+package config
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+/*
+WithXDGConfig adds the config file of the application app, found by following the XDG
+base directory specification, as a source of values below environment variables. The
+file is named `config` with any of the extensions supported by [WithConfigFile], and is
+searched for in:
+
+- `$XDG_CONFIG_HOME/<app>/`, or `$HOME/.config/<app>/` if XDG_CONFIG_HOME is unset
+- `<dir>/<app>/` for each dir in `$XDG_CONFIG_DIRS`, or `/etc/xdg/<app>/` if it is unset
+
+The first file found is used, and it is not an error if there is none. Environment
+variables are looked up with the lookupenv function passed to [New].
+*/
+func WithXDGConfig(app string) Option {
+	return func(l *loader) {
+		s := &source{name: "XDG config"}
+		s.key = func(l *loader) string {
+			return strings.Join(l.xdgConfigDirs(app), string(filepath.ListSeparator))
+		}
+		s.resolve = func(l *loader) {
+			if path, ok := findFile(l.xdgConfigDirs(app), "config"); ok {
+				s.name = "file " + path
+			}
+		}
+		s.open = func(l *loader) (map[string]string, error) {
+			path, ok := findFile(l.xdgConfigDirs(app), "config")
+			if !ok {
+				return nil, nil
+			}
+			return l.openFile(path)
+		}
+		l.sources = append(l.sources, s)
+	}
+}
+
+// xdgConfigDirs returns the directories searched for the config of app, most important
+// first. Relative paths are ignored, as required by the specification.
+func (l *loader) xdgConfigDirs(app string) []string {
+	var dirs []string
+	if home, ok := l.lookupenv("XDG_CONFIG_HOME"); ok && filepath.IsAbs(home) {
+		dirs = append(dirs, home)
+	} else if home, ok := l.lookupenv("HOME"); ok && home != "" {
+		dirs = append(dirs, filepath.Join(home, ".config"))
+	}
+	configDirs, ok := l.lookupenv("XDG_CONFIG_DIRS")
+	if !ok || configDirs == "" {
+		configDirs = "/etc/xdg"
+	}
+	for _, dir := range filepath.SplitList(configDirs) {
+		if filepath.IsAbs(dir) {
+			dirs = append(dirs, dir)
+		}
+	}
+	for i, dir := range dirs {
+		dirs[i] = filepath.Join(dir, app)
+	}
+	return dirs
+}
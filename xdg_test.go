@@ -0,0 +1,50 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestXDGConfig(t *testing.T) {
+	type C struct {
+		Name string `env:"NAME" default:"default"`
+	}
+	root := t.TempDir()
+	write := func(path, content string) {
+		t.Helper()
+		path = filepath.Join(root, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("xdg/app/config.toml", `name = "xdg"`)
+	write("home/.config/app/config.json", `{"name": "home"}`)
+	write("etc/app/config.ini", "name = etc")
+	tests := []struct {
+		name string
+		env  map[string]string
+		want *C
+	}{
+		{name: "config home", env: map[string]string{"XDG_CONFIG_HOME": filepath.Join(root, "xdg"), "HOME": filepath.Join(root, "home")}, want: &C{Name: "xdg"}},
+		{name: "home fallback", env: map[string]string{"HOME": filepath.Join(root, "home")}, want: &C{Name: "home"}},
+		{name: "relative config home", env: map[string]string{"XDG_CONFIG_HOME": "xdg", "HOME": filepath.Join(root, "home")}, want: &C{Name: "home"}},
+		{name: "config dirs", env: map[string]string{"HOME": filepath.Join(root, "nowhere"), "XDG_CONFIG_DIRS": filepath.Join(root, "none") + ":" + filepath.Join(root, "etc")}, want: &C{Name: "etc"}},
+		{name: "no file", env: map[string]string{"HOME": filepath.Join(root, "nowhere"), "XDG_CONFIG_DIRS": filepath.Join(root, "none")}, want: &C{Name: "default"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := New(makeLookup(tt.env), []string{"test"}, &C{}, WithXDGConfig("app"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("New() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,88 @@
+package config
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"strings"
+)
+
+/*
+WithXMLFile adds the XML file at path as a source of values below environment
+variables. The children of the root element map to fields like the keys of a JSON
+object, and may be given as elements or as attributes, so both of these set the
+`DB_HOST` field:
+
+	<config><db><host>localhost</host></db></config>
+	<config><db host="localhost"/></config>
+
+Elements that are repeated populate slices of structs, or slices of scalars if they only
+hold text. An element that appears once is not a list, so it only populates slices of
+scalars. Namespaces are ignored. A missing file is an error.
+*/
+func WithXMLFile(path string) Option {
+	return withFile(path, document(parseXML))
+}
+
+func parseXML(data []byte) (map[string]any, error) {
+	d := xml.NewDecoder(bytes.NewReader(data))
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return nil, fmt.Errorf("expected a root element: %w", err)
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			value, err := parseXMLElement(d, start)
+			if err != nil {
+				return nil, err
+			}
+			if doc, ok := value.(map[string]any); ok {
+				return doc, nil
+			}
+			return map[string]any{}, nil
+		}
+	}
+}
+
+// parseXMLElement returns the text of an element with neither attributes nor children.
+// Otherwise it returns the attributes and children keyed by name, and its text is
+// ignored.
+func parseXMLElement(d *xml.Decoder, start xml.StartElement) (any, error) {
+	values := map[string]any{}
+	for _, attr := range start.Attr {
+		if attr.Name.Space == "xmlns" || attr.Name.Local == "xmlns" {
+			continue
+		}
+		values[attr.Name.Local] = attr.Value
+	}
+	var text strings.Builder
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			child, err := parseXMLElement(d, t)
+			if err != nil {
+				return nil, err
+			}
+			name := t.Name.Local
+			switch existing := values[name].(type) {
+			case nil:
+				values[name] = child
+			case []any:
+				values[name] = append(existing, child)
+			default:
+				values[name] = []any{existing, child}
+			}
+		case xml.CharData:
+			text.Write(t)
+		case xml.EndElement:
+			if len(values) == 0 {
+				return strings.TrimSpace(text.String()), nil
+			}
+			return values, nil
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package config
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseXML(t *testing.T) {
+	tests := []struct {
+		name    string
+		input   string
+		want    map[string]any
+		wantErr bool
+	}{
+		{
+			name: "elements and attributes",
+			input: `<?xml version="1.0"?>
+<!-- comment -->
+<config name="app">
+  <db port="5432">
+    <host> localhost </host>
+  </db>
+</config>`,
+			want: map[string]any{"name": "app", "db": map[string]any{"port": "5432", "host": "localhost"}},
+		},
+		{
+			name:  "repeated elements",
+			input: `<config><tag>a</tag><tag>b</tag><server host="x"/><server host="y"/></config>`,
+			want:  map[string]any{"tag": []any{"a", "b"}, "server": []any{map[string]any{"host": "x"}, map[string]any{"host": "y"}}},
+		},
+		{
+			name:  "namespaces",
+			input: `<c:config xmlns:c="urn:c" xmlns="urn:d"><c:name>app</c:name></c:config>`,
+			want:  map[string]any{"name": "app"},
+		},
+		{name: "empty root", input: `<config/>`, want: map[string]any{}},
+		{name: "unclosed element", input: `<config><name>app</config>`, wantErr: true},
+		{name: "no root", input: ``, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseXML([]byte(tt.input))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseXML() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseXML() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestXMLFile(t *testing.T) {
+	type Server struct {
+		Host string `env:"HOST"`
+	}
+	type C struct {
+		Name    string   `env:"NAME"`
+		Tags    []string `env:"TAG"`
+		Servers []Server `env:"SERVER"`
+		DB      struct {
+			Host string `env:"HOST"`
+			Port int    `env:"PORT"`
+		}
+	}
+	path := writeFile(t, "config.xml", `<config name="app"><tag>a</tag><tag>b</tag><server host="x"/><server host="y"/><db port="5432"><host>db</host></db></config>`)
+	got, err := New(makeLookup(nil), []string{"test"}, &C{}, WithXMLFile(path))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := &C{Name: "app", Tags: []string{"a", "b"}, Servers: []Server{{Host: "x"}, {Host: "y"}}}
+	want.DB.Host, want.DB.Port = "db", 5432
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("New() = %+v, want %+v", got, want)
+	}
+}